@@ -0,0 +1,199 @@
+// Package apperrors defines the domain errors returned by the API. Every
+// error carries a stable machine-readable Code which is mapped centrally to
+// an HTTP status, so handlers never pick status codes themselves.
+package apperrors
+
+import (
+	"errors"
+	"net/http"
+)
+
+type Code string
+
+const (
+	CodeInvalidRequest       Code = "INVALID_REQUEST"
+	CodeValidationFailed     Code = "VALIDATION_FAILED"
+	CodeUnauthenticated      Code = "UNAUTHENTICATED"
+	CodeInvalidCredentials   Code = "INVALID_CREDENTIALS"
+	CodeUserNotFound         Code = "USER_NOT_FOUND"
+	CodeTargetUserNotFound   Code = "TARGET_USER_NOT_FOUND"
+	CodeDuplicatePhone       Code = "DUPLICATE_PHONE"
+	CodeInsufficientBalance  Code = "INSUFFICIENT_BALANCE"
+	CodeAccountFrozen        Code = "ACCOUNT_FROZEN"
+	CodeUnsupportedCurrency  Code = "UNSUPPORTED_CURRENCY"
+	CodeQuoteNotFound        Code = "QUOTE_NOT_FOUND"
+	CodeQuoteExpired         Code = "QUOTE_EXPIRED"
+	CodeRateUnavailable      Code = "RATE_UNAVAILABLE"
+	CodePocketNotFound       Code = "POCKET_NOT_FOUND"
+	CodeDuplicatePocket      Code = "DUPLICATE_POCKET"
+	CodePocketLimitReached   Code = "POCKET_LIMIT_REACHED"
+	CodePaymentNotFound      Code = "PAYMENT_NOT_FOUND"
+	CodeTransactionNotFound  Code = "TRANSACTION_NOT_FOUND"
+	CodeRefundExceedsPayment Code = "REFUND_EXCEEDS_PAYMENT"
+	CodeAlreadyReversed      Code = "ALREADY_REVERSED"
+	CodeScheduleNotFound     Code = "SCHEDULED_TRANSFER_NOT_FOUND"
+	CodeScheduleLimitReached Code = "SCHEDULE_LIMIT_REACHED"
+	CodeRequestNotFound      Code = "MONEY_REQUEST_NOT_FOUND"
+	CodeRequestExpired       Code = "MONEY_REQUEST_EXPIRED"
+	CodeRequestClosed        Code = "MONEY_REQUEST_CLOSED"
+	CodeDuplicateRequest     Code = "DUPLICATE_MONEY_REQUEST"
+	CodeRequestLimitReached  Code = "MONEY_REQUEST_LIMIT_REACHED"
+	CodePreconditionRequired Code = "PRECONDITION_REQUIRED"
+	CodeVersionConflict      Code = "VERSION_CONFLICT"
+	CodeRateLimited          Code = "RATE_LIMITED"
+	CodeServiceUnavailable   Code = "SERVICE_UNAVAILABLE"
+	CodeInternal             Code = "INTERNAL_ERROR"
+)
+
+var statusByCode = map[Code]int{
+	CodeInvalidRequest:       http.StatusBadRequest,
+	CodeValidationFailed:     http.StatusUnprocessableEntity,
+	CodeUnauthenticated:      http.StatusUnauthorized,
+	CodeInvalidCredentials:   http.StatusUnauthorized,
+	CodeUserNotFound:         http.StatusNotFound,
+	CodeTargetUserNotFound:   http.StatusNotFound,
+	CodeDuplicatePhone:       http.StatusConflict,
+	CodeInsufficientBalance:  http.StatusUnprocessableEntity,
+	CodeAccountFrozen:        http.StatusForbidden,
+	CodeUnsupportedCurrency:  http.StatusUnprocessableEntity,
+	CodeQuoteNotFound:        http.StatusNotFound,
+	CodeQuoteExpired:         http.StatusConflict,
+	CodeRateUnavailable:      http.StatusServiceUnavailable,
+	CodePocketNotFound:       http.StatusNotFound,
+	CodeDuplicatePocket:      http.StatusConflict,
+	CodePocketLimitReached:   http.StatusUnprocessableEntity,
+	CodePaymentNotFound:      http.StatusNotFound,
+	CodeTransactionNotFound:  http.StatusNotFound,
+	CodeRefundExceedsPayment: http.StatusUnprocessableEntity,
+	CodeAlreadyReversed:      http.StatusConflict,
+	CodeScheduleNotFound:     http.StatusNotFound,
+	CodeScheduleLimitReached: http.StatusUnprocessableEntity,
+	CodeRequestNotFound:      http.StatusNotFound,
+	CodeRequestExpired:       http.StatusConflict,
+	CodeRequestClosed:        http.StatusConflict,
+	CodeDuplicateRequest:     http.StatusConflict,
+	CodeRequestLimitReached:  http.StatusUnprocessableEntity,
+	CodePreconditionRequired: http.StatusPreconditionRequired,
+	CodeVersionConflict:      http.StatusPreconditionFailed,
+	CodeRateLimited:          http.StatusTooManyRequests,
+	CodeServiceUnavailable:   http.StatusServiceUnavailable,
+	CodeInternal:             http.StatusInternalServerError,
+}
+
+// Status returns the HTTP status for code, defaulting to 500 for codes that
+// are not in the catalogue.
+func (code Code) Status() int {
+	if status, ok := statusByCode[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Codes returns every code in the catalogue.
+func Codes() []Code {
+	codes := make([]Code, 0, len(statusByCode))
+	for code := range statusByCode {
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+// FieldError describes why a single request field was rejected. Rule names
+// the failed check and Param holds its argument, e.g. Rule "max_length" with
+// Param "50". Clients get a localised message built from both.
+type FieldError struct {
+	Rule  string
+	Param string
+}
+
+// Error is a domain error that is safe to show to API clients. Message is the
+// English fallback text; the wrapped cause is kept for logging only and is
+// never serialised.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  map[string]FieldError
+	cause   error
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.cause.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches any *Error with the same code, so errors.Is(err, ErrUserNotFound)
+// holds for copies produced by Wrap and WithFields.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e carrying cause for diagnostics.
+func (e *Error) Wrap(cause error) *Error {
+	clone := *e
+	clone.cause = cause
+	return &clone
+}
+
+// WithFields returns a copy of e carrying per-field validation messages.
+func (e *Error) WithFields(fields map[string]FieldError) *Error {
+	clone := *e
+	clone.Fields = fields
+	return &clone
+}
+
+var (
+	ErrInvalidRequest       = New(CodeInvalidRequest, "Invalid request")
+	ErrValidationFailed     = New(CodeValidationFailed, "Request validation failed")
+	ErrUnauthenticated      = New(CodeUnauthenticated, "Unauthenticated")
+	ErrInvalidCredentials   = New(CodeInvalidCredentials, "Phone Number and PIN doesn't match")
+	ErrUserNotFound         = New(CodeUserNotFound, "User not found")
+	ErrTargetUserNotFound   = New(CodeTargetUserNotFound, "Target user not found")
+	ErrDuplicatePhone       = New(CodeDuplicatePhone, "Phone Number already registered")
+	ErrInsufficientBalance  = New(CodeInsufficientBalance, "Balance is not enough")
+	ErrAccountFrozen        = New(CodeAccountFrozen, "Account is frozen, please contact support")
+	ErrUnsupportedCurrency  = New(CodeUnsupportedCurrency, "Currency is not supported")
+	ErrQuoteNotFound        = New(CodeQuoteNotFound, "Exchange rate quote not found")
+	ErrQuoteExpired         = New(CodeQuoteExpired, "Exchange rate quote has expired or was already used")
+	ErrRateUnavailable      = New(CodeRateUnavailable, "Exchange rate is unavailable, please retry later")
+	ErrPocketNotFound       = New(CodePocketNotFound, "Pocket not found")
+	ErrDuplicatePocket      = New(CodeDuplicatePocket, "You already have a pocket with this name")
+	ErrPocketLimitReached   = New(CodePocketLimitReached, "You cannot open any more pockets")
+	ErrPaymentNotFound      = New(CodePaymentNotFound, "Payment not found")
+	ErrTransactionNotFound  = New(CodeTransactionNotFound, "Transaction not found")
+	ErrRefundExceedsPayment = New(CodeRefundExceedsPayment, "Refund exceeds what is left of the payment")
+	ErrAlreadyReversed      = New(CodeAlreadyReversed, "Transaction has already been reversed")
+	ErrScheduleNotFound     = New(CodeScheduleNotFound, "Scheduled transfer not found")
+	ErrScheduleLimitReached = New(CodeScheduleLimitReached, "You cannot schedule any more transfers")
+	ErrRequestNotFound      = New(CodeRequestNotFound, "Money request not found")
+	ErrRequestExpired       = New(CodeRequestExpired, "Money request has expired")
+	ErrRequestClosed        = New(CodeRequestClosed, "Money request was already answered or cancelled")
+	ErrDuplicateRequest     = New(CodeDuplicateRequest, "You already requested this amount from this user")
+	ErrRequestLimitReached  = New(CodeRequestLimitReached, "You cannot request any more money for now")
+	ErrPreconditionRequired = New(CodePreconditionRequired, "If-Match header is required")
+	ErrVersionConflict      = New(CodeVersionConflict, "Resource has been modified")
+	ErrRateLimited          = New(CodeRateLimited, "Too many requests, please retry later")
+	ErrServiceUnavailable   = New(CodeServiceUnavailable, "Service is shutting down, please retry")
+	ErrInternal             = New(CodeInternal, "Internal server error")
+)
+
+// From converts any error into an *Error. Errors that are not already domain
+// errors become ErrInternal, which keeps database and driver messages from
+// reaching the client.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return ErrInternal.Wrap(err)
+}
@@ -0,0 +1,45 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeStatus(t *testing.T) {
+	assert.Equal(t, http.StatusConflict, CodeDuplicatePhone.Status())
+	assert.Equal(t, http.StatusUnprocessableEntity, CodeInsufficientBalance.Status())
+	assert.Equal(t, http.StatusInternalServerError, Code("SOMETHING_NEW").Status())
+}
+
+func TestEveryCodeHasStatus(t *testing.T) {
+	for _, err := range []*Error{
+		ErrInvalidRequest, ErrValidationFailed, ErrUnauthenticated, ErrInvalidCredentials,
+		ErrUserNotFound, ErrTargetUserNotFound, ErrDuplicatePhone, ErrInsufficientBalance,
+		ErrPreconditionRequired, ErrVersionConflict, ErrServiceUnavailable, ErrInternal,
+	} {
+		assert.Contains(t, statusByCode, err.Code, "missing status for %s", err.Code)
+	}
+}
+
+func TestFromHidesInternalErrors(t *testing.T) {
+	cause := errors.New(`pq: duplicate key value violates unique constraint "uni_users_phone_number"`)
+
+	appErr := From(cause)
+
+	assert.Equal(t, CodeInternal, appErr.Code)
+	assert.Equal(t, "Internal server error", appErr.Message)
+	assert.ErrorIs(t, appErr, cause)
+}
+
+func TestFromKeepsWrappedDomainErrors(t *testing.T) {
+	err := fmt.Errorf("transfer: %w", ErrInsufficientBalance.Wrap(errors.New("balance 10 < 20")))
+
+	appErr := From(err)
+
+	assert.Equal(t, CodeInsufficientBalance, appErr.Code)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+}
@@ -7,7 +7,19 @@ import (
 	"github.com/dgrijalva/jwt-go"
 )
 
-var jwtKey = []byte("my_secret_key")
+var (
+	jwtKey          = []byte("my_secret_key")
+	accessTokenTTL  = 24 * time.Hour
+	refreshTokenTTL = 7 * 24 * time.Hour // Refresh token lasts longer
+)
+
+// Configure sets the signing key and token lifetimes. It must be called
+// before any token is issued or parsed.
+func Configure(secret string, accessTTL, refreshTTL time.Duration) {
+	jwtKey = []byte(secret)
+	accessTokenTTL = accessTTL
+	refreshTokenTTL = refreshTTL
+}
 
 type Claims struct {
 	UserID      string `json:"user_id"`
@@ -17,9 +29,9 @@ type Claims struct {
 }
 
 func GenerateJWT(userID, phoneNumber, tokenType string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+	expirationTime := time.Now().Add(accessTokenTTL)
 	if tokenType == "refresh" {
-		expirationTime = time.Now().Add(7 * 24 * time.Hour) // Refresh token lasts longer
+		expirationTime = time.Now().Add(refreshTokenTTL)
 	}
 
 	claims := &Claims{
@@ -0,0 +1,57 @@
+// Package background tracks work that outlives the goroutine starting it, so
+// that shutdown can wait for it instead of killing it half-way.
+package background
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned by Go once Shutdown has been called.
+var ErrClosed = errors.New("background: shutting down, not accepting new work")
+
+// Group is a set of running tasks. The zero value is ready to use.
+type Group struct {
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// Go runs fn in a new goroutine unless the group is shutting down.
+func (g *Group) Go(fn func()) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return ErrClosed
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn()
+	}()
+	return nil
+}
+
+// Shutdown stops accepting new tasks and waits for the running ones to
+// finish, or for ctx to be done, whichever comes first.
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,44 @@
+package background
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownWaitsForRunningTasks(t *testing.T) {
+	var group Group
+	var finished atomic.Bool
+
+	assert.NoError(t, group.Go(func() {
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+	}))
+
+	assert.NoError(t, group.Shutdown(context.Background()))
+	assert.True(t, finished.Load())
+}
+
+func TestGoFailsAfterShutdown(t *testing.T) {
+	var group Group
+	assert.NoError(t, group.Shutdown(context.Background()))
+
+	err := group.Go(func() { t.Error("task must not run") })
+
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestShutdownGivesUpAtDeadline(t *testing.T) {
+	var group Group
+	release := make(chan struct{})
+	defer close(release)
+	assert.NoError(t, group.Go(func() { <-release }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, group.Shutdown(ctx), context.DeadlineExceeded)
+}
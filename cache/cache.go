@@ -0,0 +1,23 @@
+// Package cache keeps short-lived copies of values that are expensive to
+// load. Entries live in a Store: an in-process LRU for a single instance, or
+// Redis so that several instances share entries and invalidations.
+//
+// A cache only ever serves reads that tolerate slightly stale data. Entries
+// expire after their TTL even when an invalidation is lost, which bounds how
+// stale they can get.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store holds encoded values under string keys.
+type Store interface {
+	// Get returns the value under key, or false when there is none or it
+	// has expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the keys; missing keys are not an error.
+	Delete(ctx context.Context, keys ...string) error
+}
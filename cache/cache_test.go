@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// stores returns every Store implementation.
+func stores(t *testing.T) map[string]Store {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return map[string]Store{"lru": NewLRU(10), "redis": NewRedisStore(client, "test:")}
+}
+
+func TestSetGetAndDelete(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			_, ok, err := store.Get(ctx, "user:1")
+			assert.NoError(t, err)
+			assert.False(t, ok)
+
+			assert.NoError(t, store.Set(ctx, "user:1", []byte("one"), time.Minute))
+			assert.NoError(t, store.Set(ctx, "user:2", []byte("two"), time.Hour))
+			value, ok, err := store.Get(ctx, "user:1")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, []byte("one"), value)
+
+			assert.NoError(t, store.Delete(ctx, "user:2", "user:missing"))
+			_, ok, _ = store.Get(ctx, "user:2")
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestEntriesExpire(t *testing.T) {
+	lru := NewLRU(10)
+	now := time.Unix(1700000000, 0)
+	lru.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	assert.NoError(t, lru.Set(ctx, "user:1", []byte("one"), time.Minute))
+	now = now.Add(time.Minute)
+	_, ok, err := lru.Get(ctx, "user:1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0, lru.Len())
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	shared := NewRedisStore(client, "test:")
+	assert.NoError(t, shared.Set(ctx, "user:1", []byte("one"), time.Minute))
+	server.FastForward(time.Minute)
+	_, ok, err = shared.Get(ctx, "user:1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	lru := NewLRU(2)
+	ctx := context.Background()
+
+	lru.Set(ctx, "a", []byte("a"), time.Minute)
+	lru.Set(ctx, "b", []byte("b"), time.Minute)
+	lru.Get(ctx, "a")
+	lru.Set(ctx, "c", []byte("c"), time.Minute)
+
+	_, ok, _ := lru.Get(ctx, "b")
+	assert.False(t, ok, "b was used least recently")
+	_, ok, _ = lru.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, lru.Len())
+}
@@ -0,0 +1,318 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"myapp/cache"
+	"myapp/config"
+	"myapp/database"
+	"myapp/models"
+	"myapp/notification"
+	"myapp/repository"
+	"myapp/services"
+
+	"github.com/google/uuid"
+)
+
+// auditTrailLength is how many audit entries `user show` prints.
+const auditTrailLength = 20
+
+// admin is what the admin commands work with.
+type admin struct {
+	users  *services.UserService
+	wallet *services.WalletService
+	out    io.Writer
+}
+
+func newAdmin(store repository.Store, out io.Writer) *admin {
+	return &admin{
+		users:  services.NewUserService(store),
+		wallet: services.NewWalletService(store, notification.LogNotifier{}),
+		out:    out,
+	}
+}
+
+// adminStore wraps store so that admin changes evict the users they touch
+// from the Redis cache shared by the API instances, as the API's own
+// changes do. A memory cache lives inside each API process and cannot be
+// reached from here; its entries expire after cache.ttl. The returned func
+// releases the cache connection.
+func adminStore(cfg config.Config, store repository.Store) (repository.Store, func()) {
+	if cfg.Cache.Store != "redis" {
+		return store, func() {}
+	}
+	client := newRedisClient(cfg.Redis)
+	closeClient := func() {
+		if err := client.Close(); err != nil {
+			slog.Warn("unable to close redis", "error", err)
+		}
+	}
+	return repository.NewCachedStore(store, cache.NewRedisStore(client, cachePrefix), cfg.Cache.TTL), closeClient
+}
+
+// adminFlags adds the flags shared by every admin command.
+func adminFlags(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	actor := fs.String("actor", defaultActor(), "who is acting, recorded in the audit trail")
+	return fs, actor
+}
+
+// userCommand implements `user show | freeze | unfreeze`.
+func userCommand(ctx context.Context, cfg config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return usageError("user needs show, freeze or unfreeze")
+	}
+	subcommand := args[0]
+	fs, actor := adminFlags("user " + subcommand)
+	var reason *string
+	if subcommand == "freeze" || subcommand == "unfreeze" {
+		reason = fs.String("reason", "", "why, recorded in the audit trail (required)")
+	}
+	switch subcommand {
+	case "show", "freeze", "unfreeze":
+	default:
+		return usageError("unknown user command %q", subcommand)
+	}
+
+	positional, err := parse(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("user %s needs exactly one user ID or phone number", subcommand)
+	}
+
+	if err := connect(cfg); err != nil {
+		return err
+	}
+	defer closeDatabase()
+	store, closeStore := adminStore(cfg, repository.NewGormStore(database.DB))
+	defer closeStore()
+	a := newAdmin(store, out)
+	ctx = services.WithActor(ctx, "admin:"+*actor)
+
+	switch subcommand {
+	case "freeze":
+		return a.setFrozen(ctx, positional[0], true, *reason)
+	case "unfreeze":
+		return a.setFrozen(ctx, positional[0], false, *reason)
+	}
+	return a.show(ctx, positional[0])
+}
+
+// balanceCommand implements `balance adjust`.
+func balanceCommand(ctx context.Context, cfg config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "adjust" {
+		return usageError("balance needs adjust")
+	}
+	fs, actor := adminFlags("balance adjust")
+	amount := fs.String("amount", "", "amount to credit, or to debit when negative (required)")
+	reason := fs.String("reason", "", "why, recorded in the audit trail (required)")
+	positional, err := parse(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("balance adjust needs exactly one user ID or phone number")
+	}
+	delta, err := strconv.ParseFloat(*amount, 64)
+	if err != nil {
+		return usageError("balance adjust needs a numeric --amount, got %q", *amount)
+	}
+
+	if err := connect(cfg); err != nil {
+		return err
+	}
+	defer closeDatabase()
+	store, closeStore := adminStore(cfg, repository.NewGormStore(database.DB))
+	defer closeStore()
+	a := newAdmin(store, out)
+	return a.adjust(services.WithActor(ctx, "admin:"+*actor), positional[0], delta, *reason)
+}
+
+// paymentCommand implements `payment refund`.
+func paymentCommand(ctx context.Context, cfg config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "refund" {
+		return usageError("payment needs refund")
+	}
+	fs, actor := adminFlags("payment refund")
+	amount := fs.Float64("amount", 0, "amount to refund, all that is left of the payment when omitted")
+	reason := fs.String("reason", "", "why, recorded in the audit trail (required)")
+	positional, err := parse(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("payment refund needs exactly one payment ID")
+	}
+	paymentID, err := uuid.Parse(positional[0])
+	if err != nil {
+		return usageError("payment refund needs a payment ID, got %q", positional[0])
+	}
+	if *reason == "" {
+		return usageError("payment refund needs --reason")
+	}
+
+	if err := connect(cfg); err != nil {
+		return err
+	}
+	defer closeDatabase()
+	store, closeStore := adminStore(cfg, repository.NewGormStore(database.DB))
+	defer closeStore()
+	a := newAdmin(store, out)
+	return a.refund(services.WithActor(ctx, "admin:"+*actor), paymentID, *amount, *reason)
+}
+
+// reverseCommand implements `reverse topup | transfer`.
+func reverseCommand(ctx context.Context, cfg config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 || (args[0] != "topup" && args[0] != "transfer") {
+		return usageError("reverse needs topup or transfer")
+	}
+	subcommand := args[0]
+	fs, actor := adminFlags("reverse " + subcommand)
+	reason := fs.String("reason", "", "why, recorded in the audit trail (required)")
+	positional, err := parse(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("reverse %s needs exactly one %s ID", subcommand, subcommand)
+	}
+	id, err := uuid.Parse(positional[0])
+	if err != nil {
+		return usageError("reverse %s needs a %s ID, got %q", subcommand, subcommand, positional[0])
+	}
+
+	if err := connect(cfg); err != nil {
+		return err
+	}
+	defer closeDatabase()
+	store, closeStore := adminStore(cfg, repository.NewGormStore(database.DB))
+	defer closeStore()
+	a := newAdmin(store, out)
+	ctx = services.WithActor(ctx, "admin:"+*actor)
+	if subcommand == "topup" {
+		return a.reverseTopUp(ctx, id, *reason)
+	}
+	return a.reverseTransfer(ctx, id, *reason)
+}
+
+func (a *admin) show(ctx context.Context, ref string) error {
+	user, err := a.users.Lookup(ctx, ref)
+	if err != nil {
+		return err
+	}
+	trail, err := a.users.AuditTrail(ctx, user.ID, auditTrailLength)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	printUser(w, user)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "TIME\tACTOR\tACTION\tREASON\tDETAILS")
+	for _, entry := range trail {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			entry.CreatedDate.Format(time.RFC3339), entry.Actor, entry.Action, entry.Reason, entry.Details)
+	}
+	return w.Flush()
+}
+
+func (a *admin) setFrozen(ctx context.Context, ref string, frozen bool, reason string) error {
+	user, err := a.users.Lookup(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if frozen {
+		user, err = a.users.Freeze(ctx, user.ID, reason)
+	} else {
+		user, err = a.users.Unfreeze(ctx, user.ID, reason)
+	}
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	printUser(w, user)
+	return w.Flush()
+}
+
+func (a *admin) adjust(ctx context.Context, ref string, delta float64, reason string) error {
+	user, err := a.users.Lookup(ctx, ref)
+	if err != nil {
+		return err
+	}
+	user, err = a.wallet.Adjust(ctx, user.ID, delta, reason)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	printUser(w, user)
+	return w.Flush()
+}
+
+func (a *admin) refund(ctx context.Context, paymentID uuid.UUID, amount float64, reason string) error {
+	result, err := a.wallet.Refund(ctx, services.RefundOrder{PaymentID: paymentID, Amount: amount, Reason: reason})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	printReversal(w, result.Refund)
+	fmt.Fprintf(w, "Refunded	%.2f of %.2f\n", result.Payment.RefundedAmount, result.Payment.Amount)
+	return w.Flush()
+}
+
+func (a *admin) reverseTopUp(ctx context.Context, topUpID uuid.UUID, reason string) error {
+	reversal, err := a.wallet.ReverseTopUp(ctx, topUpID, reason)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	printReversal(w, reversal)
+	return w.Flush()
+}
+
+func (a *admin) reverseTransfer(ctx context.Context, transferID uuid.UUID, reason string) error {
+	result, err := a.wallet.ReverseTransfer(ctx, transferID, reason)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	printReversal(w, result.Sender)
+	fmt.Fprintln(w)
+	printReversal(w, result.Recipient)
+	return w.Flush()
+}
+
+func printReversal(w io.Writer, reversal models.Reversal) {
+	fmt.Fprintf(w, "Reversal\t%s\n", reversal.ID)
+	fmt.Fprintf(w, "User\t%s\n", reversal.UserID)
+	fmt.Fprintf(w, "Type\t%s\n", reversal.TransactionType)
+	fmt.Fprintf(w, "Amount\t%.2f %s\n", reversal.Amount, reversal.Currency)
+	fmt.Fprintf(w, "Balance\t%.2f\n", reversal.BalanceAfter)
+}
+
+func printUser(w io.Writer, user models.User) {
+	frozen := "no"
+	if user.Frozen() {
+		frozen = "since " + user.FrozenAt.Format(time.RFC3339)
+	}
+	fmt.Fprintf(w, "ID\t%s\n", user.ID)
+	fmt.Fprintf(w, "Name\t%s %s\n", user.FirstName, user.LastName)
+	fmt.Fprintf(w, "Phone\t%s\n", user.PhoneNumber)
+	fmt.Fprintf(w, "Balance\t%.2f\n", user.Balance)
+	fmt.Fprintf(w, "Language\t%s\n", user.Language)
+	fmt.Fprintf(w, "Frozen\t%s\n", frozen)
+	fmt.Fprintf(w, "Created\t%s\n", user.CreatedDate.Format(time.RFC3339))
+}
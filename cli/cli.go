@@ -0,0 +1,133 @@
+// Package cli implements the myapp command line: the API server, schema
+// migrations, demo data and admin operations. Admin commands go through the
+// same services as the HTTP API, so the same rules and audit trail apply.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/user"
+
+	"myapp/config"
+	"myapp/database"
+	"myapp/logging"
+)
+
+const usage = `usage: myapp <command> [arguments]
+
+commands:
+  serve                                  run the API server (default)
+  migrate up | down [n] | status         manage the database schema
+  seed sql [--dir DIR]                   load the example SQL data
+  seed synthetic [--users N] [--transactions N] [--days N] [--seed S]
+                 [--out db|sql|csv] [--dir DIR]
+                                         generate users and transaction histories
+  user show <id|phone>                   show a user and their audit trail
+  user freeze <id|phone> --reason TEXT   stop a user from logging in and paying
+  user unfreeze <id|phone> --reason TEXT lift a freeze
+  balance adjust <id|phone> --amount N --reason TEXT
+                                         credit (N > 0) or debit (N < 0) a balance
+  payment refund <payment-id> [--amount N] --reason TEXT
+                                         refund a payment, all that is left by default
+  reverse topup <top-up-id> --reason TEXT
+  reverse transfer <transfer-id> --reason TEXT
+                                         take back a top-up or transfer made in error
+
+Admin commands accept --actor NAME to record who acted; it defaults to the
+operating system user.`
+
+// ErrUsage is returned when the command line is malformed.
+var ErrUsage = errors.New("invalid command line")
+
+// Run executes the command in args, without the program name. Output meant
+// for the operator goes to out; logs go to the configured logger.
+func Run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprintln(out, usage)
+		return nil
+	}
+
+	cfg, err := config.Load(os.Getenv(config.FileEnv))
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	logging.Setup(cfg.Log)
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "serve":
+		return serve(ctx, cfg)
+	case "migrate":
+		return runMigrate(ctx, cfg, args, out)
+	case "seed":
+		return seed(ctx, cfg, args, out)
+	case "user":
+		return userCommand(ctx, cfg, args, out)
+	case "balance":
+		return balanceCommand(ctx, cfg, args, out)
+	case "payment":
+		return paymentCommand(ctx, cfg, args, out)
+	case "reverse":
+		return reverseCommand(ctx, cfg, args, out)
+	}
+	return usageError("unknown command %q", command)
+}
+
+// Usage returns the help text.
+func Usage() string {
+	return usage
+}
+
+func usageError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrUsage, fmt.Sprintf(format, args...))
+}
+
+// connect opens the database for a one-off command. The caller must close
+// it.
+func connect(cfg config.Config) error {
+	if err := database.Connect(cfg.Database); err != nil {
+		return fmt.Errorf("unable to connect to database: %w", err)
+	}
+	return nil
+}
+
+func closeDatabase() {
+	if err := database.Close(); err != nil {
+		slog.Warn("unable to close database", "error", err)
+	}
+}
+
+// parse parses flags that may appear before, between or after positional
+// arguments, e.g. `user freeze +62811 --reason fraud`, and returns the
+// positional ones.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usageError("%s: %v", fs.Name(), err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// defaultActor names the operator running an admin command.
+func defaultActor() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	return "unknown"
+}
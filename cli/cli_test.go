@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"myapp/apperrors"
+	"myapp/config"
+	"myapp/generator"
+	"myapp/models"
+	"myapp/services"
+	"myapp/testutil"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAcceptsFlagsAnywhere(t *testing.T) {
+	fs := flag.NewFlagSet("balance adjust", flag.ContinueOnError)
+	amount := fs.String("amount", "", "")
+	reason := fs.String("reason", "", "")
+
+	positional, err := parse(fs, []string{"--amount", "-300", "+62811255501", "--reason", "Duplicate top-up"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"+62811255501"}, positional)
+	assert.Equal(t, "-300", *amount)
+	assert.Equal(t, "Duplicate top-up", *reason)
+
+	_, err = parse(flag.NewFlagSet("user show", flag.ContinueOnError), []string{"--bogus"})
+	assert.True(t, errors.Is(err, ErrUsage))
+}
+
+func TestRunRejectsUnknownCommands(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, Run(context.Background(), []string{"help"}, &out))
+	assert.Contains(t, out.String(), "balance adjust")
+
+	err := Run(context.Background(), []string{"launch"}, &out)
+	assert.True(t, errors.Is(err, ErrUsage))
+}
+
+func TestAdminFreezeAndAdjust(t *testing.T) {
+	db := testutil.DB(t)
+	user := testutil.NewUser().WithBalance(1000).Create(t, db)
+	var out bytes.Buffer
+	a := newAdmin(testutil.Store(t), &out)
+	ctx := services.WithActor(context.Background(), "admin:ops")
+
+	assert.NoError(t, a.setFrozen(ctx, user.PhoneNumber, true, "Reported stolen phone"))
+	assert.Contains(t, out.String(), "Frozen    since")
+
+	assert.NoError(t, a.adjust(ctx, user.ID.String(), -250, "Duplicate top-up"))
+	assert.Contains(t, out.String(), "750.00")
+
+	err := a.adjust(ctx, user.ID.String(), -5000, "Too much")
+	assert.True(t, errors.Is(err, apperrors.ErrInsufficientBalance))
+
+	out.Reset()
+	assert.NoError(t, a.show(ctx, user.PhoneNumber))
+	assert.Contains(t, out.String(), services.ActionFreeze)
+	assert.Contains(t, out.String(), services.ActionAdjust)
+	assert.Contains(t, out.String(), "admin:ops")
+	assert.Contains(t, out.String(), "Duplicate top-up")
+
+	err = a.show(ctx, "+62800000000")
+	assert.True(t, errors.Is(err, apperrors.ErrUserNotFound))
+}
+
+func TestAdminChangesEvictCachedUsers(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := config.Default()
+	cfg.Cache.Store = "redis"
+	cfg.Redis.Addr = server.Addr()
+	store, closeStore := adminStore(cfg, testutil.Store(t))
+	defer closeStore()
+
+	user := testutil.NewUser().WithBalance(1000).Create(t, testutil.DB(t))
+	key := cachePrefix + "user:" + user.ID.String()
+	ctx := services.WithActor(context.Background(), "admin:ops")
+	cached := func() models.User {
+		found, err := store.Reader(user.ID).Users.FindByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("Failed to find user: %v", err)
+		}
+		return found
+	}
+	a := newAdmin(store, &bytes.Buffer{})
+
+	assert.Equal(t, 1000.0, cached().Balance)
+	assert.True(t, server.Exists(key))
+	assert.NoError(t, a.adjust(ctx, user.ID.String(), -250, "Duplicate top-up"))
+	assert.False(t, server.Exists(key), "an adjustment evicts the user")
+	assert.Equal(t, 750.0, cached().Balance)
+
+	assert.True(t, server.Exists(key))
+	assert.NoError(t, a.setFrozen(ctx, user.ID.String(), true, "Reported stolen phone"))
+	assert.False(t, server.Exists(key), "freezing evicts the user")
+	assert.True(t, cached().Frozen())
+}
+
+func TestAdminRefundAndReverse(t *testing.T) {
+	db := testutil.DB(t)
+	sender := testutil.NewUser().WithBalance(1000).Create(t, db)
+	recipient := testutil.NewUser().Create(t, db)
+	var out bytes.Buffer
+	a := newAdmin(testutil.Store(t), &out)
+	ctx := services.WithActor(context.Background(), "admin:ops")
+
+	payment, err := a.wallet.Pay(ctx, sender.ID, services.PaymentOrder{Amount: 300, Remarks: "Pulsa"})
+	if err != nil {
+		t.Fatalf("Failed to pay: %v", err)
+	}
+	assert.NoError(t, a.refund(ctx, payment.ID, 100, "Not delivered"))
+	assert.Contains(t, out.String(), "Refunded  100.00 of 300.00")
+	err = a.refund(ctx, payment.ID, 201, "Not delivered")
+	assert.True(t, errors.Is(err, apperrors.ErrRefundExceedsPayment))
+
+	topUp, err := a.wallet.TopUp(ctx, recipient.ID, 50, services.Account{})
+	if err != nil {
+		t.Fatalf("Failed to top up: %v", err)
+	}
+	assert.NoError(t, a.reverseTopUp(ctx, topUp.ID, "Duplicate top-up"))
+	err = a.reverseTopUp(ctx, topUp.ID, "Duplicate top-up")
+	assert.True(t, errors.Is(err, apperrors.ErrAlreadyReversed))
+
+	transfer, err := a.wallet.Transfer(ctx, services.TransferOrder{From: sender.ID, To: recipient.ID, Amount: 200})
+	if err != nil {
+		t.Fatalf("Failed to transfer: %v", err)
+	}
+	out.Reset()
+	assert.NoError(t, a.reverseTransfer(ctx, transfer.Transfer.ID, "Wrong recipient"))
+	assert.Contains(t, out.String(), sender.ID.String())
+	assert.Contains(t, out.String(), recipient.ID.String())
+
+	out.Reset()
+	assert.NoError(t, a.show(ctx, sender.PhoneNumber))
+	assert.Contains(t, out.String(), "Balance   800.00")
+	assert.Contains(t, out.String(), services.ActionRefund)
+	assert.Contains(t, out.String(), services.ActionReverseTransfer)
+}
+
+func TestSeedSyntheticWritesFiles(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	opts := generator.Options{Users: 10, TransactionsPerUser: 5, Days: 30, Seed: 7}
+
+	assert.NoError(t, seedSynthetic(context.Background(), config.Config{}, opts, "csv", dir, &out))
+	assert.Contains(t, out.String(), "generated 10 users")
+	for _, name := range []string{"users.csv", "top_ups.csv", "payments.csv", "transfers.csv"} {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+
+	err := seedSynthetic(context.Background(), config.Config{}, opts, "xml", dir, &out)
+	assert.True(t, errors.Is(err, ErrUsage))
+}
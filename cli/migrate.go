@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"myapp/config"
+	"myapp/database"
+	"myapp/migrate"
+)
+
+// runMigrate implements `migrate up | down [n] | status`.
+func runMigrate(ctx context.Context, cfg config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return usageError("migrate needs up, down or status")
+	}
+	if err := connect(cfg); err != nil {
+		return err
+	}
+	defer closeDatabase()
+
+	migrator, err := database.Migrator(database.DB)
+	if err != nil {
+		return fmt.Errorf("unable to load migrations: %w", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Fprintf(out, "applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "already up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return usageError("down expects a positive number of steps, got %q", args[1])
+			}
+			steps = n
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, m := range rolledBack {
+			fmt.Fprintf(out, "rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrations(out, statuses)
+		return nil
+	}
+	return usageError("unknown migrate command %q", args[0])
+}
+
+func printMigrations(out io.Writer, statuses []migrate.Status) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state, appliedAt := "pending", ""
+		if s.Applied {
+			state, appliedAt = "applied", s.AppliedAt.Format(time.RFC3339)
+		}
+		switch {
+		case s.Modified:
+			state = "modified"
+		case s.Missing:
+			state = "missing file"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	w.Flush()
+}
@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"myapp/auth"
+	"myapp/background"
+	"myapp/cache"
+	"myapp/config"
+	"myapp/database"
+	"myapp/fx"
+	"myapp/health"
+	"myapp/metrics"
+	"myapp/notification"
+	"myapp/ratelimit"
+	"myapp/repository"
+	"myapp/routers"
+	"myapp/server"
+	"myapp/services"
+	"myapp/tracing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// cachePrefix namespaces the shared user cache in Redis. Admin commands
+// evict from the same keys as the API.
+const cachePrefix = "myapp:cache:"
+
+func newRedisClient(cfg config.RedisConfig) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+}
+
+// serve runs the API server until ctx is cancelled, then shuts it down
+// gracefully.
+func serve(ctx context.Context, cfg config.Config) error {
+	slog.Info("loaded configuration", "config", cfg.String())
+
+	auth.Configure(cfg.Auth.JWTSecret, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("unable to set up tracing: %w", err)
+	}
+
+	if err := connect(cfg); err != nil {
+		return err
+	}
+	if cfg.Database.MigrateOnStart {
+		if err := database.Migrate(context.Background()); err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+	migrator, err := database.Migrator(database.DB)
+	if err != nil {
+		return fmt.Errorf("unable to load migrations: %w", err)
+	}
+
+	if err := tracing.InstrumentGORM(database.DB); err != nil {
+		return fmt.Errorf("unable to instrument database: %w", err)
+	}
+
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return fmt.Errorf("unable to access connection pool: %w", err)
+	}
+	if err := metrics.RegisterDBStats(sqlDB, "primary"); err != nil {
+		return fmt.Errorf("unable to register database metrics: %w", err)
+	}
+
+	store := repository.NewGormStore(database.DB)
+	replicas, err := readReplicas(cfg.Database)
+	if err != nil {
+		return err
+	}
+	if replicas != nil {
+		go replicas.Run(ctx, cfg.Database.ReplicaCheckInterval)
+		store = store.WithReplicas(replicas)
+	}
+
+	probes := health.New(cfg.Server.ReadinessTimeout)
+	probes.Register("database", health.DatabaseCheck(database.DB))
+	probes.Register("schema", health.MigrationCheck(migrator))
+
+	var redisClient *redis.Client
+	if (cfg.RateLimit.Enabled && cfg.RateLimit.Store == "redis") || cfg.Cache.Store == "redis" {
+		redisClient = newRedisClient(cfg.Redis)
+		probes.Register("redis", health.RedisCheck(redisClient))
+	}
+
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if cfg.RateLimit.Store == "redis" {
+			store = ratelimit.NewRedisStore(redisClient, "myapp:ratelimit:")
+		}
+		limiter = ratelimit.New(store, ratelimit.Policies(cfg.RateLimit))
+	}
+
+	var appStore repository.Store = store
+	switch cfg.Cache.Store {
+	case "memory":
+		appStore = repository.NewCachedStore(store, cache.NewLRU(cfg.Cache.Size), cfg.Cache.TTL)
+	case "redis":
+		appStore = repository.NewCachedStore(store, cache.NewRedisStore(redisClient, cachePrefix), cfg.Cache.TTL)
+	}
+
+	var exchange *fx.Exchange
+	if cfg.Exchange.RatesFile != "" {
+		provider, err := fx.LoadStaticProvider(cfg.Exchange.RatesFile)
+		if err != nil {
+			return err
+		}
+		exchange = fx.NewExchange(provider, cfg.Exchange.Spread, cfg.Exchange.QuoteTTL)
+	}
+
+	notifier := notification.LogNotifier{}
+	workers := &background.Group{}
+	router := routers.SetupRouter(routers.Options{
+		Store:     appStore,
+		Notifier:  notifier,
+		Workers:   workers,
+		Probes:    probes,
+		Limiter:   limiter,
+		Exchange:  exchange,
+		Merchants: cfg.Merchant.Keys(),
+	})
+
+	srv := server.New(cfg.Server, router)
+	srv.BeforeShutdown(probes.SetShuttingDown)
+	srv.OnShutdown("transfer workers", workers.Shutdown)
+	if cfg.Scheduler.Enabled {
+		wallet := services.NewWalletService(appStore, notifier)
+		if exchange != nil {
+			wallet = wallet.WithExchange(exchange)
+		}
+		scheduler := services.NewScheduler(wallet, services.SchedulerOptions{
+			Interval:    cfg.Scheduler.Interval,
+			RetryDelay:  cfg.Scheduler.RetryDelay,
+			MaxAttempts: cfg.Scheduler.MaxAttempts,
+			BatchSize:   cfg.Scheduler.BatchSize,
+		})
+		// The scheduler stops looking once ctx is cancelled; shutdown waits
+		// for the transfer it is making.
+		go scheduler.Run(ctx)
+		srv.OnShutdown("scheduler", scheduler.Shutdown)
+	}
+	srv.OnShutdown("database", func(ctx context.Context) error {
+		return database.Close()
+	})
+	if redisClient != nil {
+		srv.OnShutdown("redis", func(ctx context.Context) error {
+			return redisClient.Close()
+		})
+	}
+	srv.OnShutdown("tracing", shutdownTracing)
+
+	if err := srv.Run(ctx); err != nil {
+		return fmt.Errorf("server stopped with error: %w", err)
+	}
+	return nil
+}
+
+// readReplicas connects the configured read replicas, with tracing and
+// metrics like the primary. It returns nil when none are configured.
+func readReplicas(cfg config.DatabaseConfig) (*repository.Replicas, error) {
+	if err := database.ConnectReplicas(cfg); err != nil {
+		return nil, fmt.Errorf("unable to connect to read replicas: %w", err)
+	}
+	if len(database.Replicas) == 0 {
+		return nil, nil
+	}
+
+	replicas := make([]repository.Replica, len(database.Replicas))
+	for i, db := range database.Replicas {
+		replicas[i] = repository.Replica{Name: fmt.Sprintf("replica-%d", i+1), DB: db}
+		if err := tracing.InstrumentGORM(db); err != nil {
+			return nil, fmt.Errorf("unable to instrument %s: %w", replicas[i].Name, err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("unable to access connection pool: %w", err)
+		}
+		if err := metrics.RegisterDBStats(sqlDB, replicas[i].Name); err != nil {
+			return nil, fmt.Errorf("unable to register database metrics: %w", err)
+		}
+	}
+
+	routing := repository.NewReplicas(database.DB, replicas, repository.ReplicaOptions{
+		MaxLag:         cfg.ReplicaMaxLag,
+		ReadYourWrites: cfg.ReadYourWrites,
+	})
+	for i, replica := range replicas {
+		i := i
+		lag := func() time.Duration { return routing.Status()[i].Lag }
+		if err := metrics.RegisterReplicaLag(replica.Name, lag); err != nil {
+			return nil, fmt.Errorf("unable to register replica metrics: %w", err)
+		}
+	}
+	return routing, nil
+}
@@ -0,0 +1,672 @@
+// Package config loads the application settings. Values come from built-in
+// defaults, then an optional YAML or TOML file, then MYAPP_* environment
+// variables, each layer overriding the previous one.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvProduction  = "production"
+
+	// EnvPrefix prefixes every environment variable read by Load, e.g.
+	// MYAPP_DATABASE_HOST for database.host.
+	EnvPrefix = "MYAPP_"
+
+	// FileEnv names the environment variable holding the config file path.
+	FileEnv = EnvPrefix + "CONFIG"
+
+	// Database drivers accepted in database.driver.
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+
+	defaultJWTSecret   = "my_secret_key"
+	minJWTSecretLength = 32
+	redacted           = "[REDACTED]"
+)
+
+type Config struct {
+	Env       string
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Auth      AuthConfig
+	Tracing   TracingConfig
+	Log       LogConfig
+	Redis     RedisConfig
+	RateLimit RateLimitConfig
+	Cache     CacheConfig
+	Exchange  ExchangeConfig
+	Merchant  MerchantConfig
+	Scheduler SchedulerConfig
+}
+
+type ServerConfig struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout bounds how long in-flight requests and background
+	// work may take to finish once a shutdown signal arrives.
+	ShutdownTimeout time.Duration
+	// ShutdownDelay keeps serving after readiness starts failing, giving
+	// the load balancer time to stop sending new requests.
+	ShutdownDelay    time.Duration
+	ReadinessTimeout time.Duration
+}
+
+type DatabaseConfig struct {
+	// Driver is postgres, mysql or sqlite.
+	Driver string
+	// DSN, when set, is used as is and the individual fields are ignored.
+	// A MySQL DSN needs parseTime=true and multiStatements=true.
+	DSN      string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+	// Path is the database file used by the sqlite driver.
+	Path string
+	// Replicas are DSNs of read replicas of the same driver. Histories and
+	// profiles are read from them; writes and balance checks stay on the
+	// primary.
+	Replicas []string
+	// ReplicaMaxLag is the replication lag beyond which a replica stops
+	// serving reads until it catches up.
+	ReplicaMaxLag time.Duration
+	// ReplicaCheckInterval is how often the lag of every replica is
+	// measured.
+	ReplicaCheckInterval time.Duration
+	// ReadYourWrites keeps a user's reads on the primary for this long after
+	// they changed something, so they always see their own writes. Zero
+	// disables it.
+	ReadYourWrites time.Duration
+	// MigrateOnStart applies pending migrations when the server starts.
+	// Turn it off to run `myapp migrate up` as a separate deploy step; the
+	// readiness probe then fails until the schema is up to date.
+	MigrateOnStart bool
+}
+
+type AuthConfig struct {
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+type TracingConfig struct {
+	// Exporter is one of none, stdout, file or otlp.
+	Exporter    string
+	ServiceName string
+	// SampleRatio is the fraction of new traces that are recorded; traces
+	// started upstream follow the caller's sampling decision.
+	SampleRatio float64
+	// FilePath receives one JSON span per line with the file exporter.
+	FilePath string
+	// OTLPEndpoint is the host:port of an OTLP/HTTP collector.
+	OTLPEndpoint string
+	OTLPInsecure bool
+}
+
+type LogConfig struct {
+	// Level is one of debug, info, warn or error.
+	Level string
+	// Format is json for production log collectors or text for a terminal.
+	Format string
+}
+
+// RedisConfig points at a Redis-compatible server shared by all instances.
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	// Store is memory for a single instance or redis to share the limits
+	// between instances.
+	Store string
+	// Anonymous routes are limited per client IP, protected routes per user.
+	Login    RateLimitPolicy
+	Register RateLimitPolicy
+	// Wallet covers top-up, payment and transfer, each with its own bucket.
+	Wallet RateLimitPolicy
+	// Default covers every other protected route.
+	Default RateLimitPolicy
+}
+
+type CacheConfig struct {
+	// Store is none, memory for a single instance, or redis to share
+	// entries and invalidations between instances.
+	Store string
+	// Size is the number of users kept by the memory store.
+	Size int
+	// TTL bounds how long an entry lives, and so how stale it can get if an
+	// invalidation is lost.
+	TTL time.Duration
+}
+
+type ExchangeConfig struct {
+	// RatesFile is the JSON file of exchange rates served by the static
+	// provider. Empty keeps wallets in rupiah only.
+	RatesFile string
+	// Spread is taken off the mid-market rate, e.g. 0.005 for half a
+	// percent.
+	Spread float64
+	// QuoteTTL is how long a quoted rate is honoured.
+	QuoteTTL time.Duration
+}
+
+type MerchantConfig struct {
+	// APIKeys lets merchants refund their payments, one "CODE:key" entry
+	// per key. A merchant may have several keys, e.g. while rotating them.
+	APIKeys []string
+}
+
+// Keys maps every API key to the code of its merchant. Entries are assumed
+// to have passed Validate.
+func (c MerchantConfig) Keys() map[string]string {
+	keys := make(map[string]string, len(c.APIKeys))
+	for _, entry := range c.APIKeys {
+		code, key, _ := strings.Cut(entry, ":")
+		keys[key] = code
+	}
+	return keys
+}
+
+type SchedulerConfig struct {
+	// Enabled runs the scheduler of scheduled transfers in this instance.
+	// Several instances may run it at once.
+	Enabled bool
+	// Interval is how often it looks for due transfers.
+	Interval time.Duration
+	// RetryDelay is how long after a failed attempt, e.g. for want of
+	// balance, a transfer is tried again, and MaxAttempts how often it is
+	// tried in all.
+	RetryDelay  time.Duration
+	MaxAttempts int
+	// BatchSize is the most transfers made per look.
+	BatchSize int
+}
+
+// RateLimitPolicy is a token bucket holding Limit requests that refills
+// completely over Period.
+type RateLimitPolicy struct {
+	Limit  int
+	Period time.Duration
+}
+
+// Default returns the settings used for local development.
+func Default() Config {
+	return Config{
+		Env: EnvDevelopment,
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+			ReadinessTimeout:  2 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:         DriverPostgres,
+			Host:           "localhost",
+			Port:           5432,
+			User:           "postgres",
+			Password:       "postgres",
+			Name:           "postgres",
+			SSLMode:        "disable",
+			Path:           "myapp.db",
+			MigrateOnStart: true,
+
+			ReplicaMaxLag:        5 * time.Second,
+			ReplicaCheckInterval: time.Second,
+			ReadYourWrites:       10 * time.Second,
+		},
+		Auth: AuthConfig{
+			JWTSecret:       defaultJWTSecret,
+			AccessTokenTTL:  24 * time.Hour,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			ServiceName:  "myapp",
+			SampleRatio:  1,
+			FilePath:     "traces.json",
+			OTLPEndpoint: "localhost:4318",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Redis: RedisConfig{
+			Addr: "localhost:6379",
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:  true,
+			Store:    "memory",
+			Login:    RateLimitPolicy{Limit: 5, Period: time.Minute},
+			Register: RateLimitPolicy{Limit: 10, Period: time.Hour},
+			Wallet:   RateLimitPolicy{Limit: 30, Period: time.Minute},
+			Default:  RateLimitPolicy{Limit: 120, Period: time.Minute},
+		},
+		Cache: CacheConfig{
+			Store: "none",
+			Size:  10000,
+			TTL:   time.Minute,
+		},
+		Exchange: ExchangeConfig{
+			Spread:   0.005,
+			QuoteTTL: 30 * time.Second,
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:     true,
+			Interval:    30 * time.Second,
+			RetryDelay:  time.Hour,
+			MaxAttempts: 3,
+			BatchSize:   100,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the file at path (skipped
+// when path is empty) and the environment, then validates it.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// DSNString returns the connection string for the configured driver.
+func (db DatabaseConfig) DSNString() string {
+	if db.DSN != "" {
+		return db.DSN
+	}
+	switch db.Driver {
+	case DriverMySQL:
+		// clientFoundRows makes an UPDATE report the rows it matched, as on
+		// the other databases, rather than only those it changed.
+		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&loc=UTC&charset=utf8mb4&multiStatements=true&clientFoundRows=true",
+			db.User, db.Password, db.Host, db.Port, db.Name)
+	case DriverSQLite:
+		return "file:" + db.Path + "?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL"
+	}
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		db.Host, db.User, db.Password, db.Name, db.Port, db.SSLMode)
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Env == EnvDevelopment || c.Env == EnvTest || c.Env == EnvProduction,
+		"env must be one of %s, %s, %s", EnvDevelopment, EnvTest, EnvProduction)
+	check(c.Server.Addr != "", "server.addr is required")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "server.read_header_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay must not be negative")
+	check(c.Server.ReadinessTimeout > 0, "server.readiness_timeout must be positive")
+	check(c.Database.Driver == DriverPostgres || c.Database.Driver == DriverMySQL || c.Database.Driver == DriverSQLite,
+		"database.driver must be one of %s, %s, %s", DriverPostgres, DriverMySQL, DriverSQLite)
+	if c.Database.Driver == DriverSQLite {
+		check(c.Database.DSN != "" || c.Database.Path != "", "database.path is required with database.driver sqlite")
+	} else if c.Database.DSN == "" {
+		check(c.Database.Host != "", "database.host is required")
+		check(c.Database.Port > 0 && c.Database.Port < 65536, "database.port must be between 1 and 65535")
+		check(c.Database.Name != "", "database.name is required")
+	}
+	if len(c.Database.Replicas) > 0 {
+		check(c.Database.ReplicaMaxLag > 0, "database.replica_max_lag must be positive")
+		check(c.Database.ReplicaCheckInterval > 0, "database.replica_check_interval must be positive")
+		check(c.Database.ReplicaCheckInterval < c.Database.ReplicaMaxLag,
+			"database.replica_check_interval must be shorter than database.replica_max_lag")
+	}
+	check(c.Database.ReadYourWrites >= 0, "database.read_your_writes must not be negative")
+	check(c.Auth.JWTSecret != "", "auth.jwt_secret is required")
+	check(c.Auth.AccessTokenTTL > 0, "auth.access_token_ttl must be positive")
+	check(c.Auth.RefreshTokenTTL > c.Auth.AccessTokenTTL, "auth.refresh_token_ttl must be longer than auth.access_token_ttl")
+	check(c.Tracing.Exporter == "none" || c.Tracing.Exporter == "stdout" || c.Tracing.Exporter == "file" || c.Tracing.Exporter == "otlp",
+		"tracing.exporter must be one of none, stdout, file, otlp")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+	check(c.Log.Level == "debug" || c.Log.Level == "info" || c.Log.Level == "warn" || c.Log.Level == "error",
+		"log.level must be one of debug, info, warn, error")
+	check(c.Log.Format == "json" || c.Log.Format == "text", "log.format must be json or text")
+	check(c.RateLimit.Store == "memory" || c.RateLimit.Store == "redis", "ratelimit.store must be memory or redis")
+	if c.RateLimit.Store == "redis" {
+		check(c.Redis.Addr != "", "redis.addr is required with ratelimit.store redis")
+	}
+	check(c.Cache.Store == "none" || c.Cache.Store == "memory" || c.Cache.Store == "redis", "cache.store must be none, memory or redis")
+	if c.Cache.Store == "redis" {
+		check(c.Redis.Addr != "", "redis.addr is required with cache.store redis")
+	}
+	if c.Cache.Store != "none" {
+		check(c.Cache.Size > 0, "cache.size must be positive")
+		check(c.Cache.TTL > 0, "cache.ttl must be positive")
+	}
+	check(c.Exchange.Spread >= 0 && c.Exchange.Spread < 1, "fx.spread must be at least 0 and below 1")
+	check(c.Exchange.QuoteTTL > 0, "fx.quote_ttl must be positive")
+	seen := map[string]bool{}
+	for i, entry := range c.Merchant.APIKeys {
+		code, key, ok := strings.Cut(entry, ":")
+		check(ok && code != "" && len(code) <= 50 && key != "", "merchant.api_keys entry %d must look like CODE:key", i+1)
+		check(!seen[key], "merchant.api_keys entry %d repeats a key", i+1)
+		seen[key] = true
+	}
+	if c.Scheduler.Enabled {
+		check(c.Scheduler.Interval > 0, "scheduler.interval must be positive")
+		check(c.Scheduler.RetryDelay > 0, "scheduler.retry_delay must be positive")
+		check(c.Scheduler.MaxAttempts > 0, "scheduler.max_attempts must be positive")
+		check(c.Scheduler.BatchSize > 0, "scheduler.batch_size must be positive")
+	}
+	for name, policy := range map[string]RateLimitPolicy{
+		"login":    c.RateLimit.Login,
+		"register": c.RateLimit.Register,
+		"wallet":   c.RateLimit.Wallet,
+		"default":  c.RateLimit.Default,
+	} {
+		check(policy.Limit > 0 && policy.Period > 0, "ratelimit.%s needs a positive limit and period", name)
+	}
+	if c.Env == EnvProduction {
+		check(c.Auth.JWTSecret != defaultJWTSecret && len(c.Auth.JWTSecret) >= minJWTSecretLength,
+			"auth.jwt_secret must be set to at least %d characters in production", minJWTSecretLength)
+	}
+
+	return errors.Join(errs...)
+}
+
+// String lists every setting with secrets redacted, so the configuration can
+// be logged safely at start-up.
+func (c Config) String() string {
+	var lines []string
+	for _, f := range c.fields() {
+		value := f.get()
+		if f.secret && value != "" {
+			value = redacted
+		}
+		lines = append(lines, f.key+"="+value)
+	}
+	return strings.Join(lines, " ")
+}
+
+// field binds a dotted setting name, as used in files, to a Config member.
+type field struct {
+	key    string
+	secret bool
+	get    func() string
+	set    func(string) error
+}
+
+func (c *Config) fields() []field {
+	return []field{
+		stringField("env", &c.Env, false),
+		stringField("server.addr", &c.Server.Addr, false),
+		durationField("server.read_timeout", &c.Server.ReadTimeout),
+		durationField("server.read_header_timeout", &c.Server.ReadHeaderTimeout),
+		durationField("server.write_timeout", &c.Server.WriteTimeout),
+		durationField("server.idle_timeout", &c.Server.IdleTimeout),
+		durationField("server.shutdown_timeout", &c.Server.ShutdownTimeout),
+		durationField("server.shutdown_delay", &c.Server.ShutdownDelay),
+		durationField("server.readiness_timeout", &c.Server.ReadinessTimeout),
+		stringField("database.driver", &c.Database.Driver, false),
+		stringField("database.dsn", &c.Database.DSN, true),
+		stringField("database.host", &c.Database.Host, false),
+		intField("database.port", &c.Database.Port),
+		stringField("database.user", &c.Database.User, false),
+		stringField("database.password", &c.Database.Password, true),
+		stringField("database.name", &c.Database.Name, false),
+		stringField("database.sslmode", &c.Database.SSLMode, false),
+		stringField("database.path", &c.Database.Path, false),
+		boolField("database.migrate_on_start", &c.Database.MigrateOnStart),
+		listField("database.replicas", &c.Database.Replicas, true),
+		durationField("database.replica_max_lag", &c.Database.ReplicaMaxLag),
+		durationField("database.replica_check_interval", &c.Database.ReplicaCheckInterval),
+		durationField("database.read_your_writes", &c.Database.ReadYourWrites),
+		stringField("auth.jwt_secret", &c.Auth.JWTSecret, true),
+		durationField("auth.access_token_ttl", &c.Auth.AccessTokenTTL),
+		durationField("auth.refresh_token_ttl", &c.Auth.RefreshTokenTTL),
+		stringField("tracing.exporter", &c.Tracing.Exporter, false),
+		stringField("tracing.service_name", &c.Tracing.ServiceName, false),
+		floatField("tracing.sample_ratio", &c.Tracing.SampleRatio),
+		stringField("tracing.file_path", &c.Tracing.FilePath, false),
+		stringField("tracing.otlp_endpoint", &c.Tracing.OTLPEndpoint, false),
+		boolField("tracing.otlp_insecure", &c.Tracing.OTLPInsecure),
+		stringField("log.level", &c.Log.Level, false),
+		stringField("log.format", &c.Log.Format, false),
+		stringField("redis.addr", &c.Redis.Addr, false),
+		stringField("redis.password", &c.Redis.Password, true),
+		intField("redis.db", &c.Redis.DB),
+		boolField("ratelimit.enabled", &c.RateLimit.Enabled),
+		stringField("ratelimit.store", &c.RateLimit.Store, false),
+		intField("ratelimit.login.limit", &c.RateLimit.Login.Limit),
+		durationField("ratelimit.login.period", &c.RateLimit.Login.Period),
+		intField("ratelimit.register.limit", &c.RateLimit.Register.Limit),
+		durationField("ratelimit.register.period", &c.RateLimit.Register.Period),
+		intField("ratelimit.wallet.limit", &c.RateLimit.Wallet.Limit),
+		durationField("ratelimit.wallet.period", &c.RateLimit.Wallet.Period),
+		intField("ratelimit.default.limit", &c.RateLimit.Default.Limit),
+		durationField("ratelimit.default.period", &c.RateLimit.Default.Period),
+		stringField("cache.store", &c.Cache.Store, false),
+		intField("cache.size", &c.Cache.Size),
+		durationField("cache.ttl", &c.Cache.TTL),
+		stringField("fx.rates_file", &c.Exchange.RatesFile, false),
+		floatField("fx.spread", &c.Exchange.Spread),
+		durationField("fx.quote_ttl", &c.Exchange.QuoteTTL),
+		listField("merchant.api_keys", &c.Merchant.APIKeys, true),
+		boolField("scheduler.enabled", &c.Scheduler.Enabled),
+		durationField("scheduler.interval", &c.Scheduler.Interval),
+		durationField("scheduler.retry_delay", &c.Scheduler.RetryDelay),
+		intField("scheduler.max_attempts", &c.Scheduler.MaxAttempts),
+		intField("scheduler.batch_size", &c.Scheduler.BatchSize),
+	}
+}
+
+func stringField(key string, ptr *string, secret bool) field {
+	return field{
+		key:    key,
+		secret: secret,
+		get:    func() string { return *ptr },
+		set:    func(value string) error { *ptr = value; return nil },
+	}
+}
+
+// listField holds comma-separated values; files may also use a list.
+func listField(key string, ptr *[]string, secret bool) field {
+	return field{
+		key:    key,
+		secret: secret,
+		get:    func() string { return strings.Join(*ptr, ",") },
+		set: func(value string) error {
+			var values []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					values = append(values, item)
+				}
+			}
+			*ptr = values
+			return nil
+		},
+	}
+}
+
+func intField(key string, ptr *int) field {
+	return field{
+		key: key,
+		get: func() string { return strconv.Itoa(*ptr) },
+		set: func(value string) error {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			*ptr = parsed
+			return nil
+		},
+	}
+}
+
+func floatField(key string, ptr *float64) field {
+	return field{
+		key: key,
+		get: func() string { return strconv.FormatFloat(*ptr, 'g', -1, 64) },
+		set: func(value string) error {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return err
+			}
+			*ptr = parsed
+			return nil
+		},
+	}
+}
+
+func boolField(key string, ptr *bool) field {
+	return field{
+		key: key,
+		get: func() string { return strconv.FormatBool(*ptr) },
+		set: func(value string) error {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			*ptr = parsed
+			return nil
+		},
+	}
+}
+
+func durationField(key string, ptr *time.Duration) field {
+	return field{
+		key: key,
+		get: func() string { return ptr.String() },
+		set: func(value string) error {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			*ptr = parsed
+			return nil
+		},
+	}
+}
+
+// envName maps a dotted key to its variable, e.g. auth.jwt_secret to
+// MYAPP_AUTH_JWT_SECRET.
+func envName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	for _, f := range c.fields() {
+		name := envName(f.key)
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := f.set(value); err != nil {
+			return fmt.Errorf("config: %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("config: unsupported file type %q, use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("config: parsing %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", raw, values)
+
+	known := map[string]field{}
+	for _, f := range c.fields() {
+		known[f.key] = f
+	}
+
+	var unknown []string
+	for key, value := range values {
+		f, ok := known[key]
+		if !ok {
+			unknown = append(unknown, key)
+			continue
+		}
+		if err := f.set(value); err != nil {
+			return fmt.Errorf("config: %s in %s: %w", key, path, err)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("config: unknown settings in %s: %s", path, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// flatten turns nested tables into dotted keys with string values.
+func flatten(prefix string, raw map[string]interface{}, out map[string]string) {
+	for key, value := range raw {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			flatten(key, nested, out)
+			continue
+		}
+		if list, ok := value.([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			out[key] = strings.Join(items, ",")
+			continue
+		}
+		out[key] = fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestDefaultsAreValid(t *testing.T) {
+	cfg, err := Load("")
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":8080", cfg.Server.Addr)
+	assert.Equal(t, "host=localhost user=postgres password=postgres dbname=postgres port=5432 sslmode=disable", cfg.Database.DSNString())
+	assert.Equal(t, 24*time.Hour, cfg.Auth.AccessTokenTTL)
+}
+
+func TestLoadYAMLFile(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  addr: ":9090"
+database:
+  host: db.internal
+  port: 6432
+auth:
+  access_token_ttl: 15m
+ratelimit:
+  login:
+    limit: 3
+    period: 30s
+`)
+
+	cfg, err := Load(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.Server.Addr)
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, 6432, cfg.Database.Port)
+	assert.Equal(t, 15*time.Minute, cfg.Auth.AccessTokenTTL)
+	assert.Equal(t, RateLimitPolicy{Limit: 3, Period: 30 * time.Second}, cfg.RateLimit.Login)
+	assert.Equal(t, "postgres", cfg.Database.User, "unset values keep their default")
+}
+
+func TestLoadTOMLFile(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[database]
+dsn = "postgres://app:secret@db:5432/wallet"
+
+[auth]
+refresh_token_ttl = "720h"
+`)
+
+	cfg, err := Load(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://app:secret@db:5432/wallet", cfg.Database.DSNString())
+	assert.Equal(t, 720*time.Hour, cfg.Auth.RefreshTokenTTL)
+}
+
+func TestDSNPerDriver(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Driver = DriverMySQL
+	cfg.Database.Port = 3306
+	assert.Equal(t, "postgres:postgres@tcp(localhost:3306)/postgres?parseTime=true&loc=UTC&charset=utf8mb4&multiStatements=true&clientFoundRows=true",
+		cfg.Database.DSNString())
+
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.Path = "/var/lib/myapp/wallet.db"
+	cfg.Database.Host = ""
+	assert.NoError(t, cfg.Validate(), "sqlite needs no host")
+	assert.True(t, strings.HasPrefix(cfg.Database.DSNString(), "file:/var/lib/myapp/wallet.db?"))
+
+	cfg.Database.Driver = "oracle"
+	assert.ErrorContains(t, cfg.Validate(), "database.driver must be one of")
+}
+
+func TestReplicasFromListOrCommas(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+database:
+  replicas:
+    - host=replica-1 dbname=postgres
+    - host=replica-2 dbname=postgres
+`)
+	cfg, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"host=replica-1 dbname=postgres", "host=replica-2 dbname=postgres"}, cfg.Database.Replicas)
+	assert.Contains(t, cfg.String(), "database.replicas=[REDACTED]")
+
+	t.Setenv("MYAPP_DATABASE_REPLICAS", "postgres://replica-3/app, postgres://replica-4/app")
+	cfg, err = Load("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"postgres://replica-3/app", "postgres://replica-4/app"}, cfg.Database.Replicas)
+
+	cfg.Database.ReplicaCheckInterval = time.Minute
+	assert.ErrorContains(t, cfg.Validate(), "database.replica_check_interval must be shorter")
+}
+
+func TestMerchantKeys(t *testing.T) {
+	t.Setenv("MYAPP_MERCHANT_API_KEYS", "TOKO:k3y-1, TOKO:k3y-2,WARUNG:k3y-3")
+	cfg, err := Load("")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"k3y-1": "TOKO", "k3y-2": "TOKO", "k3y-3": "WARUNG"}, cfg.Merchant.Keys())
+	assert.NotContains(t, cfg.String(), "k3y")
+
+	cfg.Merchant.APIKeys = []string{"TOKO:k3y-1", "no-code", "WARUNG:k3y-1"}
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, "merchant.api_keys entry 2 must look like CODE:key")
+	assert.ErrorContains(t, err, "merchant.api_keys entry 3 repeats a key")
+}
+
+func TestEnvironmentOverridesFile(t *testing.T) {
+	path := writeFile(t, "config.yml", "server:\n  addr: \":9090\"\n")
+	t.Setenv("MYAPP_SERVER_ADDR", ":7070")
+	t.Setenv("MYAPP_DATABASE_PORT", "15432")
+
+	cfg, err := Load(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":7070", cfg.Server.Addr)
+	assert.Equal(t, 15432, cfg.Database.Port)
+}
+
+func TestLoadRejectsBadInput(t *testing.T) {
+	_, err := Load(writeFile(t, "config.yaml", "server:\n  adr: \":9090\"\n"))
+	assert.ErrorContains(t, err, "unknown settings")
+	assert.ErrorContains(t, err, "server.adr")
+
+	_, err = Load(writeFile(t, "config.json", "{}"))
+	assert.ErrorContains(t, err, "unsupported file type")
+
+	t.Setenv("MYAPP_AUTH_ACCESS_TOKEN_TTL", "a day")
+	_, err = Load("")
+	assert.ErrorContains(t, err, "MYAPP_AUTH_ACCESS_TOKEN_TTL")
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.Env = EnvProduction
+	cfg.Database.Port = 0
+	cfg.Auth.RefreshTokenTTL = time.Hour
+	cfg.Exchange.Spread = 1
+	cfg.Scheduler.MaxAttempts = 0
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, "database.port")
+	assert.ErrorContains(t, err, "auth.refresh_token_ttl")
+	assert.ErrorContains(t, err, "fx.spread")
+	assert.ErrorContains(t, err, "scheduler.max_attempts")
+	assert.ErrorContains(t, err, "auth.jwt_secret must be set")
+
+	cfg = Default()
+	cfg.Env = EnvProduction
+	cfg.Auth.JWTSecret = strings.Repeat("s", 32)
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestStringRedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "hunter2"
+	cfg.Auth.JWTSecret = "super-secret-signing-key"
+
+	logged := cfg.String()
+
+	assert.Contains(t, logged, "database.host=localhost")
+	assert.Contains(t, logged, "database.password=[REDACTED]")
+	assert.NotContains(t, logged, "hunter2")
+	assert.NotContains(t, logged, "super-secret-signing-key")
+}
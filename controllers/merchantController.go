@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"myapp/apperrors"
+	"myapp/dto"
+	"myapp/metrics"
+	"myapp/render"
+	"myapp/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// MerchantKeyHeader carries a merchant's API key.
+const MerchantKeyHeader = "X-Merchant-Key"
+
+// MerchantController serves merchants, who authenticate with an API key
+// instead of a user token.
+type MerchantController struct {
+	wallet *services.WalletService
+	// keys maps every API key to the code of its merchant.
+	keys map[string]string
+}
+
+func NewMerchantController(wallet *services.WalletService, keys map[string]string) *MerchantController {
+	return &MerchantController{wallet: wallet, keys: keys}
+}
+
+// Refund refunds one of the merchant's payments, in full or in part.
+func (h *MerchantController) Refund(c *gin.Context) {
+	merchant, err := h.authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		render.Error(c, apperrors.ErrPaymentNotFound.Wrap(err))
+		return
+	}
+
+	var request dto.RefundRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
+		return
+	}
+
+	result, err := h.wallet.Refund(c.Request.Context(), services.RefundOrder{
+		PaymentID: paymentID,
+		Amount:    request.Amount,
+		Merchant:  merchant,
+		Reason:    request.Reason,
+	})
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	metrics.RecordAmount(c, result.Refund.Amount, result.Refund.Currency)
+	c.JSON(http.StatusOK, dto.Success(dto.NewRefundResponse(result.Refund, result.Payment)))
+}
+
+// authenticate returns the code of the merchant whose key the request
+// carries. Keys are compared in constant time.
+func (h *MerchantController) authenticate(c *gin.Context) (string, error) {
+	given := []byte(c.GetHeader(MerchantKeyHeader))
+	if len(given) > 0 {
+		for key, merchant := range h.keys {
+			if subtle.ConstantTimeCompare(given, []byte(key)) == 1 {
+				return merchant, nil
+			}
+		}
+	}
+	return "", apperrors.ErrUnauthenticated
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"net/http"
+
+	"myapp/apperrors"
+	"myapp/dto"
+	"myapp/render"
+	"myapp/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PocketController serves the caller's pockets.
+type PocketController struct {
+	wallet *services.WalletService
+}
+
+func NewPocketController(wallet *services.WalletService) *PocketController {
+	return &PocketController{wallet: wallet}
+}
+
+func (h *PocketController) List(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	pockets, err := h.wallet.Pockets(c.Request.Context(), userID)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	result := make([]dto.PocketResponse, len(pockets))
+	for i, pocket := range pockets {
+		result[i] = dto.NewPocketResponse(pocket)
+	}
+	c.JSON(http.StatusOK, dto.Success(result))
+}
+
+func (h *PocketController) Create(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	var request dto.CreatePocketRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
+		return
+	}
+
+	pocket, err := h.wallet.CreatePocket(c.Request.Context(), userID, request.Name, request.Currency)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.Success(dto.NewPocketResponse(pocket)))
+}
+
+func (h *PocketController) Rename(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	pocketID, err := pocketParam(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	var request dto.RenamePocketRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
+		return
+	}
+
+	pocket, err := h.wallet.RenamePocket(c.Request.Context(), userID, pocketID, request.Name)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.Success(dto.NewPocketResponse(pocket)))
+}
+
+// Close closes a pocket, moving what it still holds to the main balance.
+func (h *PocketController) Close(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	pocketID, err := pocketParam(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	pocket, movement, err := h.wallet.ClosePocket(c.Request.Context(), userID, pocketID)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.Success(dto.NewClosedPocketResponse(pocket, movement)))
+}
+
+// Move moves money between the caller's pockets and main balance.
+func (h *PocketController) Move(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	var request dto.MovePocketRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
+		return
+	}
+
+	movement, err := h.wallet.MovePocketMoney(c.Request.Context(), services.PocketMove{
+		UserID: userID,
+		From:   request.FromPocketID,
+		To:     request.ToPocketID,
+		Amount: request.Amount,
+	})
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.Success(dto.NewPocketMovementResponse(movement)))
+}
+
+// pocketParam returns the pocket ID in the path. IDs that cannot be pockets
+// are not found.
+func pocketParam(c *gin.Context) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, apperrors.ErrPocketNotFound.Wrap(err)
+	}
+	return id, nil
+}
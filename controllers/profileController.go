@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"myapp/apperrors"
+	"myapp/dto"
+	"myapp/middleware"
+	"myapp/models"
+	"myapp/render"
+	"myapp/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ProfileController serves the caller's own profile.
+type ProfileController struct {
+	users *services.UserService
+}
+
+func NewProfileController(users *services.UserService) *ProfileController {
+	return &ProfileController{users: users}
+}
+
+func (h *ProfileController) GetProfile(c *gin.Context) {
+	user, err := h.currentUser(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	c.Header("ETag", etag(user.Version))
+	c.JSON(http.StatusOK, dto.Success(dto.NewProfileResponse(user)))
+}
+
+// PatchProfile applies a JSON Merge Patch to the caller's profile. The request
+// must carry the ETag of the profile it was based on in If-Match, so that two
+// devices editing at once cannot overwrite each other's changes.
+func (h *ProfileController) PatchProfile(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		render.Error(c, apperrors.ErrPreconditionRequired)
+		return
+	}
+
+	var request dto.ProfilePatchRequest
+	if err := decodeStrict(c.Request.Body, &request); err != nil {
+		render.Error(c, apperrors.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	if errs := request.Validate(); len(errs) > 0 {
+		render.Error(c, apperrors.ErrValidationFailed.WithFields(errs))
+		return
+	}
+
+	// The ETag is checked against the primary, never a cache or replica.
+	user, err := h.users.GetLatest(c.Request.Context(), userID)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	middleware.SetLanguage(c, user.Language)
+
+	if !etagMatches(ifMatch, user.Version) {
+		c.Header("ETag", etag(user.Version))
+		render.Error(c, apperrors.ErrVersionConflict)
+		return
+	}
+
+	changes := request.Apply(&user)
+	if len(changes) > 0 {
+		if err := h.users.UpdateProfile(c.Request.Context(), &user, changes); err != nil {
+			render.Error(c, err)
+			return
+		}
+	}
+	middleware.SetLanguage(c, user.Language)
+
+	c.Header("ETag", etag(user.Version))
+	c.JSON(http.StatusOK, dto.Success(dto.NewProfileResponse(user)))
+}
+
+// UpdateProfile replaces the caller's profile. If-Match is optional here for
+// backwards compatibility, but the write is still rejected when the profile
+// changed between reading and saving it.
+func (h *ProfileController) UpdateProfile(c *gin.Context) {
+	user, err := h.latestUser(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" && !etagMatches(ifMatch, user.Version) {
+		c.Header("ETag", etag(user.Version))
+		render.Error(c, apperrors.ErrVersionConflict)
+		return
+	}
+
+	// Bind JSON request body to struct
+	var request dto.UpdateProfileRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
+		return
+	}
+
+	// Update user fields
+	user.FirstName = request.FirstName
+	user.LastName = request.LastName
+	user.Address = request.Address
+
+	changes := map[string]interface{}{
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"address":    user.Address,
+	}
+	if err := h.users.UpdateProfile(c.Request.Context(), &user, changes); err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	c.Header("ETag", etag(user.Version))
+	c.JSON(http.StatusOK, dto.Success(dto.NewProfileResponse(user)))
+}
+
+// currentUser loads the caller and switches the response to their language.
+func (h *ProfileController) currentUser(c *gin.Context) (models.User, error) {
+	return h.loadUser(c, h.users.Get)
+}
+
+// latestUser is currentUser read from the primary, for writes.
+func (h *ProfileController) latestUser(c *gin.Context) (models.User, error) {
+	return h.loadUser(c, h.users.GetLatest)
+}
+
+func (h *ProfileController) loadUser(c *gin.Context, load func(context.Context, uuid.UUID) (models.User, error)) (models.User, error) {
+	userID, err := authenticate(c)
+	if err != nil {
+		return models.User{}, err
+	}
+	user, err := load(c.Request.Context(), userID)
+	if err != nil {
+		return models.User{}, err
+	}
+	middleware.SetLanguage(c, user.Language)
+	return user, nil
+}
+
+// decodeStrict decodes a single JSON object into v, rejecting members that v
+// does not declare so that read-only fields cannot be patched by accident.
+func decodeStrict(body io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("request body must contain a single JSON object")
+	}
+	return nil
+}
+
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// etagMatches reports whether an If-Match header value matches version. The
+// header may list several ETags or be the wildcard "*".
+func etagMatches(header string, version int64) bool {
+	current := etag(version)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == current {
+			return true
+		}
+	}
+	return false
+}
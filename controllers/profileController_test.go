@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEtagMatches(t *testing.T) {
+	assert.Equal(t, `"3"`, etag(3))
+
+	assert.True(t, etagMatches(`"3"`, 3))
+	assert.True(t, etagMatches(`"1", "3"`, 3))
+	assert.True(t, etagMatches(`*`, 3))
+	assert.False(t, etagMatches(`"2"`, 3))
+	assert.False(t, etagMatches(`W/"3"`, 3))
+}
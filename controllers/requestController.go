@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"net/http"
+
+	"myapp/apperrors"
+	"myapp/background"
+	"myapp/dto"
+	"myapp/metrics"
+	"myapp/middleware"
+	"myapp/models"
+	"myapp/render"
+	"myapp/services"
+	"myapp/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestController serves money requests: the caller asks another user to
+// pay them, and answers the requests made of them.
+type RequestController struct {
+	wallet *services.WalletService
+	// workers runs the transfers of accepted requests, like those of
+	// UserController.
+	workers *background.Group
+}
+
+func NewRequestController(wallet *services.WalletService, workers *background.Group) *RequestController {
+	return &RequestController{wallet: wallet, workers: workers}
+}
+
+func (h *RequestController) Create(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	var request dto.RequestMoneyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
+		return
+	}
+
+	created, err := h.wallet.RequestMoney(c.Request.Context(), services.MoneyRequestOrder{
+		From:     userID,
+		Payer:    request.TargetUser,
+		Amount:   request.Amount,
+		Currency: request.Currency,
+		Remarks:  request.Remarks,
+	})
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.Success(dto.NewMoneyRequestResponse(created)))
+}
+
+// Incoming lists the pending requests the caller is asked to pay.
+func (h *RequestController) Incoming(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	requests, err := h.wallet.IncomingRequests(c.Request.Context(), userID)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	renderRequests(c, requests)
+}
+
+// Outgoing lists the requests the caller made, however they were answered.
+func (h *RequestController) Outgoing(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	requests, err := h.wallet.OutgoingRequests(c.Request.Context(), userID)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	renderRequests(c, requests)
+}
+
+// Accept pays a request made of the caller with a transfer to the
+// requester.
+func (h *RequestController) Accept(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	id, err := requestParam(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	// The transfer must not be abandoned half-way if the client goes away.
+	type acceptResult struct {
+		accepted services.AcceptedRequest
+		err      error
+	}
+	responseChan := make(chan acceptResult, 1)
+	ctx := tracing.Detach(c.Request.Context())
+	err = h.workers.Go(func() {
+		accepted, err := h.wallet.AcceptRequest(ctx, userID, id)
+		responseChan <- acceptResult{accepted: accepted, err: err}
+	})
+	if err != nil {
+		render.Error(c, apperrors.ErrServiceUnavailable.Wrap(err))
+		return
+	}
+
+	response := <-responseChan
+	if response.err != nil {
+		render.Error(c, response.err)
+		return
+	}
+	middleware.SetLanguage(c, response.accepted.From.Language)
+
+	transfer := dto.NewTransferResponse(response.accepted.Transfer)
+	result := dto.NewMoneyRequestResponse(response.accepted.Request)
+	result.Transfer = &transfer
+	metrics.RecordAmount(c, transfer.Amount, transfer.Currency)
+	c.JSON(http.StatusOK, dto.Success(result))
+}
+
+func (h *RequestController) Decline(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	id, err := requestParam(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	declined, err := h.wallet.DeclineRequest(c.Request.Context(), userID, id)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.Success(dto.NewMoneyRequestResponse(declined)))
+}
+
+// Cancel withdraws a request the caller made.
+func (h *RequestController) Cancel(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	id, err := requestParam(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	cancelled, err := h.wallet.CancelRequest(c.Request.Context(), userID, id)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.Success(dto.NewMoneyRequestResponse(cancelled)))
+}
+
+func renderRequests(c *gin.Context, requests []models.MoneyRequest) {
+	result := make([]dto.MoneyRequestResponse, len(requests))
+	for i, request := range requests {
+		result[i] = dto.NewMoneyRequestResponse(request)
+	}
+	c.JSON(http.StatusOK, dto.Success(result))
+}
+
+// requestParam returns the money request ID in the path. IDs that cannot be
+// money requests are not found.
+func requestParam(c *gin.Context) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, apperrors.ErrRequestNotFound.Wrap(err)
+	}
+	return id, nil
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"net/http"
+
+	"myapp/apperrors"
+	"myapp/dto"
+	"myapp/render"
+	"myapp/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ScheduleController serves the caller's scheduled transfers. They are
+// made later by the scheduler, not by these handlers.
+type ScheduleController struct {
+	wallet *services.WalletService
+}
+
+func NewScheduleController(wallet *services.WalletService) *ScheduleController {
+	return &ScheduleController{wallet: wallet}
+}
+
+func (h *ScheduleController) List(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	schedules, err := h.wallet.Schedules(c.Request.Context(), userID)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	result := make([]dto.ScheduledTransferResponse, len(schedules))
+	for i, scheduled := range schedules {
+		result[i] = dto.NewScheduledTransferResponse(scheduled)
+	}
+	c.JSON(http.StatusOK, dto.Success(result))
+}
+
+func (h *ScheduleController) Get(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	id, err := scheduleParam(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	scheduled, err := h.wallet.Schedule(c.Request.Context(), userID, id)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.Success(dto.NewScheduledTransferResponse(scheduled)))
+}
+
+func (h *ScheduleController) Create(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	var request dto.ScheduledTransferRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
+		return
+	}
+
+	scheduled, err := h.wallet.CreateSchedule(c.Request.Context(), scheduleOrder(userID, request))
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.Success(dto.NewScheduledTransferResponse(scheduled)))
+}
+
+// Update replaces a scheduled transfer, which also pauses or resumes it.
+func (h *ScheduleController) Update(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	id, err := scheduleParam(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	var request dto.ScheduledTransferRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
+		return
+	}
+
+	scheduled, err := h.wallet.UpdateSchedule(c.Request.Context(), id, scheduleOrder(userID, request))
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.Success(dto.NewScheduledTransferResponse(scheduled)))
+}
+
+func (h *ScheduleController) Cancel(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	id, err := scheduleParam(c)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+
+	scheduled, err := h.wallet.CancelSchedule(c.Request.Context(), userID, id)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.Success(dto.NewScheduledTransferResponse(scheduled)))
+}
+
+func scheduleOrder(userID uuid.UUID, request dto.ScheduledTransferRequest) services.ScheduleOrder {
+	return services.ScheduleOrder{
+		From:       userID,
+		To:         request.TargetUser,
+		Amount:     request.Amount,
+		Currency:   request.Currency,
+		ToCurrency: request.ToCurrency,
+		Remarks:    request.Remarks,
+		Frequency:  request.Frequency,
+		Cron:       request.Cron,
+		StartAt:    request.StartAt,
+		Paused:     request.Paused,
+	}
+}
+
+// scheduleParam returns the scheduled transfer ID in the path. IDs that
+// cannot be scheduled transfers are not found.
+func scheduleParam(c *gin.Context) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, apperrors.ErrScheduleNotFound.Wrap(err)
+	}
+	return id, nil
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"myapp/dto"
+	"myapp/i18n"
+	"myapp/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var statementColumns = []string{
+	"date", "reference", "type", "amount", "currency", "converted_amount", "converted_currency",
+	"from_pocket", "to_pocket", "remarks", "balance_before", "balance_after", "status",
+}
+
+// writeStatementCSV renders the transaction history as a CSV statement whose
+// header row and transaction types are in the request language. Free text,
+// such as remarks written by other users, goes through safeCell.
+func writeStatementCSV(c *gin.Context, entries []dto.TransactionResponse) {
+	lang := middleware.GetLanguage(c)
+
+	c.Header("Content-Disposition", `attachment; filename="statement.csv"`)
+	c.Status(http.StatusOK)
+	c.Writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+
+	writer := csv.NewWriter(c.Writer)
+
+	header := make([]string, len(statementColumns))
+	for i, column := range statementColumns {
+		header[i] = safeCell(i18n.T(lang, "statement."+column, nil))
+	}
+	_ = writer.Write(header)
+
+	for _, entry := range entries {
+		converted := ""
+		if entry.ConvertedAmount != nil {
+			converted = formatNumber(*entry.ConvertedAmount)
+		}
+		_ = writer.Write([]string{
+			entry.CreatedDate,
+			statementReference(entry),
+			safeCell(i18n.T(lang, "statement.type."+entry.TransactionType, nil)),
+			formatNumber(entry.Amount),
+			entry.Currency,
+			converted,
+			entry.ConvertedCurrency,
+			pocketReference(entry.FromPocketID),
+			pocketReference(entry.ToPocketID),
+			safeCell(entry.Remarks),
+			formatNumber(entry.BalanceBefore),
+			formatNumber(entry.BalanceAfter),
+			entry.Status,
+		})
+	}
+	writer.Flush()
+}
+
+// safeCell keeps a spreadsheet from running text as a formula by quoting
+// values that start like one.
+func safeCell(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
+
+func statementReference(entry dto.TransactionResponse) string {
+	switch {
+	case entry.RequestID != nil:
+		return entry.RequestID.String()
+	case entry.TransferID != nil:
+		return entry.TransferID.String()
+	case entry.PaymentID != nil:
+		return entry.PaymentID.String()
+	case entry.TopUpID != nil:
+		return entry.TopUpID.String()
+	case entry.MovementID != nil:
+		return entry.MovementID.String()
+	case entry.ReversalID != nil:
+		return entry.ReversalID.String()
+	}
+	return ""
+}
+
+// pocketReference is empty for the main balance.
+func pocketReference(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myapp/dto"
+	"myapp/middleware"
+	"myapp/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatementCSVIsLocalised(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Locale())
+
+	topUp := models.TopUp{ID: uuid.New(), Amount: 50000, Currency: "IDR", BalanceAfter: 50000}
+	transfer := models.Transfer{ID: uuid.New(), Amount: 10, Currency: "USD", ToAmount: 158400, ToCurrency: "IDR", Rate: 15840}
+	savings := uuid.New()
+	movement := models.PocketMovement{ID: uuid.New(), ToPocketID: &savings, Amount: 20000, Currency: "IDR"}
+	received := models.Transfer{ID: uuid.New(), FromUserID: uuid.New(), Amount: 5000, Remarks: "=1+1"}
+	router.GET("/statement", func(c *gin.Context) {
+		writeStatementCSV(c, []dto.TransactionResponse{
+			dto.NewTopUpTransaction("user-1", topUp),
+			dto.NewTransferTransaction("user-1", transfer),
+			dto.NewMovementTransaction("user-1", movement),
+			dto.NewReceivedTransferTransaction("user-1", received),
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/statement", nil)
+	req.Header.Set("Accept-Language", "id")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "Tanggal,Referensi,Jenis,Nominal,Mata uang,Nominal konversi,Mata uang konversi,Dari kantong,Ke kantong,Keterangan,Saldo awal,Saldo akhir,Status", lines[0])
+	assert.Contains(t, lines[1], topUp.ID.String()+",Kredit,50000.00,IDR,,,")
+	assert.Contains(t, lines[2], transfer.ID.String()+",Debit,10.00,USD,158400.00,IDR,")
+	assert.Contains(t, lines[3], movement.ID.String()+",Pindah kantong,20000.00,IDR,,,,"+savings.String()+",")
+	if assert.Len(t, lines, 5) {
+		assert.Contains(t, lines[4], ",'=1+1,", "remarks are never read as formulas")
+	}
+}
+
+func TestSafeCell(t *testing.T) {
+	for value, want := range map[string]string{
+		"":                   "",
+		"Dinner":             "Dinner",
+		"=HYPERLINK(\"x\")":  "'=HYPERLINK(\"x\")",
+		"+cmd|' /C calc'!A0": "'+cmd|' /C calc'!A0",
+		"-5":                 "'-5",
+		"@SUM(A1)":           "'@SUM(A1)",
+		"\tx":                "'\tx",
+		"\rx":                "'\rx",
+	} {
+		assert.Equal(t, want, safeCell(value), value)
+	}
+}
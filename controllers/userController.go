@@ -1,483 +1,337 @@
 package controllers
 
 import (
-	"gorm.io/gorm"
-	"myapp/auth"
 	"net/http"
 	"time"
 
-	"myapp/database"
+	"myapp/apperrors"
+	"myapp/auth"
+	"myapp/background"
+	"myapp/dto"
+	"myapp/fx"
+	"myapp/metrics"
+	"myapp/middleware"
 	"myapp/models"
+	"myapp/render"
+	"myapp/services"
+	"myapp/tracing"
+	"myapp/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-func Register(c *gin.Context) {
-	var user models.User
-	if err := c.BindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+// UserController serves registration, login and the wallet endpoints.
+type UserController struct {
+	users  *services.UserService
+	wallet *services.WalletService
+	// workers runs transfers in the background. Shutdown drains it so a
+	// deploy never stops a transfer half-way.
+	workers *background.Group
+}
+
+func NewUserController(users *services.UserService, wallet *services.WalletService, workers *background.Group) *UserController {
+	return &UserController{users: users, wallet: wallet, workers: workers}
+}
+
+func (h *UserController) Register(c *gin.Context) {
+	var request dto.RegisterRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
 		return
 	}
 
-	user.ID = uuid.New()
-	user.CreatedDate = time.Now()
+	user := models.User{
+		ID:          uuid.New(),
+		FirstName:   request.FirstName,
+		LastName:    request.LastName,
+		PhoneNumber: request.PhoneNumber,
+		Address:     request.Address,
+		PIN:         request.PIN,
+		Language:    request.Language,
+		CreatedDate: time.Now(),
+	}
 
-	result := database.DB.Create(&user)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Phone Number already registered"})
+	if err := h.users.Register(c.Request.Context(), &user); err != nil {
+		render.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "SUCCESS",
-		"result": user,
-	})
+	middleware.SetLanguage(c, user.Language)
+	c.JSON(http.StatusOK, dto.Success(dto.NewUserResponse(user)))
 }
 
-func Login(c *gin.Context) {
-	var request struct {
-		PhoneNumber string `json:"phone_number"`
-		PIN         string `json:"pin"`
-	}
+func (h *UserController) Login(c *gin.Context) {
+	var request dto.LoginRequest
 
-	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
 		return
 	}
 
-	var user models.User
-	err := database.DB.Where("phone_number = ?", request.PhoneNumber).First(&user).Error
+	user, err := h.users.Authenticate(c.Request.Context(), request.PhoneNumber, request.PIN)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Phone Number and PIN doesn't match"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error"})
-		}
-		return
-	}
-
-	if user.PIN != request.PIN {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Phone Number and PIN doesn't match"})
+		render.Error(c, err)
 		return
 	}
 
 	// Generate access token
 	accessToken, err := auth.GenerateJWT(user.ID.String(), user.PhoneNumber, "access")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error generating access token"})
+		render.Error(c, err)
 		return
 	}
 
 	// Generate refresh token
 	refreshToken, err := auth.GenerateJWT(user.ID.String(), user.PhoneNumber, "refresh")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error generating refresh token"})
+		render.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": "SUCCESS",
-		"result": gin.H{
-			"access_token":  accessToken,
-			"refresh_token": refreshToken,
-		},
-	})
+	c.JSON(http.StatusOK, dto.Success(dto.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}))
 }
 
-func TopUp(c *gin.Context) {
-	var request struct {
-		Amount float64 `json:"amount"`
-	}
-	// Parse JWT token
-	claims, err := auth.ParseJWT(c.Request.Header.Get("Authorization"))
+func (h *UserController) TopUp(c *gin.Context) {
+	userID, err := authenticate(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthenticated"})
+		render.Error(c, err)
 		return
 	}
 
-	// Bind JSON request to struct
-	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+	var request dto.TopUpRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
 		return
 	}
 
-	// Retrieve user from database
-	var user models.User
-	if err := database.DB.Where("phone_number = ?", claims.PhoneNumber).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error"})
-		}
+	topUp, err := h.wallet.TopUp(c.Request.Context(), userID, request.Amount, services.Account{
+		Currency: request.Currency,
+		PocketID: request.PocketID,
+	})
+	if err != nil {
+		render.Error(c, err)
 		return
 	}
 
-	// Perform top-up operation
-	previousBalance := user.Balance
-	user.Balance += request.Amount
+	metrics.RecordAmount(c, topUp.Amount, topUp.Currency)
+	c.JSON(http.StatusOK, dto.Success(dto.NewTopUpResponse(topUp)))
+}
 
-	// Save updated user balance
-	if err := database.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update balance", "error": err.Error()})
+func (h *UserController) Payment(c *gin.Context) {
+	userID, err := authenticate(c)
+	if err != nil {
+		render.Error(c, err)
 		return
 	}
 
-	// Create top-up record
-	topUp := models.TopUp{
-		UserID:        user.ID,
-		Amount:        request.Amount,
-		BalanceBefore: previousBalance,
-		BalanceAfter:  user.Balance,
-		CreatedDate:   time.Now(),
-	}
-	if err := database.DB.Create(&topUp).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create top-up record", "error": err.Error()})
+	var request dto.PaymentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
 		return
 	}
 
-	// Prepare response
-	response := gin.H{
-		"status": "SUCCESS",
-		"result": gin.H{
-			"top_up_id":      topUp.ID,
-			"amount_top_up":  topUp.Amount,
-			"balance_before": previousBalance,
-			"balance_after":  user.Balance,
-			"created_date":   topUp.CreatedDate.Format("2006-01-02 15:04:05"),
+	payment, err := h.wallet.Pay(c.Request.Context(), userID, services.PaymentOrder{
+		Amount: request.Amount,
+		Account: services.Account{
+			Currency: request.Currency,
+			PocketID: request.PocketID,
 		},
-	}
-
-	c.JSON(http.StatusOK, response)
-}
-
-func Payment(c *gin.Context) {
-	var request struct {
-		Amount  float64 `json:"amount"`
-		Remarks string  `json:"remarks"`
-	}
-
-	// Parse JWT token
-	claims, err := auth.ParseJWT(c.Request.Header.Get("Authorization"))
+		Merchant: request.Merchant,
+		Remarks:  request.Remarks,
+	})
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthenticated"})
+		render.Error(c, err)
 		return
 	}
 
-	// Bind JSON request to struct
-	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
-		return
-	}
+	metrics.RecordAmount(c, payment.Amount, payment.Currency)
+	c.JSON(http.StatusOK, dto.Success(dto.NewPaymentResponse(payment)))
+}
 
-	// Retrieve user from database
-	var user models.User
-	err = database.DB.Where("phone_number = ?", claims.PhoneNumber).First(&user).Error
+func (h *UserController) Transfer(c *gin.Context) {
+	userID, err := authenticate(c)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error"})
-		}
+		render.Error(c, err)
 		return
 	}
 
-	// Check if user has enough balance
-	if user.Balance < request.Amount {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Balance is not enough"})
+	var request dto.TransferRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
 		return
 	}
 
-	// Perform payment operation
-	previousBalance := user.Balance
-	user.Balance -= request.Amount
+	// Create response channel, buffered so the worker never blocks on it
+	responseChan := make(chan transferResult, 1)
 
-	// Save updated user balance
-	err = database.DB.Save(&user).Error
+	// Process transfer in the background
+	// The transfer must not be abandoned half-way if the client goes away, so
+	// the worker keeps the trace of the request but not its cancellation.
+	ctx := tracing.Detach(c.Request.Context())
+	err = h.workers.Go(func() {
+		result, err := h.wallet.Transfer(ctx, services.TransferOrder{
+			From:       userID,
+			To:         request.TargetUser,
+			Amount:     request.Amount,
+			Currency:   request.Currency,
+			ToCurrency: request.ToCurrency,
+			QuoteID:    request.QuoteID,
+			Remarks:    request.Remarks,
+		})
+		responseChan <- transferResult{result: result, err: err}
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update balance"})
+		render.Error(c, apperrors.ErrServiceUnavailable.Wrap(err))
 		return
 	}
 
-	// Create payment record
-	payment := models.Payment{
-		UserID:        user.ID,
-		Amount:        request.Amount,
-		Remarks:       request.Remarks,
-		BalanceBefore: previousBalance,
-		BalanceAfter:  user.Balance,
-		CreatedDate:   time.Now(),
-	}
-	err = database.DB.Create(&payment).Error
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create payment record"})
+	// Receive the response from the background goroutine
+	response := <-responseChan
+	if response.err != nil {
+		render.Error(c, response.err)
 		return
 	}
+	middleware.SetLanguage(c, response.result.From.Language)
 
-	// Prepare response
-	response := gin.H{
-		"status": "SUCCESS",
-		"result": gin.H{
-			"payment_id":     payment.ID,
-			"amount":         payment.Amount,
-			"remarks":        payment.Remarks,
-			"balance_before": payment.BalanceBefore,
-			"balance_after":  payment.BalanceAfter,
-			"created_date":   payment.CreatedDate.Format("2006-01-02 15:04:05"),
-		},
-	}
-
-	c.JSON(http.StatusOK, response)
+	transfer := response.result.Transfer
+	metrics.RecordAmount(c, transfer.Amount, transfer.Currency)
+	c.JSON(http.StatusOK, dto.Success(dto.NewTransferResponse(transfer)))
 }
 
-func Transfer(c *gin.Context) {
-	var request struct {
-		TargetUser uuid.UUID `json:"target_user"`
-		Amount     float64   `json:"amount"`
-		Remarks    string    `json:"remarks"`
-	}
-
-	// Parse JWT token
-	claims, err := auth.ParseJWT(c.Request.Header.Get("Authorization"))
+// Quote offers the caller an exchange rate to use for a transfer.
+func (h *UserController) Quote(c *gin.Context) {
+	userID, err := authenticate(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthenticated"})
+		render.Error(c, err)
 		return
 	}
 
-	// Bind JSON request to struct
-	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+	var request dto.QuoteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		render.Error(c, bindError(err))
 		return
 	}
 
-	// Create response channel
-	responseChan := make(chan gin.H)
-
-	// Process transfer in the background
-	go processTransfer(request, claims.PhoneNumber, responseChan)
-
-	// Receive the response from the background goroutine
-	response := <-responseChan
-	c.JSON(http.StatusOK, response)
-}
-
-func processTransfer(request struct {
-	TargetUser uuid.UUID `json:"target_user"`
-	Amount     float64   `json:"amount"`
-	Remarks    string    `json:"remarks"`
-}, senderPhoneNumber string, responseChan chan gin.H) {
-	var transfer models.Transfer
-
-	// Retrieve sender (from user) from database
-	var fromUser models.User
-	err := database.DB.Where("phone_number = ?", senderPhoneNumber).First(&fromUser).Error
+	quote, err := h.wallet.Quote(c.Request.Context(), userID, request.FromCurrency, request.ToCurrency)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			responseChan <- gin.H{"message": "User not found"}
-		} else {
-			responseChan <- gin.H{"message": "Database error"}
-		}
+		render.Error(c, err)
 		return
 	}
 
-	// Check if sender has enough balance
-	if fromUser.Balance < request.Amount {
-		responseChan <- gin.H{"message": "Balance is not enough"}
-		return
+	var converted float64
+	if request.Amount > 0 {
+		converted = fx.Round(request.Amount * quote.Rate)
 	}
+	c.JSON(http.StatusOK, dto.Success(dto.NewQuoteResponse(quote, request.Amount, converted)))
+}
 
-	// Retrieve receiver (to user) from database
-	var toUser models.User
-	err = database.DB.Where("id = ?", request.TargetUser).First(&toUser).Error
+// Balances lists the caller's balance in every currency they hold.
+func (h *UserController) Balances(c *gin.Context) {
+	userID, err := authenticate(c)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			responseChan <- gin.H{"message": "Target user not found"}
-		} else {
-			responseChan <- gin.H{"message": "Database error"}
-		}
+		render.Error(c, err)
 		return
 	}
 
-	// Perform transfer operation
-	previousBalanceFrom := fromUser.Balance
-	fromUser.Balance -= request.Amount
-	toUser.Balance += request.Amount
-
-	// Save updated balances
-	err = database.DB.Transaction(func(tx *gorm.DB) error {
-		if err = tx.Save(&fromUser).Error; err != nil {
-			return err
-		}
-		if err = tx.Save(&toUser).Error; err != nil {
-			return err
-		}
-		// Create transfer record
-		transfer = models.Transfer{
-			FromUserID:    fromUser.ID,
-			ToUserID:      toUser.ID,
-			Amount:        request.Amount,
-			Remarks:       request.Remarks,
-			BalanceBefore: previousBalanceFrom,
-			BalanceAfter:  fromUser.Balance,
-			CreatedDate:   time.Now(),
-		}
-		if err = tx.Create(&transfer).Error; err != nil {
-			return err
-		}
-		return nil
-	})
-
+	balances, err := h.wallet.Balances(c.Request.Context(), userID)
 	if err != nil {
-		responseChan <- gin.H{"message": "Failed to process transfer", "error": err.Error()}
+		render.Error(c, err)
 		return
 	}
 
-	// Prepare response
-	response := gin.H{
-		"status": "SUCCESS",
-		"result": gin.H{
-			"transfer_id":    transfer.ID,
-			"amount":         request.Amount,
-			"remarks":        request.Remarks,
-			"balance_before": previousBalanceFrom,
-			"balance_after":  fromUser.Balance,
-			"created_date":   time.Now().Format("2006-01-02 15:04:05"),
-		},
+	result := make([]dto.BalanceResponse, len(balances))
+	for i, balance := range balances {
+		result[i] = dto.NewBalanceResponse(balance)
 	}
+	c.JSON(http.StatusOK, dto.Success(result))
+}
 
-	responseChan <- response
+// transferResult is what the transfer worker hands back to the waiting
+// handler.
+type transferResult struct {
+	result services.TransferResult
+	err    error
 }
 
-func Transactions(c *gin.Context) {
-	// Parse JWT token
-	claims, err := auth.ParseJWT(c.Request.Header.Get("Authorization"))
+func (h *UserController) Transactions(c *gin.Context) {
+	userID, err := authenticate(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthenticated"})
+		render.Error(c, err)
 		return
 	}
 
-	// Get transfers for the user
-	var transfers []models.Transfer
-	database.DB.Where("from_user_id = ?", claims.UserID).Find(&transfers)
+	history, err := h.wallet.History(c.Request.Context(), userID)
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	middleware.SetLanguage(c, history.User.Language)
 
-	// Get payments for the user
-	var payments []models.Payment
-	database.DB.Where("user_id = ?", claims.UserID).Find(&payments)
+	// Prepare result array
+	owner := userID.String()
+	result := make([]dto.TransactionResponse, 0,
+		len(history.Transfers)+len(history.Received)+len(history.Payments)+len(history.TopUps)+len(history.Movements)+len(history.Reversals)+len(history.Requests))
 
-	// Get top-ups for the user
-	var topUps []models.TopUp
-	database.DB.Where("user_id = ?", claims.UserID).Find(&topUps)
+	for _, t := range history.Transfers {
+		result = append(result, dto.NewTransferTransaction(owner, t))
+	}
 
-	// Prepare result array
-	var result []gin.H
-
-	// Process transfers
-	for _, t := range transfers {
-		entry := gin.H{
-			"transfer_id":      t.ID,
-			"status":           "SUCCESS",
-			"user_id":          claims.UserID,
-			"transaction_type": "DEBIT",
-			"amount":           t.Amount,
-			"remarks":          t.Remarks,
-			"balance_before":   t.BalanceBefore,
-			"balance_after":    t.BalanceAfter,
-			"created_date":     t.CreatedDate.Format("2006-01-02 15:04:05"),
-		}
-		result = append(result, entry)
-	}
-
-	// Process payments
-	for _, p := range payments {
-		entry := gin.H{
-			"payment_id":       p.ID,
-			"status":           "SUCCESS",
-			"user_id":          claims.UserID,
-			"transaction_type": "DEBIT",
-			"amount":           p.Amount,
-			"remarks":          p.Remarks,
-			"balance_before":   p.BalanceBefore,
-			"balance_after":    p.BalanceAfter,
-			"created_date":     p.CreatedDate.Format("2006-01-02 15:04:05"),
-		}
-		result = append(result, entry)
-	}
-
-	// Process top-ups
-	for _, tu := range topUps {
-		entry := gin.H{
-			"top_up_id":        tu.ID,
-			"status":           "SUCCESS",
-			"user_id":          claims.UserID,
-			"transaction_type": "CREDIT",
-			"amount":           tu.Amount,
-			"balance_before":   tu.BalanceBefore,
-			"balance_after":    tu.BalanceAfter,
-			"created_date":     tu.CreatedDate.Format("2006-01-02 15:04:05"),
-		}
-		result = append(result, entry)
+	for _, t := range history.Received {
+		result = append(result, dto.NewReceivedTransferTransaction(owner, t))
 	}
 
-	// Return the combined results
-	response := gin.H{
-		"status": "SUCCESS",
-		"result": result,
+	for _, p := range history.Payments {
+		result = append(result, dto.NewPaymentTransaction(owner, p))
 	}
 
-	c.JSON(http.StatusOK, response)
-}
+	for _, tu := range history.TopUps {
+		result = append(result, dto.NewTopUpTransaction(owner, tu))
+	}
 
-func UpdateProfile(c *gin.Context) {
-	// Parse JWT token
-	claims, err := auth.ParseJWT(c.Request.Header.Get("Authorization"))
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthenticated"})
-		return
+	for _, m := range history.Movements {
+		result = append(result, dto.NewMovementTransaction(owner, m))
 	}
 
-	// Retrieve current user
-	var user models.User
-	err = database.DB.Where("id = ?", claims.UserID).First(&user).Error
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
-		return
+	for _, r := range history.Reversals {
+		result = append(result, dto.NewReversalTransaction(owner, r))
 	}
 
-	// Bind JSON request body to struct
-	var request struct {
-		FirstName string `json:"first_name"`
-		LastName  string `json:"last_name"`
-		Address   string `json:"address"`
+	for _, mr := range history.Requests {
+		result = append(result, dto.NewRequestTransaction(owner, mr))
 	}
 
-	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+	if c.Query("format") == "csv" {
+		writeStatementCSV(c, result)
 		return
 	}
 
-	// Update user fields
-	user.FirstName = request.FirstName
-	user.LastName = request.LastName
-	user.Address = request.Address
-	user.UpdatedDate = time.Now()
+	// Return the combined results
+	c.JSON(http.StatusOK, dto.Success(result))
+}
 
-	// Save updated user profile to database
-	if err := database.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update profile"})
-		return
+// authenticate returns the ID of the user the request's token was issued
+// to.
+func authenticate(c *gin.Context) (uuid.UUID, error) {
+	claims, err := auth.ParseJWT(c.Request.Header.Get("Authorization"))
+	if err != nil {
+		return uuid.Nil, apperrors.ErrUnauthenticated.Wrap(err)
 	}
-
-	// Prepare response
-	response := gin.H{
-		"status": "SUCCESS",
-		"result": gin.H{
-			"user_id":      user.ID,
-			"first_name":   user.FirstName,
-			"last_name":    user.LastName,
-			"address":      user.Address,
-			"updated_date": user.UpdatedDate.Format("2006-01-02 15:04:05"),
-		},
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return uuid.Nil, apperrors.ErrUnauthenticated.Wrap(err)
 	}
+	return userID, nil
+}
 
-	c.JSON(http.StatusOK, response)
+// bindError reports validation failures per field and anything else, such as
+// malformed JSON, as an invalid request.
+func bindError(err error) error {
+	if fields := validation.FieldErrors(err); fields != nil {
+		return apperrors.ErrValidationFailed.WithFields(fields).Wrap(err)
+	}
+	return apperrors.ErrInvalidRequest.Wrap(err)
 }
@@ -2,52 +2,41 @@ package controllers
 
 import (
 	"encoding/json"
-	"github.com/gin-gonic/gin"
-	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
-	"myapp/database"
-	"myapp/models"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"myapp/background"
+	"myapp/notification"
+	"myapp/services"
+	"myapp/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestLoginSuccess(t *testing.T) {
-	dsn := "host=localhost user=postgres password=postgres dbname=postgres port=5432 sslmode=disable"
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("Failed to connect database: %v", err)
-	}
-	database.DB = db
-
-	// Auto-migrate model
-	err = db.AutoMigrate(&models.User{})
-	if err != nil {
-		t.Fatalf("Failed to migrate database: %v", err)
-	}
+	store := testutil.Store(t)
+	controller := NewUserController(
+		services.NewUserService(store),
+		services.NewWalletService(store, notification.LogNotifier{}),
+		&background.Group{},
+	)
 
 	// Setup Gin
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
 
 	// Endpoint handler
-	router.POST("/login", Login)
+	router.POST("/login", controller.Login)
+
+	// Create mock user
+	testutil.NewUser().WithPhone("08123456789").WithPIN("123456").Create(t, testutil.DB(t))
 
 	// Mock request JSON
 	jsonStr := `{"phone_number": "08123456789", "pin": "123456"}`
 
-	// Create mock user
-	mockUser := models.User{
-		PhoneNumber: "08123456789",
-		PIN:         "123456",
-	}
-	err = db.Create(&mockUser).Error
-	if err != nil {
-		t.Fatalf("Failed to create mock user: %v", err)
-	}
-
 	// Perform request
 	req, err := http.NewRequest("POST", "/login", strings.NewReader(jsonStr))
 	assert.NoError(t, err)
@@ -70,10 +59,4 @@ func TestLoginSuccess(t *testing.T) {
 	result := response["result"].(map[string]interface{})
 	assert.NotNil(t, result["access_token"])
 	assert.NotNil(t, result["refresh_token"])
-
-	// Clean up mock data
-	err = db.Delete(&mockUser).Error
-	if err != nil {
-		t.Fatalf("Failed to delete mock user: %v", err)
-	}
 }
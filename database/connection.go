@@ -1,36 +1,148 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"log"
+	"log/slog"
+	"myapp/config"
+	"myapp/migrate"
+	"myapp/migrations"
 	"myapp/models"
 )
 
 var DB *gorm.DB
 
-func Connect() {
-	var err error
-	dsn := "host=localhost user=postgres password=postgres dbname=postgres port=5432 sslmode=disable"
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// Replicas holds the read replicas opened by ConnectReplicas, in the order
+// they are configured.
+var Replicas []*gorm.DB
+
+func Connect(cfg config.DatabaseConfig) error {
+	db, err := Open(cfg, &gorm.Config{TranslateError: true})
 	if err != nil {
-		log.Fatalf("Unable to connect to database: %v\n", err)
+		return err
+	}
+	DB = db
+
+	slog.Info("connected to database", "driver", cfg.Driver)
+	return nil
+}
+
+// ConnectReplicas opens every replica in cfg.Replicas with the driver of the
+// primary.
+func ConnectReplicas(cfg config.DatabaseConfig) error {
+	for i, dsn := range cfg.Replicas {
+		replicaCfg := cfg
+		replicaCfg.DSN = dsn
+		db, err := Open(replicaCfg, &gorm.Config{TranslateError: true})
+		if err != nil {
+			return fmt.Errorf("replica %d: %w", i+1, err)
+		}
+		Replicas = append(Replicas, db)
+	}
+	if len(Replicas) > 0 {
+		slog.Info("connected to read replicas", "count", len(Replicas))
+	}
+	return nil
+}
+
+// Open opens the database selected by cfg.Driver.
+func Open(cfg config.DatabaseConfig, gormConfig *gorm.Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case config.DriverPostgres, "":
+		dialector = postgres.Open(cfg.DSNString())
+	case config.DriverMySQL:
+		dialector = mysql.Open(cfg.DSNString())
+	case config.DriverSQLite:
+		dialector = sqlite.Open(cfg.DSNString())
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
 	}
 
-	fmt.Println("Connected to the database successfully!")
+	db, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Driver == config.DriverSQLite {
+		// SQLite allows one writer at a time; a single connection queues
+		// writers in the pool instead of failing them with SQLITE_BUSY.
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+	return db, nil
 }
 
-func Migrate() {
-	err := DB.AutoMigrate(
+// Close closes the connection pools of the primary and the replicas,
+// waiting for in-use connections to be returned first.
+func Close() error {
+	var errs []error
+	for _, db := range append([]*gorm.DB{DB}, Replicas...) {
+		if db == nil {
+			continue
+		}
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		errs = append(errs, err)
+	}
+	Replicas = nil
+	return errors.Join(errs...)
+}
+
+// Models lists every model persisted by the application.
+func Models() []interface{} {
+	return []interface{}{
 		&models.User{},
 		&models.TopUp{},
 		&models.Payment{},
 		&models.Transfer{},
-	)
+		&models.AuditLog{},
+		&models.Balance{},
+		&models.Quote{},
+		&models.Pocket{},
+		&models.PocketMovement{},
+		&models.Reversal{},
+		&models.ScheduledTransfer{},
+		&models.MoneyRequest{},
+	}
+}
+
+// Migrator returns the runner for the embedded SQL migrations on db.
+func Migrator(db *gorm.DB) (*migrate.Migrator, error) {
+	all, err := migrate.Load(migrations.FS, db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, db.Dialector.Name(), all)
+}
+
+// Migrate applies every pending migration.
+func Migrate(ctx context.Context) error {
+	migrator, err := Migrator(DB)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		slog.Info("applied migration", "version", migration.Version, "name", migration.Name)
+	}
 	if err != nil {
-		log.Fatalf("Failed to migrate models: %v\n", err)
+		return err
 	}
 
-	fmt.Println("Database migrated successfully!")
+	slog.Info("database migrated", "version", migrator.Latest())
+	return nil
 }
@@ -1,21 +1,43 @@
-package database
+package database_test
 
 import (
+	"context"
 	"github.com/stretchr/testify/assert"
-	"myapp/models"
+	"myapp/config"
+	"myapp/database"
+	"myapp/testutil"
+	"path/filepath"
 	"testing"
 )
 
+// testDatabaseConfig returns the settings for the driver the suite runs
+// against. SQLite gets a fresh database file; Postgres and MySQL are read
+// from MYAPP_* variables so the tests can point at any instance.
+func testDatabaseConfig(t *testing.T) config.DatabaseConfig {
+	driver := testutil.Driver()
+	if driver == config.DriverSQLite {
+		return config.DatabaseConfig{Driver: driver, Path: filepath.Join(t.TempDir(), "myapp.db")}
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.Database.Driver = driver
+	return cfg.Database
+}
+
 // TestConnect tests the database connection
 func TestConnect(t *testing.T) {
 	// Call the Connect function
-	Connect()
+	err := database.Connect(testDatabaseConfig(t))
+	assert.NoError(t, err, "Connecting should not return an error")
+	t.Cleanup(func() { database.Close() })
 
 	// Assert that DB is not nil
-	assert.NotNil(t, DB, "Database connection should not be nil")
+	assert.NotNil(t, database.DB, "Database connection should not be nil")
 
 	// Assert that the database is connected
-	sqlDB, err := DB.DB()
+	sqlDB, err := database.DB.DB()
 	assert.NoError(t, err, "Getting database instance should not return an error")
 	err = sqlDB.Ping()
 	assert.NoError(t, err, "Database should be connected and pingable")
@@ -24,20 +46,22 @@ func TestConnect(t *testing.T) {
 // TestMigrate tests the database migration
 func TestMigrate(t *testing.T) {
 	// Ensure database connection is established
-	Connect()
+	if err := database.Connect(testDatabaseConfig(t)); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
 
 	// Call the Migrate function
-	Migrate()
+	assert.NoError(t, database.Migrate(context.Background()))
 
 	// Check if the tables exist in the database
-	tables := []interface{}{
-		&models.User{},
-		&models.TopUp{},
-		&models.Payment{},
-		&models.Transfer{},
+	for _, table := range database.Models() {
+		assert.True(t, database.DB.Migrator().HasTable(table), "Table should exist in the database")
 	}
 
-	for _, table := range tables {
-		assert.True(t, DB.Migrator().HasTable(table), "Table should exist in the database")
-	}
+	// Running again is a no-op and leaves the schema up to date
+	assert.NoError(t, database.Migrate(context.Background()))
+	migrator, err := database.Migrator(database.DB)
+	assert.NoError(t, err)
+	assert.NoError(t, migrator.Check(context.Background()))
 }
@@ -0,0 +1,105 @@
+package dto
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"myapp/apperrors"
+	"myapp/i18n"
+	"myapp/models"
+)
+
+// PatchString is a string member of a JSON Merge Patch (RFC 7396) document.
+// It distinguishes an absent member (Set is false) from an explicit null,
+// which removes the value.
+type PatchString struct {
+	Set   bool
+	Null  bool
+	Value string
+}
+
+func (s *PatchString) UnmarshalJSON(data []byte) error {
+	s.Set = true
+	if string(data) == "null" {
+		s.Null = true
+		s.Value = ""
+		return nil
+	}
+	return json.Unmarshal(data, &s.Value)
+}
+
+// ProfilePatchRequest is the merge-patch body accepted by PATCH /profile.
+// Members left out of the document keep their current value.
+type ProfilePatchRequest struct {
+	FirstName PatchString `json:"first_name"`
+	LastName  PatchString `json:"last_name"`
+	Address   PatchString `json:"address"`
+	Language  PatchString `json:"language"` // null falls back to Accept-Language
+}
+
+const (
+	maxNameLength    = 50
+	maxAddressLength = 255
+)
+
+// Validate returns the failed rule of every invalid member, keyed by its JSON
+// name. An empty map means the patch can be applied.
+func (p ProfilePatchRequest) Validate() map[string]apperrors.FieldError {
+	errs := map[string]apperrors.FieldError{}
+
+	if p.FirstName.Set && strings.TrimSpace(p.FirstName.Value) == "" {
+		errs["first_name"] = apperrors.FieldError{Rule: "not_blank"}
+	}
+	checkLength(errs, "first_name", p.FirstName, maxNameLength)
+	checkLength(errs, "last_name", p.LastName, maxNameLength)
+	checkLength(errs, "address", p.Address, maxAddressLength)
+
+	if p.Language.Set && !p.Language.Null {
+		if _, ok := i18n.Parse(p.Language.Value); !ok || len(p.Language.Value) != 2 {
+			errs["language"] = apperrors.FieldError{Rule: "oneof", Param: supportedLanguages()}
+		}
+	}
+
+	return errs
+}
+
+// Apply merges the patch into user and returns the changed columns, ready to
+// be passed to gorm's Updates. Members whose value is unchanged are skipped.
+func (p ProfilePatchRequest) Apply(user *models.User) map[string]interface{} {
+	changes := map[string]interface{}{}
+
+	apply := func(column string, patch PatchString, field *string) {
+		if !patch.Set || *field == patch.Value {
+			return
+		}
+		*field = patch.Value
+		changes[column] = patch.Value
+	}
+
+	apply("first_name", p.FirstName, &user.FirstName)
+	apply("last_name", p.LastName, &user.LastName)
+	apply("address", p.Address, &user.Address)
+	apply("language", p.Language, &user.Language)
+
+	return changes
+}
+
+func checkLength(errs map[string]apperrors.FieldError, field string, patch PatchString, max int) {
+	if _, exists := errs[field]; exists {
+		return
+	}
+	if utf8.RuneCountInString(patch.Value) > max {
+		errs[field] = apperrors.FieldError{Rule: "max_length", Param: strconv.Itoa(max)}
+	}
+}
+
+func supportedLanguages() string {
+	languages := i18n.Languages()
+	names := make([]string, len(languages))
+	for i, lang := range languages {
+		names[i] = string(lang)
+	}
+	return strings.Join(names, " ")
+}
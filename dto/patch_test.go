@@ -0,0 +1,60 @@
+package dto
+
+import (
+	"encoding/json"
+	"myapp/apperrors"
+	"myapp/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodePatch(t *testing.T, body string) ProfilePatchRequest {
+	var patch ProfilePatchRequest
+	assert.NoError(t, json.Unmarshal([]byte(body), &patch))
+	return patch
+}
+
+// TestProfilePatchKeepsOmittedFields checks merge-patch semantics for absent and null members
+func TestProfilePatchKeepsOmittedFields(t *testing.T) {
+	user := models.User{FirstName: "Tom", LastName: "Araya", Address: "Jl. Diponegoro No. 215"}
+
+	patch := decodePatch(t, `{"last_name": "Saputro", "address": null}`)
+	assert.Empty(t, patch.Validate())
+
+	changes := patch.Apply(&user)
+
+	assert.Equal(t, "Tom", user.FirstName)
+	assert.Equal(t, "Saputro", user.LastName)
+	assert.Equal(t, "", user.Address)
+	assert.Equal(t, map[string]interface{}{"last_name": "Saputro", "address": ""}, changes)
+}
+
+// TestProfilePatchSkipsUnchangedFields checks that re-sending the current value is not a change
+func TestProfilePatchSkipsUnchangedFields(t *testing.T) {
+	user := models.User{FirstName: "Tom"}
+
+	changes := decodePatch(t, `{"first_name": "Tom"}`).Apply(&user)
+
+	assert.Empty(t, changes)
+}
+
+// TestProfilePatchValidation checks the per-field validation messages
+func TestProfilePatchValidation(t *testing.T) {
+	patch := decodePatch(t, `{"first_name": null, "address": "`+strings.Repeat("a", 256)+`"}`)
+
+	errs := patch.Validate()
+
+	assert.Equal(t, "not_blank", errs["first_name"].Rule)
+	assert.Equal(t, apperrors.FieldError{Rule: "max_length", Param: "255"}, errs["address"])
+	assert.NotContains(t, errs, "last_name")
+}
+
+// TestProfilePatchLanguage checks that only supported languages can be chosen
+func TestProfilePatchLanguage(t *testing.T) {
+	assert.Empty(t, decodePatch(t, `{"language": "en"}`).Validate())
+	assert.Empty(t, decodePatch(t, `{"language": null}`).Validate())
+	assert.Equal(t, "oneof", decodePatch(t, `{"language": "fr"}`).Validate()["language"].Rule)
+	assert.Equal(t, "oneof", decodePatch(t, `{"language": "en-US"}`).Validate()["language"].Rule)
+}
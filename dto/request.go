@@ -0,0 +1,127 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Request structs declare their validation rules in `binding` tags, which gin
+// checks when binding the body. Amounts are in the request's currency, rupiah
+// unless stated otherwise, and may carry at most two decimal places.
+
+// RegisterRequest is the body accepted by POST /register.
+type RegisterRequest struct {
+	FirstName   string `json:"first_name" binding:"required,max=50"`
+	LastName    string `json:"last_name" binding:"max=50"`
+	PhoneNumber string `json:"phone_number" binding:"required,e164"`
+	Address     string `json:"address" binding:"max=255"`
+	PIN         string `json:"pin" binding:"required,numeric,len=6"`
+	Language    string `json:"language" binding:"omitempty,oneof=en id"`
+}
+
+// LoginRequest is the body accepted by POST /login. The phone number is not
+// checked against E.164 so that accounts registered before that rule can
+// still sign in.
+type LoginRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required,max=16"`
+	PIN         string `json:"pin" binding:"required,numeric,len=6"`
+}
+
+// TopUpRequest is the body accepted by POST /topup. Without a pocket the
+// main balance is credited.
+type TopUpRequest struct {
+	Amount   float64   `json:"amount" binding:"required,min=1,max=10000000,decimals=2"`
+	Currency string    `json:"currency" binding:"omitempty,iso4217"`
+	PocketID uuid.UUID `json:"pocket_id"`
+}
+
+// PaymentRequest is the body accepted by POST /pay. Without a pocket the
+// main balance is debited. Naming the merchant lets it refund the payment.
+type PaymentRequest struct {
+	Amount   float64   `json:"amount" binding:"required,min=1,max=50000000,decimals=2"`
+	Currency string    `json:"currency" binding:"omitempty,iso4217"`
+	PocketID uuid.UUID `json:"pocket_id"`
+	Merchant string    `json:"merchant" binding:"max=50"`
+	Remarks  string    `json:"remarks" binding:"max=255"`
+}
+
+// RefundRequest is the body accepted by POST /merchant/payments/:id/refunds.
+// Without an amount whatever is left of the payment is refunded.
+type RefundRequest struct {
+	Amount float64 `json:"amount" binding:"omitempty,gt=0,max=50000000,decimals=2"`
+	Reason string  `json:"reason" binding:"max=255"`
+}
+
+// TransferRequest is the body accepted by POST /transfer. Transfers to the
+// sender's own account are rejected by the handler, which knows the caller.
+// The amount is debited in Currency and credited in ToCurrency, converted
+// at the rate of QuoteID or at the current rate when no quote is given.
+type TransferRequest struct {
+	TargetUser uuid.UUID `json:"target_user" binding:"required"`
+	Amount     float64   `json:"amount" binding:"required,min=1,max=50000000,decimals=2"`
+	Currency   string    `json:"currency" binding:"omitempty,iso4217"`
+	ToCurrency string    `json:"to_currency" binding:"omitempty,iso4217"`
+	QuoteID    uuid.UUID `json:"quote_id"`
+	Remarks    string    `json:"remarks" binding:"max=255"`
+}
+
+// QuoteRequest is the body accepted by POST /quotes. Amount is optional and
+// only used to show what it converts to.
+type QuoteRequest struct {
+	FromCurrency string  `json:"from_currency" binding:"required,iso4217"`
+	ToCurrency   string  `json:"to_currency" binding:"required,iso4217"`
+	Amount       float64 `json:"amount" binding:"omitempty,min=1,max=50000000,decimals=2"`
+}
+
+// CreatePocketRequest is the body accepted by POST /pockets.
+type CreatePocketRequest struct {
+	Name     string `json:"name" binding:"required,max=50"`
+	Currency string `json:"currency" binding:"omitempty,iso4217"`
+}
+
+// RenamePocketRequest is the body accepted by PUT /pockets/:id.
+type RenamePocketRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+// MovePocketRequest is the body accepted by POST /pockets/move. An omitted
+// pocket is the main balance.
+type MovePocketRequest struct {
+	FromPocketID uuid.UUID `json:"from_pocket_id"`
+	ToPocketID   uuid.UUID `json:"to_pocket_id"`
+	Amount       float64   `json:"amount" binding:"required,min=1,max=50000000,decimals=2"`
+}
+
+// ScheduledTransferRequest is the body accepted by POST
+// /scheduled-transfers and PUT /scheduled-transfers/:id. StartAt is an
+// RFC 3339 time, required for ONCE; recurring transfers start at once
+// without it. Cron is a five-field expression evaluated in UTC, required
+// for CRON.
+type ScheduledTransferRequest struct {
+	TargetUser uuid.UUID `json:"target_user" binding:"required"`
+	Amount     float64   `json:"amount" binding:"required,min=1,max=50000000,decimals=2"`
+	Currency   string    `json:"currency" binding:"omitempty,iso4217"`
+	ToCurrency string    `json:"to_currency" binding:"omitempty,iso4217"`
+	Remarks    string    `json:"remarks" binding:"max=255"`
+	Frequency  string    `json:"frequency" binding:"required,oneof=ONCE DAILY WEEKLY MONTHLY CRON"`
+	Cron       string    `json:"cron" binding:"max=100"`
+	StartAt    time.Time `json:"start_at"`
+	Paused     bool      `json:"paused"`
+}
+
+// RequestMoneyRequest is the body accepted by POST /money-requests. It asks
+// TargetUser to pay the caller Amount in Currency.
+type RequestMoneyRequest struct {
+	TargetUser uuid.UUID `json:"target_user" binding:"required"`
+	Amount     float64   `json:"amount" binding:"required,min=1,max=50000000,decimals=2"`
+	Currency   string    `json:"currency" binding:"omitempty,iso4217"`
+	Remarks    string    `json:"remarks" binding:"required,max=255"`
+}
+
+// UpdateProfileRequest is the body accepted by PUT /profile.
+type UpdateProfileRequest struct {
+	FirstName string `json:"first_name" binding:"required,max=50"`
+	LastName  string `json:"last_name" binding:"max=50"`
+	Address   string `json:"address" binding:"max=255"`
+}
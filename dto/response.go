@@ -0,0 +1,653 @@
+package dto
+
+import (
+	"time"
+
+	"myapp/fx"
+	"myapp/models"
+
+	"github.com/google/uuid"
+)
+
+// DateFormat is the layout used for every timestamp returned by the API.
+const DateFormat = "2006-01-02 15:04:05"
+
+const (
+	StatusSuccess = "SUCCESS"
+
+	TransactionCredit = "CREDIT"
+	TransactionDebit  = "DEBIT"
+	// TransactionMove is money moved between the user's own pockets.
+	TransactionMove = "MOVE"
+	// TransactionRequestSent and TransactionRequestReceived are money
+	// requests the user made or was asked to pay. They move no money
+	// themselves.
+	TransactionRequestSent     = "REQUEST_SENT"
+	TransactionRequestReceived = "REQUEST_RECEIVED"
+
+	// Statuses of history entries that were given back in part or in full.
+	StatusPartiallyRefunded = "PARTIALLY_REFUNDED"
+	StatusRefunded          = "REFUNDED"
+	StatusReversed          = "REVERSED"
+)
+
+// Response is the envelope wrapping every successful API result.
+type Response struct {
+	Status string      `json:"status"`
+	Result interface{} `json:"result"`
+}
+
+// Success wraps result in a SUCCESS envelope.
+func Success(result interface{}) Response {
+	return Response{Status: StatusSuccess, Result: result}
+}
+
+// UserResponse is the public view of a models.User. Only the fields listed
+// here are ever serialised; credentials such as the PIN stay on the server.
+type UserResponse struct {
+	UserID      uuid.UUID `json:"user_id"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	PhoneNumber string    `json:"phone_number"`
+	Address     string    `json:"address"`
+	Language    string    `json:"language"`
+	Balance     float64   `json:"balance"`
+	CreatedDate string    `json:"created_date"`
+}
+
+func NewUserResponse(user models.User) UserResponse {
+	return UserResponse{
+		UserID:      user.ID,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		PhoneNumber: user.PhoneNumber,
+		Address:     user.Address,
+		Language:    user.Language,
+		Balance:     user.Balance,
+		CreatedDate: formatDate(user.CreatedDate),
+	}
+}
+
+// LoginResponse carries the token pair issued by POST /login.
+type LoginResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TopUpResponse struct {
+	TopUpID       uuid.UUID `json:"top_up_id"`
+	AmountTopUp   float64   `json:"amount_top_up"`
+	Currency      string    `json:"currency"`
+	BalanceBefore float64   `json:"balance_before"`
+	BalanceAfter  float64   `json:"balance_after"`
+	CreatedDate   string    `json:"created_date"`
+	// PocketID is the pocket credited, omitted for the main balance.
+	PocketID *uuid.UUID `json:"pocket_id,omitempty"`
+}
+
+func NewTopUpResponse(topUp models.TopUp) TopUpResponse {
+	return TopUpResponse{
+		TopUpID:       topUp.ID,
+		AmountTopUp:   topUp.Amount,
+		Currency:      currencyOf(topUp.Currency),
+		BalanceBefore: topUp.BalanceBefore,
+		BalanceAfter:  topUp.BalanceAfter,
+		CreatedDate:   formatDate(topUp.CreatedDate),
+		PocketID:      topUp.PocketID,
+	}
+}
+
+type PaymentResponse struct {
+	PaymentID     uuid.UUID `json:"payment_id"`
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	Remarks       string    `json:"remarks"`
+	BalanceBefore float64   `json:"balance_before"`
+	BalanceAfter  float64   `json:"balance_after"`
+	CreatedDate   string    `json:"created_date"`
+	// PocketID is the pocket debited, omitted for the main balance.
+	PocketID *uuid.UUID `json:"pocket_id,omitempty"`
+}
+
+func NewPaymentResponse(payment models.Payment) PaymentResponse {
+	return PaymentResponse{
+		PaymentID:     payment.ID,
+		Amount:        payment.Amount,
+		Currency:      currencyOf(payment.Currency),
+		Remarks:       payment.Remarks,
+		BalanceBefore: payment.BalanceBefore,
+		BalanceAfter:  payment.BalanceAfter,
+		CreatedDate:   formatDate(payment.CreatedDate),
+		PocketID:      payment.PocketID,
+	}
+}
+
+// TransferResponse shows the amount debited from the sender and what it
+// was converted to for the recipient, which is the same amount when both
+// currencies are.
+type TransferResponse struct {
+	TransferID        uuid.UUID  `json:"transfer_id"`
+	Amount            float64    `json:"amount"`
+	Currency          string     `json:"currency"`
+	ConvertedAmount   float64    `json:"converted_amount"`
+	ConvertedCurrency string     `json:"converted_currency"`
+	ExchangeRate      float64    `json:"exchange_rate"`
+	QuoteID           *uuid.UUID `json:"quote_id,omitempty"`
+	Remarks           string     `json:"remarks"`
+	BalanceBefore     float64    `json:"balance_before"`
+	BalanceAfter      float64    `json:"balance_after"`
+	CreatedDate       string     `json:"created_date"`
+}
+
+func NewTransferResponse(transfer models.Transfer) TransferResponse {
+	conversion := conversionOf(transfer)
+	return TransferResponse{
+		TransferID:        transfer.ID,
+		Amount:            transfer.Amount,
+		Currency:          currencyOf(transfer.Currency),
+		ConvertedAmount:   *conversion.ConvertedAmount,
+		ConvertedCurrency: conversion.ConvertedCurrency,
+		ExchangeRate:      *conversion.ExchangeRate,
+		QuoteID:           transfer.QuoteID,
+		Remarks:           transfer.Remarks,
+		BalanceBefore:     transfer.BalanceBefore,
+		BalanceAfter:      transfer.BalanceAfter,
+		CreatedDate:       formatDate(transfer.CreatedDate),
+	}
+}
+
+// TransactionResponse is one entry of the GET /transactions history. Exactly
+// one of the TransferID, PaymentID, TopUpID, MovementID, ReversalID and
+// RequestID fields is set, except that paid money requests also name their
+// transfer; received transfers and requests name the other user in
+// CounterpartyID. Refunds and
+// reversals name what they gave back in ReversalOf. Transfers made by a
+// scheduled transfer name it in ScheduleID.
+// Transfers also carry the amount the recipient was credited in
+// Conversion, and entries touching pockets name them in Pockets.
+type TransactionResponse struct {
+	TransferID      *uuid.UUID `json:"transfer_id,omitempty"`
+	PaymentID       *uuid.UUID `json:"payment_id,omitempty"`
+	TopUpID         *uuid.UUID `json:"top_up_id,omitempty"`
+	MovementID      *uuid.UUID `json:"movement_id,omitempty"`
+	ReversalID      *uuid.UUID `json:"reversal_id,omitempty"`
+	RequestID       *uuid.UUID `json:"request_id,omitempty"`
+	ReversalOf      *uuid.UUID `json:"reversal_of,omitempty"`
+	ScheduleID      *uuid.UUID `json:"schedule_id,omitempty"`
+	Status          string     `json:"status"`
+	UserID          string     `json:"user_id"`
+	CounterpartyID  *uuid.UUID `json:"counterparty_id,omitempty"`
+	TransactionType string     `json:"transaction_type"`
+	Amount          float64    `json:"amount"`
+	Currency        string     `json:"currency"`
+	Conversion
+	Pockets
+	Remarks       string  `json:"remarks,omitempty"`
+	BalanceBefore float64 `json:"balance_before"`
+	BalanceAfter  float64 `json:"balance_after"`
+	CreatedDate   string  `json:"created_date"`
+}
+
+func NewTransferTransaction(userID string, transfer models.Transfer) TransactionResponse {
+	id := transfer.ID
+	status := transfer.Status
+	if status == "" {
+		status = StatusSuccess
+	}
+	return TransactionResponse{
+		TransferID:      &id,
+		ScheduleID:      transfer.ScheduleID,
+		Status:          status,
+		UserID:          userID,
+		TransactionType: TransactionDebit,
+		Amount:          transfer.Amount,
+		Currency:        currencyOf(transfer.Currency),
+		Conversion:      conversionOf(transfer),
+		Remarks:         transfer.Remarks,
+		BalanceBefore:   transfer.BalanceBefore,
+		BalanceAfter:    transfer.BalanceAfter,
+		CreatedDate:     formatDate(transfer.CreatedDate),
+	}
+}
+
+// NewReceivedTransferTransaction shows a transfer made to the user in the
+// amount and currency they were credited. Transfers record the balances of
+// the sender only, so its balances are zero.
+func NewReceivedTransferTransaction(userID string, transfer models.Transfer) TransactionResponse {
+	id := transfer.ID
+	sender := transfer.FromUserID
+	status := transfer.Status
+	if status == "" {
+		status = StatusSuccess
+	}
+	conversion := conversionOf(transfer)
+	return TransactionResponse{
+		TransferID:      &id,
+		ScheduleID:      transfer.ScheduleID,
+		Status:          status,
+		UserID:          userID,
+		CounterpartyID:  &sender,
+		TransactionType: TransactionCredit,
+		Amount:          *conversion.ConvertedAmount,
+		Currency:        conversion.ConvertedCurrency,
+		Remarks:         transfer.Remarks,
+		CreatedDate:     formatDate(transfer.CreatedDate),
+	}
+}
+
+func NewPaymentTransaction(userID string, payment models.Payment) TransactionResponse {
+	id := payment.ID
+	return TransactionResponse{
+		PaymentID:       &id,
+		Status:          paymentStatus(payment),
+		UserID:          userID,
+		TransactionType: TransactionDebit,
+		Amount:          payment.Amount,
+		Currency:        currencyOf(payment.Currency),
+		Pockets:         Pockets{FromPocketID: payment.PocketID},
+		Remarks:         payment.Remarks,
+		BalanceBefore:   payment.BalanceBefore,
+		BalanceAfter:    payment.BalanceAfter,
+		CreatedDate:     formatDate(payment.CreatedDate),
+	}
+}
+
+func NewTopUpTransaction(userID string, topUp models.TopUp) TransactionResponse {
+	id := topUp.ID
+	return TransactionResponse{
+		TopUpID:         &id,
+		Status:          topUpStatus(topUp),
+		UserID:          userID,
+		TransactionType: TransactionCredit,
+		Amount:          topUp.Amount,
+		Currency:        currencyOf(topUp.Currency),
+		Pockets:         Pockets{ToPocketID: topUp.PocketID},
+		BalanceBefore:   topUp.BalanceBefore,
+		BalanceAfter:    topUp.BalanceAfter,
+		CreatedDate:     formatDate(topUp.CreatedDate),
+	}
+}
+
+// NewMovementTransaction shows a move between pockets with the balances of
+// the pocket the money came from.
+func NewMovementTransaction(userID string, movement models.PocketMovement) TransactionResponse {
+	id := movement.ID
+	return TransactionResponse{
+		MovementID:      &id,
+		Status:          StatusSuccess,
+		UserID:          userID,
+		TransactionType: TransactionMove,
+		Amount:          movement.Amount,
+		Currency:        currencyOf(movement.Currency),
+		Pockets:         Pockets{FromPocketID: movement.FromPocketID, ToPocketID: movement.ToPocketID},
+		BalanceBefore:   movement.BalanceBefore,
+		BalanceAfter:    movement.BalanceAfter,
+		CreatedDate:     formatDate(movement.CreatedDate),
+	}
+}
+
+// NewReversalTransaction shows a refund or reversal, with the reason it was
+// made as its remarks.
+func NewReversalTransaction(userID string, reversal models.Reversal) TransactionResponse {
+	id := reversal.ID
+	pockets := Pockets{ToPocketID: reversal.PocketID}
+	if reversal.TransactionType == TransactionDebit {
+		pockets = Pockets{FromPocketID: reversal.PocketID}
+	}
+	return TransactionResponse{
+		ReversalID:      &id,
+		ReversalOf:      reversalOf(reversal),
+		Status:          reversal.Status,
+		UserID:          userID,
+		TransactionType: reversal.TransactionType,
+		Amount:          reversal.Amount,
+		Currency:        currencyOf(reversal.Currency),
+		Pockets:         pockets,
+		Remarks:         reversal.Reason,
+		BalanceBefore:   reversal.BalanceBefore,
+		BalanceAfter:    reversal.BalanceAfter,
+		CreatedDate:     formatDate(reversal.CreatedDate),
+	}
+}
+
+// NewRequestTransaction shows a money request the user made or was asked to
+// pay. Its balances are zero, as the request itself moves no money.
+func NewRequestTransaction(userID string, request models.MoneyRequest) TransactionResponse {
+	id := request.ID
+	transactionType, counterparty := TransactionRequestSent, request.PayerID
+	if request.RequesterID.String() != userID {
+		transactionType, counterparty = TransactionRequestReceived, request.RequesterID
+	}
+	return TransactionResponse{
+		RequestID:       &id,
+		TransferID:      request.TransferID,
+		Status:          request.Status,
+		UserID:          userID,
+		CounterpartyID:  &counterparty,
+		TransactionType: transactionType,
+		Amount:          request.Amount,
+		Currency:        currencyOf(request.Currency),
+		Remarks:         request.Remarks,
+		CreatedDate:     formatDate(request.CreatedDate),
+	}
+}
+
+func paymentStatus(payment models.Payment) string {
+	switch {
+	case payment.RefundedAmount <= 0:
+		return StatusSuccess
+	case fx.Round(payment.Amount-payment.RefundedAmount) > 0:
+		return StatusPartiallyRefunded
+	default:
+		return StatusRefunded
+	}
+}
+
+func topUpStatus(topUp models.TopUp) string {
+	if topUp.ReversedAt != nil {
+		return StatusReversed
+	}
+	return StatusSuccess
+}
+
+// reversalOf is the ID of the record a reversal gave back.
+func reversalOf(reversal models.Reversal) *uuid.UUID {
+	switch {
+	case reversal.PaymentID != nil:
+		return reversal.PaymentID
+	case reversal.TopUpID != nil:
+		return reversal.TopUpID
+	default:
+		return reversal.TransferID
+	}
+}
+
+// Pockets are the pockets money of a history entry came from and went to.
+// The main balance is omitted.
+type Pockets struct {
+	FromPocketID *uuid.UUID `json:"from_pocket_id,omitempty"`
+	ToPocketID   *uuid.UUID `json:"to_pocket_id,omitempty"`
+}
+
+// Conversion is what a transfer was credited to its recipient.
+type Conversion struct {
+	ConvertedAmount   *float64 `json:"converted_amount,omitempty"`
+	ConvertedCurrency string   `json:"converted_currency,omitempty"`
+	ExchangeRate      *float64 `json:"exchange_rate,omitempty"`
+}
+
+func conversionOf(transfer models.Transfer) Conversion {
+	amount, rate := transfer.ToAmount, transfer.Rate
+	if transfer.ToCurrency == "" {
+		// Transfers made before conversions were recorded
+		amount, rate = transfer.Amount, 1
+	}
+	return Conversion{
+		ConvertedAmount:   &amount,
+		ConvertedCurrency: currencyOf(transfer.ToCurrency),
+		ExchangeRate:      &rate,
+	}
+}
+
+// currencyOf returns currency, which is empty on records made before they
+// had one.
+func currencyOf(currency string) string {
+	if currency == "" {
+		return models.DefaultCurrency
+	}
+	return currency
+}
+
+// BalanceResponse is one entry of GET /balances.
+type BalanceResponse struct {
+	Currency string  `json:"currency"`
+	Balance  float64 `json:"balance"`
+}
+
+func NewBalanceResponse(balance models.Balance) BalanceResponse {
+	return BalanceResponse{Currency: balance.Currency, Balance: balance.Amount}
+}
+
+// QuoteResponse is the rate offered by POST /quotes, to be passed as
+// quote_id to POST /transfer before it expires. ConvertedAmount is only set
+// when the request had an amount.
+type QuoteResponse struct {
+	QuoteID         uuid.UUID `json:"quote_id"`
+	FromCurrency    string    `json:"from_currency"`
+	ToCurrency      string    `json:"to_currency"`
+	MidRate         float64   `json:"mid_rate"`
+	Spread          float64   `json:"spread"`
+	Rate            float64   `json:"rate"`
+	Amount          float64   `json:"amount,omitempty"`
+	ConvertedAmount float64   `json:"converted_amount,omitempty"`
+	ExpiresAt       string    `json:"expires_at"`
+}
+
+func NewQuoteResponse(quote models.Quote, amount, converted float64) QuoteResponse {
+	return QuoteResponse{
+		QuoteID:         quote.ID,
+		FromCurrency:    quote.FromCurrency,
+		ToCurrency:      quote.ToCurrency,
+		MidRate:         quote.MidRate,
+		Spread:          quote.Spread,
+		Rate:            quote.Rate,
+		Amount:          amount,
+		ConvertedAmount: converted,
+		ExpiresAt:       formatDate(quote.ExpiresAt),
+	}
+}
+
+// ScheduledTransferResponse is one scheduled transfer of GET
+// /scheduled-transfers. Its times are in UTC; NextRunAt is omitted when no
+// occurrence is left.
+type ScheduledTransferResponse struct {
+	ScheduleID uuid.UUID `json:"schedule_id"`
+	TargetUser uuid.UUID `json:"target_user"`
+	Amount     float64   `json:"amount"`
+	Currency   string    `json:"currency"`
+	ToCurrency string    `json:"to_currency"`
+	Remarks    string    `json:"remarks,omitempty"`
+	Frequency  string    `json:"frequency"`
+	Cron       string    `json:"cron,omitempty"`
+	StartAt    string    `json:"start_at"`
+	NextRunAt  string    `json:"next_run_at,omitempty"`
+	LastRunAt  string    `json:"last_run_at,omitempty"`
+	// Attempts counts the failed attempts at the next occurrence, and
+	// LastError is the error code of the last failed attempt.
+	Attempts    int    `json:"attempts"`
+	LastError   string `json:"last_error,omitempty"`
+	Status      string `json:"status"`
+	CreatedDate string `json:"created_date"`
+}
+
+func NewScheduledTransferResponse(scheduled models.ScheduledTransfer) ScheduledTransferResponse {
+	response := ScheduledTransferResponse{
+		ScheduleID:  scheduled.ID,
+		TargetUser:  scheduled.ToUserID,
+		Amount:      scheduled.Amount,
+		Currency:    currencyOf(scheduled.Currency),
+		ToCurrency:  currencyOf(scheduled.ToCurrency),
+		Remarks:     scheduled.Remarks,
+		Frequency:   scheduled.Frequency,
+		Cron:        scheduled.Cron,
+		StartAt:     formatDate(scheduled.StartAt.UTC()),
+		Attempts:    scheduled.Attempts,
+		LastError:   scheduled.LastError,
+		Status:      scheduled.Status,
+		CreatedDate: formatDate(scheduled.CreatedDate),
+	}
+	if scheduled.NextRunAt != nil {
+		response.NextRunAt = formatDate(scheduled.NextRunAt.UTC())
+	}
+	if scheduled.LastRunAt != nil {
+		response.LastRunAt = formatDate(scheduled.LastRunAt.UTC())
+	}
+	return response
+}
+
+// MoneyRequestResponse is one money request of GET /money-requests/incoming
+// and /money-requests/outgoing. TransferID names the transfer that paid an
+// accepted request, which Transfer shows right after it was accepted.
+type MoneyRequestResponse struct {
+	RequestID   uuid.UUID         `json:"request_id"`
+	RequesterID uuid.UUID         `json:"requester_id"`
+	PayerID     uuid.UUID         `json:"payer_id"`
+	Amount      float64           `json:"amount"`
+	Currency    string            `json:"currency"`
+	Remarks     string            `json:"remarks"`
+	Status      string            `json:"status"`
+	TransferID  *uuid.UUID        `json:"transfer_id,omitempty"`
+	Transfer    *TransferResponse `json:"transfer,omitempty"`
+	ExpiresAt   string            `json:"expires_at"`
+	RespondedAt string            `json:"responded_at,omitempty"`
+	CreatedDate string            `json:"created_date"`
+}
+
+func NewMoneyRequestResponse(request models.MoneyRequest) MoneyRequestResponse {
+	response := MoneyRequestResponse{
+		RequestID:   request.ID,
+		RequesterID: request.RequesterID,
+		PayerID:     request.PayerID,
+		Amount:      request.Amount,
+		Currency:    currencyOf(request.Currency),
+		Remarks:     request.Remarks,
+		Status:      request.Status,
+		TransferID:  request.TransferID,
+		ExpiresAt:   formatDate(request.ExpiresAt),
+		CreatedDate: formatDate(request.CreatedDate),
+	}
+	if request.RespondedAt != nil {
+		response.RespondedAt = formatDate(*request.RespondedAt)
+	}
+	return response
+}
+
+// PocketResponse is one pocket of GET /pockets.
+type PocketResponse struct {
+	PocketID    uuid.UUID `json:"pocket_id"`
+	Name        string    `json:"name"`
+	Currency    string    `json:"currency"`
+	Balance     float64   `json:"balance"`
+	CreatedDate string    `json:"created_date"`
+}
+
+func NewPocketResponse(pocket models.Pocket) PocketResponse {
+	return PocketResponse{
+		PocketID:    pocket.ID,
+		Name:        pocket.Name,
+		Currency:    pocket.Currency,
+		Balance:     pocket.Balance,
+		CreatedDate: formatDate(pocket.CreatedDate),
+	}
+}
+
+// PocketMovementResponse is a move made by POST /pockets/move, or by
+// closing a pocket that still held money.
+type PocketMovementResponse struct {
+	MovementID uuid.UUID `json:"movement_id"`
+	Pockets
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
+	BalanceBefore float64 `json:"balance_before"`
+	BalanceAfter  float64 `json:"balance_after"`
+	CreatedDate   string  `json:"created_date"`
+}
+
+func NewPocketMovementResponse(movement models.PocketMovement) PocketMovementResponse {
+	return PocketMovementResponse{
+		MovementID:    movement.ID,
+		Pockets:       Pockets{FromPocketID: movement.FromPocketID, ToPocketID: movement.ToPocketID},
+		Amount:        movement.Amount,
+		Currency:      movement.Currency,
+		BalanceBefore: movement.BalanceBefore,
+		BalanceAfter:  movement.BalanceAfter,
+		CreatedDate:   formatDate(movement.CreatedDate),
+	}
+}
+
+// ClosedPocketResponse is the result of DELETE /pockets/:id. Movement is
+// set when the pocket still held money, which went to the main balance.
+type ClosedPocketResponse struct {
+	PocketID   uuid.UUID               `json:"pocket_id"`
+	Name       string                  `json:"name"`
+	ClosedDate string                  `json:"closed_date"`
+	Movement   *PocketMovementResponse `json:"movement,omitempty"`
+}
+
+func NewClosedPocketResponse(pocket models.Pocket, movement *models.PocketMovement) ClosedPocketResponse {
+	response := ClosedPocketResponse{PocketID: pocket.ID, Name: pocket.Name}
+	if pocket.ClosedAt != nil {
+		response.ClosedDate = formatDate(*pocket.ClosedAt)
+	}
+	if movement != nil {
+		moved := NewPocketMovementResponse(*movement)
+		response.Movement = &moved
+	}
+	return response
+}
+
+// RefundResponse is the result of POST /merchant/payments/:id/refunds.
+type RefundResponse struct {
+	RefundID        uuid.UUID `json:"refund_id"`
+	PaymentID       uuid.UUID `json:"payment_id"`
+	Amount          float64   `json:"amount"`
+	Currency        string    `json:"currency"`
+	Reason          string    `json:"reason,omitempty"`
+	Status          string    `json:"status"`
+	RefundedAmount  float64   `json:"refunded_amount"`
+	RemainingAmount float64   `json:"remaining_amount"`
+	CreatedDate     string    `json:"created_date"`
+}
+
+func NewRefundResponse(refund models.Reversal, payment models.Payment) RefundResponse {
+	return RefundResponse{
+		RefundID:        refund.ID,
+		PaymentID:       payment.ID,
+		Amount:          refund.Amount,
+		Currency:        currencyOf(refund.Currency),
+		Reason:          refund.Reason,
+		Status:          refund.Status,
+		RefundedAmount:  payment.RefundedAmount,
+		RemainingAmount: fx.Round(payment.Amount - payment.RefundedAmount),
+		CreatedDate:     formatDate(refund.CreatedDate),
+	}
+}
+
+type ProfileResponse struct {
+	UserID      uuid.UUID `json:"user_id"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	Address     string    `json:"address"`
+	Language    string    `json:"language"`
+	UpdatedDate string    `json:"updated_date"`
+}
+
+func NewProfileResponse(user models.User) ProfileResponse {
+	return ProfileResponse{
+		UserID:      user.ID,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Address:     user.Address,
+		Language:    user.Language,
+		UpdatedDate: formatDate(user.UpdatedDate),
+	}
+}
+
+func formatDate(t time.Time) string {
+	return t.Format(DateFormat)
+}
+
+const StatusFailed = "FAILED"
+
+// ErrorResponse is the envelope wrapping every failed API call.
+type ErrorResponse struct {
+	Status    string      `json:"status"`
+	RequestID string      `json:"request_id,omitempty"`
+	Error     ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
@@ -0,0 +1,145 @@
+package dto
+
+import (
+	"encoding/json"
+	"myapp/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUserResponseOmitsPIN ensures the PIN is never part of the public user view
+func TestUserResponseOmitsPIN(t *testing.T) {
+	user := models.User{
+		ID:          uuid.New(),
+		FirstName:   "Guntur",
+		PhoneNumber: "08112555011",
+		PIN:         "123456",
+		CreatedDate: time.Now(),
+	}
+
+	body, err := json.Marshal(Success(NewUserResponse(user)))
+	assert.NoError(t, err)
+
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &response))
+
+	result := response["result"].(map[string]interface{})
+	assert.Equal(t, "SUCCESS", response["status"])
+	assert.Equal(t, "08112555011", result["phone_number"])
+	assert.NotContains(t, result, "pin")
+	assert.NotContains(t, string(body), "123456")
+}
+
+// TestTransactionResponseSetsSingleID checks that history entries only carry their own ID key
+func TestTransactionResponseSetsSingleID(t *testing.T) {
+	topUp := models.TopUp{ID: uuid.New(), Amount: 50000, BalanceAfter: 50000}
+
+	body, err := json.Marshal(NewTopUpTransaction("user-1", topUp))
+	assert.NoError(t, err)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &entry))
+
+	assert.Equal(t, topUp.ID.String(), entry["top_up_id"])
+	assert.Equal(t, TransactionCredit, entry["transaction_type"])
+	assert.NotContains(t, entry, "transfer_id")
+	assert.NotContains(t, entry, "payment_id")
+}
+
+// TestTransferTransactionStatus checks that stored transfer statuses are reported as is
+func TestTransferTransactionStatus(t *testing.T) {
+	transfer := models.Transfer{ID: uuid.New(), Amount: 50000, Status: "PENDING"}
+	assert.Equal(t, "PENDING", NewTransferTransaction("user-1", transfer).Status)
+
+	// Rows written before transfers had a status
+	transfer.Status = ""
+	assert.Equal(t, StatusSuccess, NewTransferTransaction("user-1", transfer).Status)
+}
+
+// TestTransferTransactionShowsConversion checks that history shows both the debited and the credited amount
+func TestTransferTransactionShowsConversion(t *testing.T) {
+	transfer := models.Transfer{ID: uuid.New(), Amount: 10, Currency: "USD", ToAmount: 158400, ToCurrency: "IDR", Rate: 15840}
+
+	body, err := json.Marshal(NewTransferTransaction("user-1", transfer))
+	assert.NoError(t, err)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &entry))
+	assert.Equal(t, 10.0, entry["amount"])
+	assert.Equal(t, "USD", entry["currency"])
+	assert.Equal(t, 158400.0, entry["converted_amount"])
+	assert.Equal(t, "IDR", entry["converted_currency"])
+	assert.Equal(t, 15840.0, entry["exchange_rate"])
+
+	// Transfers made before currencies were recorded
+	legacy := NewTransferTransaction("user-1", models.Transfer{ID: uuid.New(), Amount: 5000})
+	assert.Equal(t, "IDR", legacy.Currency)
+	assert.Equal(t, 5000.0, *legacy.ConvertedAmount)
+	assert.Equal(t, "IDR", legacy.ConvertedCurrency)
+
+	// Payments are never converted
+	body, err = json.Marshal(NewPaymentTransaction("user-1", models.Payment{ID: uuid.New(), Amount: 5000}))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), "converted_amount")
+}
+
+func TestReceivedTransferShowsCreditedAmount(t *testing.T) {
+	sender := uuid.New()
+	transfer := models.Transfer{ID: uuid.New(), FromUserID: sender, Amount: 10, Currency: "USD", ToAmount: 158400, ToCurrency: "IDR", Rate: 15840}
+
+	entry := NewReceivedTransferTransaction("user-2", transfer)
+	assert.Equal(t, TransactionCredit, entry.TransactionType)
+	assert.Equal(t, StatusSuccess, entry.Status)
+	assert.Equal(t, &sender, entry.CounterpartyID)
+	assert.Equal(t, 158400.0, entry.Amount)
+	assert.Equal(t, "IDR", entry.Currency)
+
+	// Transfers made before currencies were recorded
+	legacy := NewReceivedTransferTransaction("user-2", models.Transfer{ID: uuid.New(), Amount: 5000})
+	assert.Equal(t, 5000.0, legacy.Amount)
+	assert.Equal(t, "IDR", legacy.Currency)
+}
+
+func TestTransactionsNamePockets(t *testing.T) {
+	savings := uuid.New()
+	movement := models.PocketMovement{ID: uuid.New(), ToPocketID: &savings, Amount: 500, BalanceBefore: 1000, BalanceAfter: 500}
+
+	body, err := json.Marshal(NewMovementTransaction("user-1", movement))
+	assert.NoError(t, err)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &entry))
+	assert.Equal(t, movement.ID.String(), entry["movement_id"])
+	assert.Equal(t, TransactionMove, entry["transaction_type"])
+	assert.Equal(t, savings.String(), entry["to_pocket_id"])
+	assert.NotContains(t, entry, "from_pocket_id", "the main balance is omitted")
+	assert.Equal(t, "IDR", entry["currency"])
+
+	topUp := NewTopUpTransaction("user-1", models.TopUp{ID: uuid.New(), Amount: 100, PocketID: &savings})
+	assert.Equal(t, &savings, topUp.ToPocketID)
+	payment := NewPaymentTransaction("user-1", models.Payment{ID: uuid.New(), Amount: 100, PocketID: &savings})
+	assert.Equal(t, &savings, payment.FromPocketID)
+}
+
+func TestTransactionsShowRefunds(t *testing.T) {
+	payment := models.Payment{ID: uuid.New(), Amount: 300}
+	assert.Equal(t, StatusSuccess, NewPaymentTransaction("user-1", payment).Status)
+	payment.RefundedAmount = 100.1
+	assert.Equal(t, StatusPartiallyRefunded, NewPaymentTransaction("user-1", payment).Status)
+	payment.RefundedAmount = 300
+	assert.Equal(t, StatusRefunded, NewPaymentTransaction("user-1", payment).Status)
+
+	refund := models.Reversal{ID: uuid.New(), PaymentID: &payment.ID, TransactionType: TransactionCredit, Amount: 100, Reason: "Wrong size", Status: StatusSuccess}
+	body, err := json.Marshal(NewReversalTransaction("user-1", refund))
+	assert.NoError(t, err)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &entry))
+	assert.Equal(t, refund.ID.String(), entry["reversal_id"])
+	assert.Equal(t, payment.ID.String(), entry["reversal_of"])
+	assert.Equal(t, "Wrong size", entry["remarks"])
+	assert.NotContains(t, entry, "payment_id")
+}
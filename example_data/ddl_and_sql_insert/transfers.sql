@@ -1,2 +1,2 @@
-INSERT INTO public.transfers (id, from_user_id, to_user_id, amount, remarks, balance_before, balance_after, created_date, status) VALUES ('c52fa5f6-6ebc-41e0-827f-79da3704c57a', '33352f2f-874c-4f05-a3e9-dcbb2ce3285e', 'fa1ff8a8-4ea5-4aa6-9d55-4f88175b71e6', 50000, 'Hadiah Ultah', 50000, 0, '2024-07-05 13:49:40.930408 +00:00', null);
-INSERT INTO public.transfers (id, from_user_id, to_user_id, amount, remarks, balance_before, balance_after, created_date, status) VALUES ('c4648a44-2a82-4915-bebd-3e5ceca331f8', '33352f2f-874c-4f05-a3e9-dcbb2ce3285e', 'fa1ff8a8-4ea5-4aa6-9d55-4f88175b71e6', 50000, 'Hadiah Ultah', 50000, 0, '2024-07-05 13:55:49.947769 +00:00', null);
+INSERT INTO public.transfers (id, from_user_id, to_user_id, amount, remarks, balance_before, balance_after, created_date, status) VALUES ('c52fa5f6-6ebc-41e0-827f-79da3704c57a', '33352f2f-874c-4f05-a3e9-dcbb2ce3285e', 'fa1ff8a8-4ea5-4aa6-9d55-4f88175b71e6', 50000, 'Hadiah Ultah', 50000, 0, '2024-07-05 13:49:40.930408 +00:00', 'SUCCESS');
+INSERT INTO public.transfers (id, from_user_id, to_user_id, amount, remarks, balance_before, balance_after, created_date, status) VALUES ('c4648a44-2a82-4915-bebd-3e5ceca331f8', '33352f2f-874c-4f05-a3e9-dcbb2ce3285e', 'fa1ff8a8-4ea5-4aa6-9d55-4f88175b71e6', 50000, 'Hadiah Ultah', 50000, 0, '2024-07-05 13:55:49.947769 +00:00', 'SUCCESS');
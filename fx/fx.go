@@ -0,0 +1,109 @@
+// Package fx converts amounts between currencies. A Provider supplies
+// mid-market rates, and an Exchange turns them into quotes for customers by
+// taking a spread and limiting how long a quoted rate is honoured.
+package fx
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	// ErrUnsupportedCurrency is returned for a currency the provider has no
+	// rate for.
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	// ErrRateExpired is returned when the provider no longer vouches for a
+	// rate, e.g. because its rates file has not been refreshed in time.
+	ErrRateExpired = errors.New("exchange rate expired")
+)
+
+// Rate is the mid-market price of one unit of From in To.
+type Rate struct {
+	From string
+	To   string
+	Mid  float64
+	// ExpiresAt is when the provider stops vouching for the rate. Zero
+	// means never.
+	ExpiresAt time.Time
+}
+
+// Provider supplies exchange rates.
+type Provider interface {
+	// Supports reports whether the provider has rates for currency.
+	Supports(currency string) bool
+	Rate(ctx context.Context, from, to string) (Rate, error)
+}
+
+// Quote is a rate offered to a customer: the mid-market rate less the
+// spread, honoured until ExpiresAt.
+type Quote struct {
+	From      string
+	To        string
+	Mid       float64
+	Spread    float64
+	Rate      float64
+	ExpiresAt time.Time
+}
+
+// Convert returns amount in the quote's target currency.
+func (q Quote) Convert(amount float64) float64 {
+	return Round(amount * q.Rate)
+}
+
+// Round rounds amount to cents, the precision of every stored amount.
+func Round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// Exchange quotes rates from a provider.
+type Exchange struct {
+	provider Provider
+	spread   float64
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// NewExchange returns an exchange quoting the provider's rates less spread,
+// a fraction such as 0.005 for half a percent, for ttl at most.
+func NewExchange(provider Provider, spread float64, ttl time.Duration) *Exchange {
+	return &Exchange{provider: provider, spread: spread, ttl: ttl, now: time.Now}
+}
+
+// Supports reports whether amounts can be converted from and to currency.
+func (e *Exchange) Supports(currency string) bool {
+	return e.provider.Supports(currency)
+}
+
+// Quote prices converting from into to. The quote expires after the TTL, or
+// earlier when the provider's rate does. Converting a currency into itself
+// is free.
+func (e *Exchange) Quote(ctx context.Context, from, to string) (Quote, error) {
+	now := e.now()
+	if from == to {
+		return Quote{From: from, To: to, Mid: 1, Rate: 1, ExpiresAt: now.Add(e.ttl)}, nil
+	}
+
+	rate, err := e.provider.Rate(ctx, from, to)
+	if err != nil {
+		return Quote{}, err
+	}
+	expiresAt := now.Add(e.ttl)
+	if !rate.ExpiresAt.IsZero() {
+		if !now.Before(rate.ExpiresAt) {
+			return Quote{}, ErrRateExpired
+		}
+		if rate.ExpiresAt.Before(expiresAt) {
+			expiresAt = rate.ExpiresAt
+		}
+	}
+	return Quote{
+		From:      from,
+		To:        to,
+		Mid:       rate.Mid,
+		Spread:    e.spread,
+		Rate:      rate.Mid * (1 - e.spread),
+		ExpiresAt: expiresAt,
+	}, nil
+}
@@ -0,0 +1,93 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticProviderCrossRates(t *testing.T) {
+	ctx := context.Background()
+	provider := NewStaticProvider("IDR", map[string]float64{"USD": 16000, "SGD": 12000}, time.Time{})
+
+	rate, err := provider.Rate(ctx, "USD", "IDR")
+	assert.NoError(t, err)
+	assert.Equal(t, 16000.0, rate.Mid)
+
+	rate, err = provider.Rate(ctx, "IDR", "USD")
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.0000625, rate.Mid, 1e-12)
+
+	rate, err = provider.Rate(ctx, "SGD", "USD")
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.75, rate.Mid, 1e-12)
+
+	_, err = provider.Rate(ctx, "IDR", "GBP")
+	assert.True(t, errors.Is(err, ErrUnsupportedCurrency))
+	assert.ErrorContains(t, err, "GBP")
+}
+
+func TestExchangeQuotes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	provider := NewStaticProvider("IDR", map[string]float64{"USD": 16000}, now.Add(10*time.Second))
+	exchange := NewExchange(provider, 0.01, time.Minute)
+	exchange.now = func() time.Time { return now }
+
+	quote, err := exchange.Quote(ctx, "USD", "IDR")
+	assert.NoError(t, err)
+	assert.Equal(t, 16000.0, quote.Mid)
+	assert.Equal(t, 15840.0, quote.Rate, "the spread is taken off the mid rate")
+	assert.Equal(t, 158400.0, quote.Convert(10))
+	assert.Equal(t, now.Add(10*time.Second), quote.ExpiresAt, "a quote never outlives its rate")
+
+	quote, err = exchange.Quote(ctx, "IDR", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, 6.19, quote.Convert(100000), "converted amounts are rounded to cents")
+
+	quote, err = exchange.Quote(ctx, "IDR", "IDR")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, quote.Rate)
+	assert.Equal(t, now.Add(time.Minute), quote.ExpiresAt)
+
+	now = now.Add(10 * time.Second)
+	_, err = exchange.Quote(ctx, "USD", "IDR")
+	assert.True(t, errors.Is(err, ErrRateExpired))
+}
+
+func TestLoadStaticProvider(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write rates: %v", err)
+		}
+		return path
+	}
+
+	provider, err := LoadStaticProvider(write("rates.json",
+		`{"base": "IDR", "expires_at": "2024-07-01T00:00:00Z", "rates": {"USD": 16250}}`))
+	assert.NoError(t, err)
+	assert.True(t, provider.Supports("IDR"))
+	assert.True(t, provider.Supports("USD"))
+	assert.False(t, provider.Supports("EUR"))
+	rate, err := provider.Rate(context.Background(), "USD", "IDR")
+	assert.NoError(t, err)
+	assert.Equal(t, 16250.0, rate.Mid)
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), rate.ExpiresAt)
+
+	_, err = LoadStaticProvider(write("base.json", `{"base": "rupiah", "rates": {}}`))
+	assert.ErrorContains(t, err, "not a currency code")
+	_, err = LoadStaticProvider(write("price.json", `{"base": "IDR", "rates": {"USD": 0}}`))
+	assert.ErrorContains(t, err, "must be positive")
+	_, err = LoadStaticProvider(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+
+	_, err = LoadStaticProvider(filepath.Join("..", "example_data", "fx_rates.json"))
+	assert.NoError(t, err, "the example rates load")
+}
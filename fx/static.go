@@ -0,0 +1,79 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// StaticProvider serves fixed rates, for local use and tests. Every rate is
+// derived from the price of each currency in one base currency.
+type StaticProvider struct {
+	prices    map[string]float64
+	expiresAt time.Time
+}
+
+// NewStaticProvider returns a provider for base and the currencies priced
+// in it, e.g. {"USD": 16250} when one dollar costs 16,250 of base. Its
+// rates expire at expiresAt, or never when that is zero.
+func NewStaticProvider(base string, prices map[string]float64, expiresAt time.Time) *StaticProvider {
+	all := map[string]float64{base: 1}
+	for currency, price := range prices {
+		all[currency] = price
+	}
+	return &StaticProvider{prices: all, expiresAt: expiresAt}
+}
+
+// rateFile is the format read by LoadStaticProvider:
+//
+//	{"base": "IDR", "expires_at": "2024-07-01T00:00:00Z", "rates": {"USD": 16250, "SGD": 12100}}
+//
+// expires_at may be left out for rates that never expire.
+type rateFile struct {
+	Base      string             `json:"base"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	Rates     map[string]float64 `json:"rates"`
+}
+
+// LoadStaticProvider reads the rates from a JSON file.
+func LoadStaticProvider(path string) (*StaticProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read rates: %w", err)
+	}
+	var file rateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("unable to parse rates in %s: %w", path, err)
+	}
+	if !currencyCode.MatchString(file.Base) {
+		return nil, fmt.Errorf("rates in %s: base %q is not a currency code", path, file.Base)
+	}
+	for currency, price := range file.Rates {
+		if !currencyCode.MatchString(currency) {
+			return nil, fmt.Errorf("rates in %s: %q is not a currency code", path, currency)
+		}
+		if price <= 0 {
+			return nil, fmt.Errorf("rates in %s: price of %s must be positive", path, currency)
+		}
+	}
+	return NewStaticProvider(file.Base, file.Rates, file.ExpiresAt), nil
+}
+
+func (p *StaticProvider) Supports(currency string) bool {
+	_, ok := p.prices[currency]
+	return ok
+}
+
+func (p *StaticProvider) Rate(ctx context.Context, from, to string) (Rate, error) {
+	for _, currency := range []string{from, to} {
+		if !p.Supports(currency) {
+			return Rate{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+		}
+	}
+	return Rate{From: from, To: to, Mid: p.prices[from] / p.prices[to], ExpiresAt: p.expiresAt}, nil
+}
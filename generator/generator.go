@@ -0,0 +1,242 @@
+// Package generator creates realistic wallet histories for load testing:
+// users with top-ups, payments and transfers spread over time. Output is
+// deterministic per seed, IDs and timestamps included, and every record
+// keeps a consistent balance_before/balance_after chain, so a generated
+// dataset looks exactly like one built through the API.
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"myapp/models"
+
+	"github.com/google/uuid"
+)
+
+type Options struct {
+	Users int
+	// TransactionsPerUser is the average; activity is skewed so that a few
+	// users have far longer histories than most, as in production.
+	TransactionsPerUser int
+	// Days is the length of the history, ending at End.
+	Days int
+	End  time.Time
+	Seed int64
+}
+
+// DefaultEnd is the end of generated histories unless set, fixed so that
+// the same seed always yields the same timestamps.
+var DefaultEnd = time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+// PIN is the PIN of every generated user, so load tests can log in.
+const PIN = "123456"
+
+// Dataset is a generated history. Users hold their final balances.
+type Dataset struct {
+	Users     []models.User
+	TopUps    []models.TopUp
+	Payments  []models.Payment
+	Transfers []models.Transfer
+}
+
+var (
+	firstNames = []string{"Budi", "Siti", "Agus", "Dewi", "Rina", "Joko", "Putri", "Andi", "Wulan", "Hendra", "Fajar", "Indah", "Rudi", "Maya", "Yusuf", "Ayu"}
+	lastNames  = []string{"Santoso", "Wijaya", "Saputro", "Lestari", "Kurniawan", "Hidayat", "Pratama", "Sari", "Nugroho", "Halim", "Gunawan", "Susanti"}
+	streets    = []string{"Jl. Kebon Sirih", "Jl. Diponegoro", "Jl. Sudirman", "Jl. Gatot Subroto", "Jl. Malioboro", "Jl. Asia Afrika", "Jl. Pemuda", "Jl. Ahmad Yani"}
+	languages  = []string{"", "", "id", "id", "en"}
+	payees     = []string{"Pulsa Telkomsel 100k", "Token PLN", "Makan siang", "Bayar kos", "Belanja bulanan", "Tagihan internet", "BPJS", "Tiket KRL", "Ojek online", "Kopi"}
+	gifts      = []string{"Hadiah Ultah", "Arisan", "Bayar utang", "Patungan makan", "Uang saku", "Sewa", ""}
+)
+
+// event is one transaction waiting to be simulated.
+type event struct {
+	at   time.Time
+	user int
+}
+
+// Generate builds a dataset. Transactions are simulated in time order, so
+// payments and transfers never exceed the balance at that moment; a user
+// short of money tops up first, as real users do.
+func Generate(opts Options) Dataset {
+	if opts.End.IsZero() {
+		opts.End = DefaultEnd
+	}
+	if opts.Days <= 0 {
+		opts.Days = 90
+	}
+	g := &generation{rng: rand.New(rand.NewSource(opts.Seed))}
+	start := opts.End.AddDate(0, 0, -opts.Days)
+
+	// Users join over the first half of the period and each gets an
+	// activity weight from a heavy-tailed distribution.
+	weights := make([]float64, opts.Users)
+	var totalWeight float64
+	for i := 0; i < opts.Users; i++ {
+		created := g.between(start, start.Add(opts.End.Sub(start)/2))
+		g.data.Users = append(g.data.Users, models.User{
+			ID:          g.uuid(),
+			FirstName:   pick(g.rng, firstNames),
+			LastName:    pick(g.rng, lastNames),
+			PhoneNumber: fmt.Sprintf("+6281%03d%07d", opts.Seed%1000, i),
+			Address:     fmt.Sprintf("%s No. %d", pick(g.rng, streets), g.rng.Intn(300)+1),
+			PIN:         PIN,
+			Language:    pick(g.rng, languages),
+			Version:     1,
+			CreatedDate: created,
+			UpdatedDate: created,
+		})
+		weights[i] = math.Exp(g.rng.NormFloat64())
+		totalWeight += weights[i]
+	}
+
+	var events []event
+	for i, user := range g.data.Users {
+		n := int(math.Round(float64(opts.TransactionsPerUser*opts.Users) * weights[i] / totalWeight))
+		for j := 0; j < n; j++ {
+			events = append(events, event{at: g.between(user.CreatedDate, opts.End), user: i})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+
+	for _, e := range events {
+		g.simulate(e)
+	}
+	return g.data
+}
+
+type generation struct {
+	rng  *rand.Rand
+	data Dataset
+}
+
+func (g *generation) simulate(e event) {
+	user := &g.data.Users[e.user]
+	switch roll := g.rng.Intn(100); {
+	case roll < 30:
+		g.topUp(user, e.at, g.topUpAmount())
+	case roll < 75:
+		amount := roundTo(500+g.rng.ExpFloat64()*60000, 500)
+		g.ensure(user, e.at, amount)
+		g.data.Payments = append(g.data.Payments, models.Payment{
+			ID:            g.uuid(),
+			UserID:        user.ID,
+			Amount:        amount,
+			Currency:      models.DefaultCurrency,
+			Remarks:       pick(g.rng, payees),
+			BalanceBefore: user.Balance,
+			BalanceAfter:  user.Balance - amount,
+			CreatedDate:   e.at,
+		})
+		g.move(user, -amount, e.at)
+	default:
+		to := g.recipient(e)
+		if to == nil {
+			g.topUp(user, e.at, g.topUpAmount())
+			return
+		}
+		amount := roundTo(10000+g.rng.ExpFloat64()*150000, 1000)
+		g.ensure(user, e.at, amount)
+		g.data.Transfers = append(g.data.Transfers, models.Transfer{
+			ID:            g.uuid(),
+			FromUserID:    user.ID,
+			ToUserID:      to.ID,
+			Amount:        amount,
+			Currency:      models.DefaultCurrency,
+			ToAmount:      amount,
+			ToCurrency:    models.DefaultCurrency,
+			Rate:          1,
+			Remarks:       pick(g.rng, gifts),
+			BalanceBefore: user.Balance,
+			BalanceAfter:  user.Balance - amount,
+			Status:        models.TransferSucceeded,
+			CreatedDate:   e.at,
+		})
+		g.move(user, -amount, e.at)
+		g.move(to, amount, e.at)
+	}
+}
+
+// ensure tops the user up shortly before at when amount exceeds their
+// balance. The top-up never predates the user's previous activity, so their
+// records stay in balance order when sorted by time.
+func (g *generation) ensure(user *models.User, at time.Time, amount float64) {
+	if user.Balance >= amount {
+		return
+	}
+	topUp := g.topUpAmount()
+	for user.Balance+topUp < amount {
+		topUp += 100000
+	}
+	when := at.Add(-time.Duration(1+g.rng.Intn(600)) * time.Second)
+	if !when.After(user.UpdatedDate) {
+		when = user.UpdatedDate.Add(at.Sub(user.UpdatedDate) / 2).Truncate(time.Microsecond)
+	}
+	g.topUp(user, when, topUp)
+}
+
+func (g *generation) topUp(user *models.User, at time.Time, amount float64) {
+	g.data.TopUps = append(g.data.TopUps, models.TopUp{
+		ID:            g.uuid(),
+		UserID:        user.ID,
+		Amount:        amount,
+		Currency:      models.DefaultCurrency,
+		BalanceBefore: user.Balance,
+		BalanceAfter:  user.Balance + amount,
+		CreatedDate:   at,
+	})
+	g.move(user, amount, at)
+}
+
+func (g *generation) move(user *models.User, delta float64, at time.Time) {
+	user.Balance += delta
+	if at.After(user.UpdatedDate) {
+		user.UpdatedDate = at
+	}
+}
+
+// recipient picks another user who had already joined at the time of e.
+func (g *generation) recipient(e event) *models.User {
+	for attempt := 0; attempt < 5; attempt++ {
+		i := g.rng.Intn(len(g.data.Users))
+		if i != e.user && !g.data.Users[i].CreatedDate.After(e.at) {
+			return &g.data.Users[i]
+		}
+	}
+	return nil
+}
+
+// topUpAmount favours the round amounts offered in the app.
+func (g *generation) topUpAmount() float64 {
+	amounts := []float64{20000, 50000, 100000, 100000, 200000, 250000, 500000, 1000000}
+	return pick(g.rng, amounts)
+}
+
+func (g *generation) between(from, to time.Time) time.Time {
+	span := to.Sub(from)
+	if span <= 0 {
+		return from
+	}
+	return from.Add(time.Duration(g.rng.Int63n(int64(span)))).Truncate(time.Microsecond)
+}
+
+// uuid draws a version 4 UUID from the seeded source.
+func (g *generation) uuid() uuid.UUID {
+	id, err := uuid.NewRandomFromReader(g.rng)
+	if err != nil {
+		// rand.Rand never fails to read.
+		panic(err)
+	}
+	return id
+}
+
+func pick[T any](rng *rand.Rand, values []T) T {
+	return values[rng.Intn(len(values))]
+}
+
+func roundTo(value, step float64) float64 {
+	return math.Max(step, math.Round(value/step)*step)
+}
@@ -0,0 +1,136 @@
+package generator_test
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"myapp/generator"
+	"myapp/models"
+	"myapp/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var options = generator.Options{Users: 50, TransactionsPerUser: 20, Days: 60, Seed: 42}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	first := generator.Generate(options)
+	second := generator.Generate(options)
+	assert.Equal(t, first, second)
+
+	other := options
+	other.Seed = 43
+	assert.NotEqual(t, first.Users[0].ID, generator.Generate(other).Users[0].ID)
+}
+
+func TestGenerateMix(t *testing.T) {
+	data := generator.Generate(options)
+
+	assert.Len(t, data.Users, 50)
+	total := len(data.TopUps) + len(data.Payments) + len(data.Transfers)
+	assert.GreaterOrEqual(t, total, 50*20, "top-ups needed to afford spending come on top")
+	assert.NotEmpty(t, data.TopUps)
+	assert.NotEmpty(t, data.Payments)
+	assert.NotEmpty(t, data.Transfers)
+
+	end := generator.DefaultEnd
+	for _, transfer := range data.Transfers {
+		assert.NotEqual(t, transfer.FromUserID, transfer.ToUserID)
+		assert.False(t, transfer.CreatedDate.After(end))
+	}
+}
+
+// entry is one balance change of a user, as recorded in the tables.
+type entry struct {
+	at      time.Time
+	delta   float64
+	before  float64
+	after   float64
+	ownLine bool // false for incoming transfers, which only record the sender's balance
+}
+
+func TestGenerateKeepsBalanceChains(t *testing.T) {
+	data := generator.Generate(options)
+
+	entries := map[uuid.UUID][]entry{}
+	for _, topUp := range data.TopUps {
+		entries[topUp.UserID] = append(entries[topUp.UserID], entry{topUp.CreatedDate, topUp.Amount, topUp.BalanceBefore, topUp.BalanceAfter, true})
+	}
+	for _, p := range data.Payments {
+		entries[p.UserID] = append(entries[p.UserID], entry{p.CreatedDate, -p.Amount, p.BalanceBefore, p.BalanceAfter, true})
+	}
+	for _, tr := range data.Transfers {
+		entries[tr.FromUserID] = append(entries[tr.FromUserID], entry{tr.CreatedDate, -tr.Amount, tr.BalanceBefore, tr.BalanceAfter, true})
+		entries[tr.ToUserID] = append(entries[tr.ToUserID], entry{at: tr.CreatedDate, delta: tr.Amount})
+	}
+
+	for _, user := range data.Users {
+		list := entries[user.ID]
+		sort.SliceStable(list, func(i, j int) bool { return list[i].at.Before(list[j].at) })
+
+		balance := 0.0
+		for _, e := range list {
+			assert.False(t, e.at.Before(user.CreatedDate), "activity before %s joined", user.PhoneNumber)
+			if e.ownLine {
+				assert.Equal(t, balance, e.before, "balance_before of %s at %s", user.PhoneNumber, e.at)
+				assert.Equal(t, e.before+e.delta, e.after)
+				assert.GreaterOrEqual(t, e.after, 0.0)
+			}
+			balance += e.delta
+		}
+		assert.Equal(t, balance, user.Balance, "final balance of %s", user.PhoneNumber)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	db := testutil.DB(t)
+	data := generator.Generate(generator.Options{Users: 5, TransactionsPerUser: 5, Days: 10, Seed: 1})
+
+	assert.NoError(t, generator.Insert(context.Background(), db, data, 3))
+
+	var stored models.User
+	assert.NoError(t, db.First(&stored, "id = ?", data.Users[0].ID).Error)
+	assert.Equal(t, data.Users[0].Balance, stored.Balance)
+
+	var count int64
+	db.Model(&models.Transfer{}).Where("from_user_id IN ?", userIDs(data)).Count(&count)
+	assert.Equal(t, int64(len(data.Transfers)), count)
+}
+
+func userIDs(data generator.Dataset) []uuid.UUID {
+	ids := make([]uuid.UUID, len(data.Users))
+	for i, user := range data.Users {
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+func TestWriteSQLAndCSV(t *testing.T) {
+	dir := t.TempDir()
+	data := generator.Generate(generator.Options{Users: 3, TransactionsPerUser: 4, Days: 10, Seed: 1})
+	data.Payments[0].Remarks = "Kopi 'Tuku'"
+
+	assert.NoError(t, generator.WriteSQL(dir, data))
+	script, err := os.ReadFile(filepath.Join(dir, "payments.sql"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(script), "INSERT INTO payments (id, user_id, amount, remarks"))
+	assert.Contains(t, string(script), "'Kopi ''Tuku'''")
+
+	assert.NoError(t, generator.WriteCSV(dir, data))
+	f, err := os.Open(filepath.Join(dir, "users.csv"))
+	assert.NoError(t, err)
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 4) {
+		assert.Equal(t, "id", records[0][0])
+		assert.Equal(t, data.Users[0].ID.String(), records[1][0])
+	}
+}
@@ -0,0 +1,199 @@
+package generator
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// sqlBatch is the number of rows per INSERT statement in SQL output.
+const sqlBatch = 500
+
+// table describes how one model is written to files.
+type table struct {
+	name    string
+	columns []string
+	rows    int
+	row     func(i int) []interface{}
+}
+
+// tables lists the dataset in foreign key order.
+func tables(d Dataset) []table {
+	return []table{
+		{
+			name:    "users",
+			columns: []string{"id", "first_name", "last_name", "phone_number", "address", "pin", "created_date", "balance", "updated_date", "language", "version"},
+			rows:    len(d.Users),
+			row: func(i int) []interface{} {
+				u := d.Users[i]
+				return []interface{}{u.ID, u.FirstName, u.LastName, u.PhoneNumber, u.Address, u.PIN, u.CreatedDate, u.Balance, u.UpdatedDate, u.Language, u.Version}
+			},
+		},
+		{
+			name:    "top_ups",
+			columns: []string{"id", "user_id", "amount", "balance_before", "balance_after", "created_date", "currency"},
+			rows:    len(d.TopUps),
+			row: func(i int) []interface{} {
+				t := d.TopUps[i]
+				return []interface{}{t.ID, t.UserID, t.Amount, t.BalanceBefore, t.BalanceAfter, t.CreatedDate, t.Currency}
+			},
+		},
+		{
+			name:    "payments",
+			columns: []string{"id", "user_id", "amount", "remarks", "balance_before", "balance_after", "created_date", "currency"},
+			rows:    len(d.Payments),
+			row: func(i int) []interface{} {
+				p := d.Payments[i]
+				return []interface{}{p.ID, p.UserID, p.Amount, p.Remarks, p.BalanceBefore, p.BalanceAfter, p.CreatedDate, p.Currency}
+			},
+		},
+		{
+			name:    "transfers",
+			columns: []string{"id", "from_user_id", "to_user_id", "amount", "remarks", "balance_before", "balance_after", "created_date", "status", "currency", "to_amount", "to_currency", "rate"},
+			rows:    len(d.Transfers),
+			row: func(i int) []interface{} {
+				t := d.Transfers[i]
+				return []interface{}{t.ID, t.FromUserID, t.ToUserID, t.Amount, t.Remarks, t.BalanceBefore, t.BalanceAfter, t.CreatedDate, t.Status, t.Currency, t.ToAmount, t.ToCurrency, t.Rate}
+			},
+		},
+	}
+}
+
+// Insert writes the dataset to db in one transaction. Model hooks are
+// skipped so that the generated IDs are kept.
+func Insert(ctx context.Context, db *gorm.DB, d Dataset, batchSize int) error {
+	return db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Transaction(func(tx *gorm.DB) error {
+		if len(d.Users) > 0 {
+			if err := tx.Omit("TopUps").CreateInBatches(d.Users, batchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(d.TopUps) > 0 {
+			if err := tx.Omit("User").CreateInBatches(d.TopUps, batchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(d.Payments) > 0 {
+			if err := tx.Omit("User").CreateInBatches(d.Payments, batchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(d.Transfers) > 0 {
+			return tx.Omit("FromUser", "ToUser").CreateInBatches(d.Transfers, batchSize).Error
+		}
+		return nil
+	})
+}
+
+// WriteSQL writes one INSERT script per table into dir, named like the
+// files in example_data so that `seed sql --dir` can load them.
+func WriteSQL(dir string, d Dataset) error {
+	for _, t := range tables(d) {
+		err := writeFile(filepath.Join(dir, t.name+".sql"), func(w *bufio.Writer) error {
+			for start := 0; start < t.rows; start += sqlBatch {
+				w.WriteString("INSERT INTO " + t.name + " (" + strings.Join(t.columns, ", ") + ") VALUES\n")
+				for i := start; i < t.rows && i < start+sqlBatch; i++ {
+					if i > start {
+						w.WriteString(",\n")
+					}
+					values := t.row(i)
+					literals := make([]string, len(values))
+					for j, value := range values {
+						literals[j] = sqlLiteral(value)
+					}
+					w.WriteString("(" + strings.Join(literals, ", ") + ")")
+				}
+				w.WriteString(";\n")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes one CSV file with a header row per table into dir, ready
+// for Postgres COPY ... WITH (FORMAT csv, HEADER).
+func WriteCSV(dir string, d Dataset) error {
+	for _, t := range tables(d) {
+		err := writeFile(filepath.Join(dir, t.name+".csv"), func(w *bufio.Writer) error {
+			out := csv.NewWriter(w)
+			if err := out.Write(t.columns); err != nil {
+				return err
+			}
+			record := make([]string, len(t.columns))
+			for i := 0; i < t.rows; i++ {
+				for j, value := range t.row(i) {
+					record[j] = csvValue(value)
+				}
+				if err := out.Write(record); err != nil {
+					return err
+				}
+			}
+			out.Flush()
+			return out.Error()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, fn func(w *bufio.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := fn(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// timeLayout matches the timestamps in example_data.
+const timeLayout = "2006-01-02 15:04:05.000000 -07:00"
+
+func sqlLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case uuid.UUID:
+		return "'" + v.String() + "'"
+	case time.Time:
+		return "'" + v.Format(timeLayout) + "'"
+	}
+	return csvValue(value)
+}
+
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case uuid.UUID:
+		return v.String()
+	case time.Time:
+		return v.Format(timeLayout)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+	panic("generator: unsupported column type")
+}
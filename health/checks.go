@@ -0,0 +1,34 @@
+package health
+
+import (
+	"context"
+
+	"myapp/migrate"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// DatabaseCheck pings the database behind db.
+func DatabaseCheck(db *gorm.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// MigrationCheck fails while migrations are pending or an applied one has
+// been modified, i.e. while the schema is not what this build expects.
+func MigrationCheck(migrator *migrate.Migrator) CheckFunc {
+	return migrator.Check
+}
+
+// RedisCheck pings a Redis-compatible server.
+func RedisCheck(client redis.UniversalClient) CheckFunc {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
@@ -0,0 +1,123 @@
+// Package health serves the liveness and readiness probes used by the
+// orchestrator.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StatusUp   = "UP"
+	StatusDown = "DOWN"
+)
+
+// CheckFunc reports whether a dependency is usable. It must return promptly
+// once ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Health runs the registered readiness checks.
+type Health struct {
+	timeout      time.Duration
+	checks       []check
+	shuttingDown atomic.Bool
+}
+
+// Response is the body of /healthz and /readyz.
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// New returns a Health whose checks each get at most timeout to answer.
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+// Register adds a readiness check. It must be called before serving.
+func (h *Health) Register(name string, fn CheckFunc) {
+	h.checks = append(h.checks, check{name: name, fn: fn})
+}
+
+// SetShuttingDown makes readiness fail from now on, so the orchestrator stops
+// routing traffic here while in-flight requests drain.
+func (h *Health) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Liveness answers as long as the process can serve HTTP at all. It does not
+// look at dependencies, so an outage never gets healthy pods restarted.
+func (h *Health) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{Status: StatusUp})
+}
+
+// Readiness runs every check concurrently and answers 503 if any of them
+// fails or the server is shutting down.
+func (h *Health) Readiness(c *gin.Context) {
+	response := h.Check(c.Request.Context())
+
+	status := http.StatusOK
+	if response.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, response)
+}
+
+// Check runs the readiness checks and aggregates their results.
+func (h *Health) Check(ctx context.Context) Response {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	response := Response{Status: StatusUp, Checks: make(map[string]CheckResult, len(h.checks)+1)}
+	if h.shuttingDown.Load() {
+		response.Status = StatusDown
+		response.Checks["shutdown"] = CheckResult{Status: StatusDown}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, chk := range h.checks {
+		wg.Add(1)
+		go func(chk check) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := chk.fn(ctx)
+			result := CheckResult{
+				Status:    StatusUp,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				// Details stay in the log; probes are reachable from outside.
+				slog.WarnContext(ctx, "readiness check failed", "check", chk.name, "error", err)
+				result.Status = StatusDown
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[chk.name] = result
+			if err != nil {
+				response.Status = StatusDown
+			}
+		}(chk)
+	}
+	wg.Wait()
+
+	return response
+}
@@ -0,0 +1,78 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func probe(h *Health, path string) (int, Response) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/healthz", h.Liveness)
+	router.GET("/readyz", h.Readiness)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var response Response
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func TestReadyWhenAllChecksPass(t *testing.T) {
+	h := New(time.Second)
+	h.Register("database", func(ctx context.Context) error { return nil })
+	h.Register("schema", func(ctx context.Context) error { return nil })
+
+	code, response := probe(h, "/readyz")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusUp, response.Status)
+	assert.Equal(t, StatusUp, response.Checks["database"].Status)
+	assert.Equal(t, StatusUp, response.Checks["schema"].Status)
+}
+
+func TestNotReadyWhenACheckFails(t *testing.T) {
+	h := New(time.Second)
+	h.Register("database", func(ctx context.Context) error { return nil })
+	h.Register("cache", func(ctx context.Context) error { return assert.AnError })
+
+	code, response := probe(h, "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusDown, response.Status)
+	assert.Equal(t, StatusUp, response.Checks["database"].Status)
+	assert.Equal(t, StatusDown, response.Checks["cache"].Status)
+}
+
+func TestSlowCheckTimesOut(t *testing.T) {
+	h := New(20 * time.Millisecond)
+	h.Register("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	code, response := probe(h, "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.GreaterOrEqual(t, response.Checks["database"].LatencyMS, 20.0)
+}
+
+func TestNotReadyDuringShutdownButStillLive(t *testing.T) {
+	h := New(time.Second)
+	h.SetShuttingDown()
+
+	readyCode, response := probe(h, "/readyz")
+	liveCode, _ := probe(h, "/healthz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, readyCode)
+	assert.Equal(t, StatusDown, response.Checks["shutdown"].Status)
+	assert.Equal(t, http.StatusOK, liveCode)
+}
@@ -0,0 +1,188 @@
+// Package i18n holds the message catalogues for every language the API
+// speaks and negotiates which one to use for a request.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Language string
+
+const (
+	English    Language = "en"
+	Indonesian Language = "id"
+
+	// Default is used when neither the user nor the client asked for a
+	// supported language. Most of our users are Indonesian.
+	Default = Indonesian
+)
+
+// Params fills the {name} placeholders of a message.
+type Params map[string]string
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var catalogues = mustLoadCatalogues()
+
+func mustLoadCatalogues() map[Language]map[string]string {
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: reading locales: %v", err))
+	}
+
+	loaded := make(map[Language]map[string]string, len(files))
+	for _, file := range files {
+		data, err := localeFiles.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: reading %s: %v", file.Name(), err))
+		}
+
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: parsing %s: %v", file.Name(), err))
+		}
+		loaded[Language(strings.TrimSuffix(file.Name(), ".json"))] = messages
+	}
+	return loaded
+}
+
+// Languages returns the supported languages in a stable order.
+func Languages() []Language {
+	languages := make([]Language, 0, len(catalogues))
+	for lang := range catalogues {
+		languages = append(languages, lang)
+	}
+	sort.Slice(languages, func(i, j int) bool { return languages[i] < languages[j] })
+	return languages
+}
+
+// Parse returns the supported language matching tag, e.g. "id" or "en-US".
+func Parse(tag string) (Language, bool) {
+	primary := strings.ToLower(strings.TrimSpace(strings.SplitN(tag, "-", 2)[0]))
+	if _, ok := catalogues[Language(primary)]; ok {
+		return Language(primary), true
+	}
+	return "", false
+}
+
+// Lookup returns the message for key in lang with params filled in. It falls
+// back to the default language when lang lacks the key.
+func Lookup(lang Language, key string, params Params) (string, bool) {
+	message, ok := catalogues[lang][key]
+	if !ok {
+		message, ok = catalogues[Default][key]
+	}
+	if !ok {
+		return "", false
+	}
+
+	for name, value := range params {
+		message = strings.ReplaceAll(message, "{"+name+"}", value)
+	}
+	return message, true
+}
+
+// T is Lookup for keys that are known to exist; a missing key is returned
+// as is so that it stands out instead of rendering an empty string.
+func T(lang Language, key string, params Params) string {
+	if message, ok := Lookup(lang, key, params); ok {
+		return message
+	}
+	return key
+}
+
+// Negotiate picks the best supported language from an Accept-Language
+// header, honouring quality values. It returns Default when nothing matches.
+func Negotiate(acceptLanguage string) Language {
+	type candidate struct {
+		lang    string
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if fields[0] == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{lang: fields[0], quality: quality})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	for _, c := range candidates {
+		if lang, ok := Parse(c.lang); ok {
+			return lang
+		}
+	}
+	return Default
+}
+
+// FormatAmount formats an amount with the digit grouping of lang, e.g.
+// 1500000 is "1.500.000" in Indonesian and "1,500,000" in English. Decimals
+// are only shown when the amount has any.
+func FormatAmount(lang Language, amount float64) string {
+	thousands, decimal := ",", "."
+	if lang == Indonesian {
+		thousands, decimal = ".", ","
+	}
+
+	formatted := strconv.FormatFloat(amount, 'f', 2, 64)
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign, formatted = "-", formatted[1:]
+	}
+	whole, fraction := formatted[:len(formatted)-3], formatted[len(formatted)-2:]
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteString(thousands)
+		}
+		grouped.WriteRune(digit)
+	}
+
+	if fraction == "00" {
+		return sign + grouped.String()
+	}
+	return sign + grouped.String() + decimal + fraction
+}
+
+// Catalogue returns a copy of every message in lang.
+func Catalogue(lang Language) map[string]string {
+	messages := make(map[string]string, len(catalogues[lang]))
+	for key, message := range catalogues[lang] {
+		messages[key] = message
+	}
+	return messages
+}
+
+// FormatMoney formats amount in currency: rupiah as "Rp1.500.000" and any
+// other currency with its code, e.g. "USD 12.50".
+func FormatMoney(lang Language, amount float64, currency string) string {
+	if currency == "" || currency == "IDR" {
+		return "Rp" + FormatAmount(lang, amount)
+	}
+	return currency + " " + FormatAmount(lang, amount)
+}
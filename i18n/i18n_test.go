@@ -0,0 +1,90 @@
+package i18n_test
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+
+	"myapp/apperrors"
+	"myapp/i18n"
+	"myapp/validation"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var placeholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+func placeholders(message string) []string {
+	found := placeholder.FindAllString(message, -1)
+	sort.Strings(found)
+	return found
+}
+
+// TestCataloguesHaveSameKeys checks that every key exists in every catalogue
+// and uses the same placeholders there
+func TestCataloguesHaveSameKeys(t *testing.T) {
+	reference := i18n.Catalogue(i18n.Default)
+	assert.NotEmpty(t, reference)
+
+	for _, lang := range i18n.Languages() {
+		catalogue := i18n.Catalogue(lang)
+
+		for key, message := range reference {
+			translated, ok := catalogue[key]
+			if assert.True(t, ok, "%s is missing %q", lang, key) {
+				assert.Equal(t, placeholders(message), placeholders(translated), "%s: placeholders of %q", lang, key)
+			}
+		}
+		for key := range catalogue {
+			assert.Contains(t, reference, key, "%s has %q which %s lacks", lang, key, i18n.Default)
+		}
+	}
+}
+
+// TestErrorCodesAndRulesAreTranslated checks the keys produced by other packages
+func TestErrorCodesAndRulesAreTranslated(t *testing.T) {
+	var keys []string
+	for _, code := range apperrors.Codes() {
+		keys = append(keys, "error."+string(code))
+	}
+	for _, rule := range validation.Rules() {
+		keys = append(keys, "validation."+rule)
+	}
+	keys = append(keys, "validation.not_blank", "validation.self_transfer")
+
+	for _, lang := range i18n.Languages() {
+		for _, key := range keys {
+			assert.Contains(t, i18n.Catalogue(lang), key, "%s is missing %q", lang, key)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	assert.Equal(t, i18n.English, i18n.Negotiate("en-US,en;q=0.9"))
+	assert.Equal(t, i18n.Indonesian, i18n.Negotiate("fr-FR, id;q=0.5, en;q=0.3"))
+	assert.Equal(t, i18n.English, i18n.Negotiate("id;q=0.2, en;q=0.8"))
+	assert.Equal(t, i18n.Default, i18n.Negotiate("fr, de;q=0.7"))
+	assert.Equal(t, i18n.Default, i18n.Negotiate(""))
+	assert.Equal(t, i18n.Default, i18n.Negotiate("en;q=0"))
+}
+
+func TestLookupFillsParams(t *testing.T) {
+	message := i18n.T(i18n.English, "validation.max_length", i18n.Params{"param": "50"})
+
+	assert.Equal(t, "must be at most 50 characters", message)
+	assert.Equal(t, "no.such.key", i18n.T(i18n.English, "no.such.key", nil))
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "1.500.000", i18n.FormatAmount(i18n.Indonesian, 1500000))
+	assert.Equal(t, "1,500,000", i18n.FormatAmount(i18n.English, 1500000))
+	assert.Equal(t, "12.345,50", i18n.FormatAmount(i18n.Indonesian, 12345.5))
+	assert.Equal(t, "999", i18n.FormatAmount(i18n.English, 999))
+	assert.Equal(t, "-1,000", i18n.FormatAmount(i18n.English, -1000))
+}
+
+func TestFormatMoney(t *testing.T) {
+	assert.Equal(t, "Rp1.500.000", i18n.FormatMoney(i18n.Indonesian, 1500000, "IDR"))
+	assert.Equal(t, "USD 12.50", i18n.FormatMoney(i18n.English, 12.5, "USD"))
+	assert.Equal(t, "SGD 1.200,75", i18n.FormatMoney(i18n.Indonesian, 1200.75, "SGD"))
+}
@@ -0,0 +1,105 @@
+// Package logging configures the structured application log. Every record
+// goes through a Handler that adds the request-scoped attributes stored in
+// the context, such as the request ID, and masks personal data before it is
+// written.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"myapp/config"
+)
+
+// New returns a logger writing to w in the format and level from cfg.
+func New(w io.Writer, cfg config.LogConfig) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLevel(cfg.Level)}
+
+	var next slog.Handler
+	if cfg.Format == "text" {
+		next = slog.NewTextHandler(w, opts)
+	} else {
+		next = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(NewHandler(next))
+}
+
+// Setup installs the logger from cfg as the process-wide default. Output of
+// the standard log package is routed through it as well.
+func Setup(cfg config.LogConfig) {
+	slog.SetDefault(New(os.Stdout, cfg))
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+type contextKey struct{}
+
+// With returns a copy of ctx carrying attrs. Records logged with that
+// context, or any context derived from it, include them.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing := fromContext(ctx)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, contextKey{}, merged)
+}
+
+func fromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	return attrs
+}
+
+// Handler wraps another slog.Handler, adding the attributes stored with
+// With and masking sensitive values on the way through.
+type Handler struct {
+	next slog.Handler
+}
+
+func NewHandler(next slog.Handler) *Handler {
+	return &Handler{next: next}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	masked := slog.NewRecord(r.Time, r.Level, scrub(r.Message), r.PC)
+	for _, attr := range fromContext(ctx) {
+		masked.AddAttrs(maskAttr(attr))
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		masked.AddAttrs(maskAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, masked)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		masked[i] = maskAttr(attr)
+	}
+	return &Handler{next: h.next.WithAttrs(masked)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// phonePattern matches E.164 numbers and Indonesian mobile numbers in
+	// their local (08…) and international (628…) forms.
+	phonePattern = regexp.MustCompile(`\+\d{8,15}\b|\b(?:62|0)8\d{7,12}\b`)
+	// tokenPattern matches JWTs, bearer or not.
+	tokenPattern = regexp.MustCompile(`eyJ[\w-]+\.[\w-]+\.[\w-]*`)
+)
+
+// keyKind reports how the value under key must be masked: phone numbers keep
+// their last digits for support lookups, everything else sensitive is
+// dropped entirely.
+func keyKind(key string) (phone, secret bool) {
+	key = strings.ToLower(key)
+	switch {
+	case strings.Contains(key, "phone"):
+		return true, false
+	case key == "pin", strings.HasSuffix(key, "_pin"),
+		strings.Contains(key, "address"),
+		strings.Contains(key, "token"),
+		strings.Contains(key, "secret"),
+		strings.Contains(key, "password"),
+		strings.Contains(key, "authorization"):
+		return false, true
+	}
+	return false, false
+}
+
+func maskAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+
+	if value.Kind() == slog.KindGroup {
+		group := value.Group()
+		masked := make([]slog.Attr, len(group))
+		for i, member := range group {
+			masked[i] = maskAttr(member)
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(masked...)}
+	}
+
+	phone, secret := keyKind(attr.Key)
+	switch {
+	case secret:
+		return slog.String(attr.Key, redacted)
+	case phone:
+		return slog.String(attr.Key, MaskPhone(value.String()))
+	}
+
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, scrub(value.String()))
+	case slog.KindAny:
+		// Errors often quote the offending input, e.g. a duplicate key.
+		if err, ok := value.Any().(error); ok {
+			return slog.String(attr.Key, scrub(err.Error()))
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
+
+// scrub masks phone numbers and tokens embedded in free text.
+func scrub(s string) string {
+	s = tokenPattern.ReplaceAllString(s, redacted)
+	return phonePattern.ReplaceAllStringFunc(s, MaskPhone)
+}
+
+// MaskPhone hides all but the last four characters of a phone number.
+func MaskPhone(phone string) string {
+	const visible = 4
+	if len(phone) <= visible {
+		return strings.Repeat("*", len(phone))
+	}
+	return strings.Repeat("*", len(phone)-visible) + phone[len(phone)-visible:]
+}
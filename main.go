@@ -1,15 +1,38 @@
 package main
 
 import (
-	"myapp/database"
-	"myapp/routers"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"myapp/apperrors"
+	"myapp/cli"
 )
 
 func main() {
-	database.Connect()
-	database.Migrate()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	r := routers.SetupRouter()
-	r.Run(":8080")
+	err := cli.Run(ctx, os.Args[1:], os.Stdout)
+	switch {
+	case err == nil:
+		return
+	case errors.Is(err, cli.ErrUsage):
+		fmt.Fprintf(os.Stderr, "%v\n\n%s\n", err, cli.Usage())
+		os.Exit(2)
+	}
 
+	// Domain errors are the operator's fault, e.g. an unknown user, and
+	// read better without a stack of causes.
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", appErr.Code, appErr.Message)
+		os.Exit(1)
+	}
+	slog.Error("command failed", "error", err)
+	os.Exit(1)
 }
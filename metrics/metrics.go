@@ -0,0 +1,166 @@
+// Package metrics exposes Prometheus metrics for HTTP traffic, the database
+// pool and wallet activity.
+//
+// Labels are limited to bounded values such as route templates, methods,
+// status codes, operation names, error codes and the currencies the wallet
+// supports. User IDs, phone numbers and
+// raw paths must never be used as label values.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"myapp/apperrors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "myapp"
+
+	OperationTopUp    = "top_up"
+	OperationPayment  = "payment"
+	OperationTransfer = "transfer"
+	OperationRefund   = "refund"
+
+	OutcomeSuccess = "success"
+
+	amountKey = "metrics_amount"
+)
+
+// amount is the value moved by a wallet operation.
+type amount struct {
+	value    float64
+	currency string
+}
+
+// Registry holds every collector of the application.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route template, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	walletOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wallet_operations_total",
+		Help:      "Top-ups, payments and transfers by outcome (success or error code).",
+	}, []string{"operation", "outcome"})
+
+	walletAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wallet_amount_total",
+		Help:      "Total value moved by successful wallet operations, by currency. Values in different currencies must not be summed.",
+	}, []string{"operation", "currency"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		walletOperations,
+		walletAmount,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDBStats exposes the statistics of the connection pool.
+func RegisterDBStats(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterReplicaLag exposes the replication lag of a read replica, as
+// reported by lag.
+func RegisterReplicaLag(name string, lag func() time.Duration) error {
+	return Registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "db_replica_lag_seconds",
+		Help:        "Replication lag of a read replica as of the last check.",
+		ConstLabels: prometheus.Labels{"replica": name},
+	}, func() float64 { return lag().Seconds() }))
+}
+
+// knownMethods are the HTTP methods labelled as themselves; any other is
+// "other".
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+}
+
+// Middleware counts and times every request. Requests that match no route
+// are grouped under "unmatched", and unknown methods under "other", so that
+// arbitrary requests cannot create new series.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		if !knownMethods[method] {
+			method = "other"
+		}
+
+		httpRequests.WithLabelValues(route, method, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// WalletOperation counts the outcome of a money-moving handler. The outcome
+// is the error code of the last error attached to the context, or "success".
+// Handlers report the value moved with RecordAmount.
+func WalletOperation(operation string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if last := c.Errors.Last(); last != nil {
+			code := apperrors.From(last.Err).Code
+			walletOperations.WithLabelValues(operation, strings.ToLower(string(code))).Inc()
+			return
+		}
+
+		walletOperations.WithLabelValues(operation, OutcomeSuccess).Inc()
+		if moved, ok := c.Get(amountKey); ok {
+			moved := moved.(amount)
+			walletAmount.WithLabelValues(operation, moved.currency).Add(moved.value)
+		}
+	}
+}
+
+// RecordAmount reports the value moved by a successful wallet operation in
+// currency. The wallet only moves the currencies it supports, which keeps
+// the currency label bounded.
+func RecordAmount(c *gin.Context, value float64, currency string) {
+	c.Set(amountKey, amount{value: value, currency: currency})
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myapp/apperrors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.POST("/pay", WalletOperation(OperationPayment), func(c *gin.Context) {
+		if c.Query("fail") != "" {
+			_ = c.Error(apperrors.ErrInsufficientBalance)
+			c.Status(http.StatusUnprocessableEntity)
+			return
+		}
+		RecordAmount(c, 25000, "IDR")
+		if c.Query("usd") != "" {
+			RecordAmount(c, 10, "USD")
+		}
+		c.Status(http.StatusOK)
+	})
+	router.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func serve(router *gin.Engine, method, path string) {
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+}
+
+func TestWalletOperationCountsOutcomeAndValue(t *testing.T) {
+	router := newRouter()
+	success := walletOperations.WithLabelValues(OperationPayment, OutcomeSuccess)
+	insufficient := walletOperations.WithLabelValues(OperationPayment, "insufficient_balance")
+	rupiah := walletAmount.WithLabelValues(OperationPayment, "IDR")
+	dollars := walletAmount.WithLabelValues(OperationPayment, "USD")
+	before := []float64{testutil.ToFloat64(success), testutil.ToFloat64(insufficient),
+		testutil.ToFloat64(rupiah), testutil.ToFloat64(dollars)}
+
+	serve(router, http.MethodPost, "/pay")
+	serve(router, http.MethodPost, "/pay?usd=1")
+	serve(router, http.MethodPost, "/pay?fail=1")
+
+	assert.Equal(t, before[0]+2, testutil.ToFloat64(success))
+	assert.Equal(t, before[1]+1, testutil.ToFloat64(insufficient))
+	assert.Equal(t, before[2]+25000, testutil.ToFloat64(rupiah))
+	assert.Equal(t, before[3]+10, testutil.ToFloat64(dollars), "currencies are kept apart")
+}
+
+func TestMiddlewareUsesRouteTemplates(t *testing.T) {
+	router := newRouter()
+
+	serve(router, http.MethodGet, "/users/3b241101-e2bb-4255-8caf-4136c566a962")
+	serve(router, http.MethodGet, "/no/such/path/08123456789")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("/users/:id", http.MethodGet, "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("unmatched", http.MethodGet, "404")))
+
+	serve(router, "FOO1", "/users/3b241101-e2bb-4255-8caf-4136c566a962")
+	serve(router, "FOO2", "/no/such/path")
+	assert.Equal(t, 2.0, testutil.ToFloat64(httpRequests.WithLabelValues("unmatched", "other", "404")))
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, "myapp_http_request_duration_seconds_bucket"))
+	assert.NotContains(t, body, "3b241101-e2bb-4255-8caf-4136c566a962")
+	assert.NotContains(t, body, "08123456789")
+	assert.NotContains(t, body, "FOO1")
+}
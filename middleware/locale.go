@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"myapp/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+const languageKey = "language"
+
+// Locale negotiates the response language from the Accept-Language header.
+// Handlers that know the caller may override it with SetLanguage.
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setLanguage(c, i18n.Negotiate(c.GetHeader("Accept-Language")))
+		c.Next()
+	}
+}
+
+// SetLanguage switches the request to the user's preferred language. Empty
+// or unsupported preferences keep the negotiated language.
+func SetLanguage(c *gin.Context, preferred string) {
+	if lang, ok := i18n.Parse(preferred); ok {
+		setLanguage(c, lang)
+	}
+}
+
+// GetLanguage returns the language chosen for the request, or i18n.Default
+// when the middleware is not installed.
+func GetLanguage(c *gin.Context) i18n.Language {
+	if lang, ok := c.Get(languageKey); ok {
+		return lang.(i18n.Language)
+	}
+	return i18n.Default
+}
+
+func setLanguage(c *gin.Context, lang i18n.Language) {
+	c.Set(languageKey, lang)
+	c.Header("Content-Language", string(lang))
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myapp/auth"
+	"myapp/config"
+	"myapp/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, config.LogConfig{Level: "debug", Format: "json"}))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Log line is not JSON: %v\n%s", err, line)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAccessLogIncludesRequestContext(t *testing.T) {
+	buf := captureLogs(t)
+	token, err := auth.GenerateJWT("user-1", "+6281234567890", "access")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(), Identify(), AccessLog())
+	router.GET("/users/:id", func(c *gin.Context) {
+		slog.InfoContext(c.Request.Context(), "handling")
+		c.Status(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.Header.Set("Authorization", token)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	records := logRecords(t, buf)
+	if assert.Len(t, records, 2) {
+		for _, record := range records {
+			assert.Equal(t, "req-1", record["request_id"])
+			assert.Equal(t, "user-1", record["user_id"])
+			assert.Equal(t, "/users/:id", record["route"])
+		}
+
+		access := records[1]
+		assert.Equal(t, "request completed", access["msg"])
+		assert.Equal(t, "WARN", access["level"])
+		assert.Equal(t, float64(http.StatusNotFound), access["status"])
+	}
+}
+
+func TestIdentifyIgnoresInvalidTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Identify())
+
+	var seen string
+	router.GET("/", func(c *gin.Context) {
+		seen = GetUserID(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "not-a-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, seen)
+}
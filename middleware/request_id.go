@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"myapp/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+
+	maxRequestIDLength = 128
+)
+
+// RequestID makes sure every request has an ID. A well-formed X-Request-ID
+// sent by the client or an upstream proxy is kept, otherwise a new one is
+// generated. The ID is echoed back in the response headers and attached to
+// every log record written with the request context.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(requestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), slog.String("request_id", requestID)))
+		c.Next()
+	}
+}
+
+// GetRequestID returns the ID assigned by RequestID, or an empty string when
+// the middleware is not installed.
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func performRequestID(header string) (*httptest.ResponseRecorder, string) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+
+	var seen string
+	router.GET("/", func(c *gin.Context) {
+		seen = GetRequestID(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(RequestIDHeader, header)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w, seen
+}
+
+func TestRequestIDHonoursHeader(t *testing.T) {
+	w, seen := performRequestID("abc-123")
+
+	assert.Equal(t, "abc-123", seen)
+	assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestIDGeneratesWhenMissingOrInvalid(t *testing.T) {
+	for _, header := range []string{"", "has space", strings.Repeat("x", 200)} {
+		w, seen := performRequestID(header)
+
+		assert.NotEmpty(t, seen)
+		assert.NotEqual(t, header, seen)
+		assert.Equal(t, seen, w.Header().Get(RequestIDHeader))
+	}
+}
@@ -0,0 +1,84 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	// lockKey identifies the migration lock among other advisory locks.
+	lockKey = 72066405 // "myapp" on a phone keypad, padded
+	// lockName is the MySQL equivalent of lockKey.
+	lockName = "myapp_migrate"
+)
+
+// dialect holds what differs between databases.
+type dialect struct {
+	lock   func(ctx context.Context, conn *sql.Conn) error
+	unlock func(ctx context.Context, conn *sql.Conn) error
+	// bind rewrites ? placeholders into the database's own syntax.
+	bind func(query string) string
+}
+
+var dialects = map[string]dialect{
+	"postgres": {
+		// Session-level advisory locks block until the holder releases
+		// them, so a second instance waits and then finds nothing to do.
+		lock: func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(`+strconv.Itoa(lockKey)+`)`)
+			return err
+		},
+		unlock: func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock(`+strconv.Itoa(lockKey)+`)`)
+			return err
+		},
+		bind: dollarPlaceholders,
+	},
+	"mysql": {
+		// Named locks belong to the session like Postgres advisory locks;
+		// a negative timeout waits for as long as the holder needs.
+		lock: func(ctx context.Context, conn *sql.Conn) error {
+			var acquired sql.NullInt64
+			if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK('`+lockName+`', -1)`).Scan(&acquired); err != nil {
+				return err
+			}
+			if acquired.Int64 != 1 {
+				return errors.New("GET_LOCK failed")
+			}
+			return nil
+		},
+		unlock: func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, `SELECT RELEASE_LOCK('`+lockName+`')`)
+			return err
+		},
+		bind: func(query string) string { return query },
+	},
+	"sqlite": {
+		// SQLite admits one writer at a time, so each migration's
+		// transaction already excludes the others.
+		lock:   noLock,
+		unlock: noLock,
+		bind:   func(query string) string { return query },
+	},
+}
+
+func noLock(ctx context.Context, conn *sql.Conn) error {
+	return nil
+}
+
+func dollarPlaceholders(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,350 @@
+// Package migrate applies versioned SQL migrations. Applied versions are
+// recorded with a checksum of their up script in the schema_migrations
+// table. A migration and its version row are written in one transaction
+// (MySQL commits DDL implicitly, so there a failed migration may leave part
+// of its changes behind), and a database-level lock keeps instances that
+// start at the same time from applying the same migration twice.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Table records the applied migrations.
+const Table = "schema_migrations"
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)(?:\.([a-z]+))?\.sql$`)
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// script is one file found by Load.
+type script struct {
+	content string
+	// specific means the file is written for one dialect only.
+	specific bool
+}
+
+// Load reads the migrations for dialectName in the root of fsys, sorted by
+// version. A script named NNNN_name.up.sql serves every database; one named
+// NNNN_name.up.<dialect>.sql, e.g. 0001_baseline.up.mysql.sql, takes its
+// place on that database and is ignored elsewhere. Every version needs both
+// an up and a down script for the dialect.
+func Load(fsys fs.FS, dialectName string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[int64]string{}
+	ups, downs := map[int64]script{}, map[int64]script{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migrate: %s does not match NNNN_name.up.sql or NNNN_name.down.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %s: %w", entry.Name(), err)
+		}
+		if name, ok := names[version]; ok && name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d is used by both %s and %s", version, name, match[2])
+		}
+		names[version] = match[2]
+
+		if match[4] != "" && match[4] != dialectName {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		scripts := ups
+		if match[3] == "down" {
+			scripts = downs
+		}
+		if existing, ok := scripts[version]; ok && existing.specific {
+			continue
+		}
+		scripts[version] = script{content: string(content), specific: match[4] != ""}
+	}
+
+	migrations := make([]Migration, 0, len(names))
+	for version, name := range names {
+		up, hasUp := ups[version]
+		down, hasDown := downs[version]
+		if !hasUp || !hasDown {
+			return nil, fmt.Errorf("migrate: version %d (%s) needs both an up and a down script for %s", version, name, dialectName)
+		}
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     name,
+			Up:       up.content,
+			Down:     down.content,
+			Checksum: checksum(up.content),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func checksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// Status describes one migration, known from the files, the database or
+// both.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Modified means the up script changed after it was applied.
+	Modified bool
+	// Missing means the migration is applied but this build has no file for
+	// it, e.g. after rolling back to an older release.
+	Missing bool
+}
+
+// ErrModified is returned when an applied migration has been edited.
+var ErrModified = errors.New("migrate: applied migration has been modified")
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []Migration
+}
+
+// New returns a migrator for db. dialectName is the GORM dialector name:
+// "postgres", "mysql" or "sqlite".
+func New(db *sql.DB, dialectName string, migrations []Migration) (*Migrator, error) {
+	d, ok := dialects[dialectName]
+	if !ok {
+		return nil, fmt.Errorf("migrate: unsupported database %q", dialectName)
+	}
+	return &Migrator{db: db, dialect: d, migrations: migrations}, nil
+}
+
+// Latest returns the highest version this build knows about.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration in order and returns those applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		records, err := m.records(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			record, ok := records[migration.Version]
+			if ok {
+				if record.checksum != migration.Checksum {
+					return fmt.Errorf("%w: %04d_%s", ErrModified, migration.Version, migration.Name)
+				}
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the last steps applied migrations, newest first, and
+// returns those rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		records, err := m.records(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := records[migration.Version]; !ok {
+				continue
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status lists every migration known to the files or the database.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withConn(ctx, func(conn *sql.Conn) error {
+		records, err := m.records(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		known := map[int64]bool{}
+		for _, migration := range m.migrations {
+			known[migration.Version] = true
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if record, ok := records[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = record.appliedAt
+				status.Modified = record.checksum != migration.Checksum
+			}
+			statuses = append(statuses, status)
+		}
+		for version, record := range records {
+			if !known[version] {
+				statuses = append(statuses, Status{
+					Version: version, Name: record.name, Applied: true, AppliedAt: record.appliedAt, Missing: true,
+				})
+			}
+		}
+		sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+		return nil
+	})
+	return statuses, err
+}
+
+// Check reports an error when migrations are pending or applied ones have
+// been modified, i.e. when the schema is not what this build expects.
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if status.Modified {
+			return fmt.Errorf("%w: %04d_%s", ErrModified, status.Version, status.Name)
+		}
+		if !status.Applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("migrate: %d migrations pending, latest is %d", pending, m.Latest())
+	}
+	return nil
+}
+
+type record struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+func (m *Migrator) records(ctx context.Context, conn *sql.Conn) (map[int64]record, error) {
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+Table+` (
+		version    BIGINT       NOT NULL PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		checksum   VARCHAR(64)  NOT NULL,
+		applied_at TIMESTAMP    NOT NULL
+	)`); err != nil {
+		return nil, fmt.Errorf("migrate: create %s: %w", Table, err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM `+Table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := map[int64]record{}
+	for rows.Next() {
+		var version int64
+		var r record
+		if err := rows.Scan(&version, &r.name, &r.checksum, &r.appliedAt); err != nil {
+			return nil, err
+		}
+		records[version] = r
+	}
+	return records, rows.Err()
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	return m.inTransaction(ctx, conn, migration, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, m.dialect.bind(`INSERT INTO `+Table+` (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`),
+			migration.Version, migration.Name, migration.Checksum, time.Now().UTC())
+		return err
+	})
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	return m.inTransaction(ctx, conn, migration, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, m.dialect.bind(`DELETE FROM `+Table+` WHERE version = ?`), migration.Version)
+		return err
+	})
+}
+
+func (m *Migrator) inTransaction(ctx context.Context, conn *sql.Conn, migration Migration, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("migrate: %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return tx.Commit()
+}
+
+// locked runs fn on a single connection holding the migration lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	return m.withConn(ctx, func(conn *sql.Conn) error {
+		if err := m.dialect.lock(ctx, conn); err != nil {
+			return fmt.Errorf("migrate: acquire lock: %w", err)
+		}
+		defer func() {
+			// The lock dies with the session anyway; a failed unlock only
+			// delays the next migrator until the connection is closed.
+			_ = m.dialect.unlock(context.Background(), conn)
+		}()
+		return fn(conn)
+	})
+}
+
+func (m *Migrator) withConn(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return fn(conn)
+}
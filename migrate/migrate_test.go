@@ -0,0 +1,258 @@
+package migrate_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	"myapp/migrate"
+	"myapp/migrations"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_accounts.up.sql":   {Data: []byte(`CREATE TABLE accounts (id INTEGER PRIMARY KEY, name TEXT);`)},
+		"0001_create_accounts.down.sql": {Data: []byte(`DROP TABLE accounts;`)},
+		"0002_add_balance.up.sql":       {Data: []byte(`ALTER TABLE accounts ADD COLUMN balance NUMERIC NOT NULL DEFAULT 0;`)},
+		"0002_add_balance.down.sql":     {Data: []byte(`ALTER TABLE accounts DROP COLUMN balance;`)},
+		"README.md":                     {Data: []byte(`ignored`)},
+	}
+}
+
+// openDB returns a fresh in-memory SQLite database for one test.
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return sqlDB
+}
+
+func newMigrator(t *testing.T, db *sql.DB, fsys fstest.MapFS) *migrate.Migrator {
+	t.Helper()
+	all, err := migrate.Load(fsys, "sqlite")
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	migrator, err := migrate.New(db, "sqlite", all)
+	if err != nil {
+		t.Fatalf("Failed to create migrator: %v", err)
+	}
+	return migrator
+}
+
+func hasTable(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count); err != nil {
+		t.Fatalf("Failed to look up table %s: %v", name, err)
+	}
+	return count > 0
+}
+
+func TestLoad(t *testing.T) {
+	all, err := migrate.Load(testFS(), "sqlite")
+	assert.NoError(t, err)
+	if !assert.Len(t, all, 2) {
+		return
+	}
+	assert.Equal(t, int64(1), all[0].Version)
+	assert.Equal(t, "create_accounts", all[0].Name)
+	assert.Equal(t, int64(2), all[1].Version)
+	assert.Len(t, all[0].Checksum, 64)
+}
+
+func TestLoadRejectsIncompleteMigrations(t *testing.T) {
+	fsys := testFS()
+	delete(fsys, "0002_add_balance.down.sql")
+	_, err := migrate.Load(fsys, "sqlite")
+	assert.ErrorContains(t, err, "needs both an up and a down script")
+
+	fsys = testFS()
+	fsys["3_Bad Name.up.sql"] = &fstest.MapFile{Data: []byte(`SELECT 1;`)}
+	_, err = migrate.Load(fsys, "sqlite")
+	assert.ErrorContains(t, err, "does not match")
+}
+
+func TestLoadPrefersDialectScripts(t *testing.T) {
+	fsys := testFS()
+	fsys["0001_create_accounts.up.mysql.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE accounts (id BIGINT PRIMARY KEY, name VARCHAR(255));`)}
+	fsys["0001_create_accounts.down.mysql.sql"] = &fstest.MapFile{Data: []byte(`DROP TABLE accounts;`)}
+
+	sqliteMigrations, err := migrate.Load(fsys, "sqlite")
+	assert.NoError(t, err)
+	mysqlMigrations, err := migrate.Load(fsys, "mysql")
+	assert.NoError(t, err)
+	if assert.Len(t, sqliteMigrations, 2) && assert.Len(t, mysqlMigrations, 2) {
+		assert.Contains(t, sqliteMigrations[0].Up, "INTEGER PRIMARY KEY")
+		assert.Contains(t, mysqlMigrations[0].Up, "BIGINT PRIMARY KEY")
+		assert.NotEqual(t, sqliteMigrations[0].Checksum, mysqlMigrations[0].Checksum)
+		assert.Equal(t, sqliteMigrations[1], mysqlMigrations[1], "generic scripts serve every dialect")
+	}
+
+	fsys = testFS()
+	delete(fsys, "0002_add_balance.up.sql")
+	fsys["0002_add_balance.up.postgres.sql"] = &fstest.MapFile{Data: []byte(`ALTER TABLE accounts ADD COLUMN balance numeric;`)}
+	_, err = migrate.Load(fsys, "sqlite")
+	assert.ErrorContains(t, err, "needs both an up and a down script for sqlite")
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	for _, dialect := range []string{"postgres", "mysql", "sqlite"} {
+		all, err := migrate.Load(migrations.FS, dialect)
+		assert.NoError(t, err, dialect)
+		assert.NotEmpty(t, all, dialect)
+		for i, m := range all {
+			assert.Equal(t, int64(i+1), m.Version, "%s versions must be consecutive", dialect)
+		}
+	}
+}
+
+func TestEmbeddedSQLiteMigrationsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	all, err := migrate.Load(migrations.FS, "sqlite")
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	migrator, err := migrate.New(db, "sqlite", all)
+	if err != nil {
+		t.Fatalf("Failed to create migrator: %v", err)
+	}
+
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.True(t, hasTable(t, db, "audit_logs"))
+
+	rolledBack, err := migrator.Down(ctx, len(all))
+	assert.NoError(t, err)
+	assert.Len(t, rolledBack, len(all))
+	assert.False(t, hasTable(t, db, "users"))
+}
+
+func TestUpDownAndStatus(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	migrator := newMigrator(t, db, testFS())
+
+	assert.ErrorContains(t, migrator.Check(ctx), "2 migrations pending")
+
+	applied, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 2)
+	assert.True(t, hasTable(t, db, "accounts"))
+	assert.NoError(t, migrator.Check(ctx))
+
+	// Nothing left to do
+	applied, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 2)
+	for _, status := range statuses {
+		assert.True(t, status.Applied)
+		assert.False(t, status.AppliedAt.IsZero())
+	}
+
+	rolledBack, err := migrator.Down(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, rolledBack, 1) {
+		assert.Equal(t, int64(2), rolledBack[0].Version)
+	}
+	assert.True(t, hasTable(t, db, "accounts"))
+
+	statuses, err = migrator.Status(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 2) {
+		assert.True(t, statuses[0].Applied)
+		assert.False(t, statuses[1].Applied)
+	}
+
+	rolledBack, err = migrator.Down(ctx, 5)
+	assert.NoError(t, err)
+	assert.Len(t, rolledBack, 1)
+	assert.False(t, hasTable(t, db, "accounts"))
+}
+
+func TestModifiedMigrationIsRejected(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	if _, err := newMigrator(t, db, testFS()).Up(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	fsys := testFS()
+	fsys["0001_create_accounts.up.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE accounts (id INTEGER PRIMARY KEY);`)}
+	migrator := newMigrator(t, db, fsys)
+
+	_, err := migrator.Up(ctx)
+	assert.ErrorIs(t, err, migrate.ErrModified)
+	assert.ErrorIs(t, migrator.Check(ctx), migrate.ErrModified)
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, statuses) {
+		assert.True(t, statuses[0].Modified)
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	fsys := testFS()
+	fsys["0002_add_balance.up.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE ledger (id INTEGER); ALTER TABLE missing ADD COLUMN x TEXT;`)}
+	migrator := newMigrator(t, db, fsys)
+
+	applied, err := migrator.Up(ctx)
+	assert.ErrorContains(t, err, "0002_add_balance")
+	assert.Len(t, applied, 1)
+	assert.False(t, hasTable(t, db, "ledger"), "a failed migration must leave nothing behind")
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 2) {
+		assert.True(t, statuses[0].Applied)
+		assert.False(t, statuses[1].Applied)
+	}
+}
+
+func TestMissingMigrationFiles(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	if _, err := newMigrator(t, db, testFS()).Up(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	// An older build only knows the first migration
+	fsys := testFS()
+	delete(fsys, "0002_add_balance.up.sql")
+	delete(fsys, "0002_add_balance.down.sql")
+	statuses, err := newMigrator(t, db, fsys).Status(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 2) {
+		assert.True(t, statuses[1].Missing)
+		assert.Equal(t, "add_balance", statuses[1].Name)
+	}
+}
+
+func TestUnsupportedDialect(t *testing.T) {
+	_, err := migrate.New(nil, "oracle", nil)
+	assert.ErrorContains(t, err, "unsupported database")
+}
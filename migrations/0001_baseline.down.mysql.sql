@@ -0,0 +1,4 @@
+DROP TABLE transfers;
+DROP TABLE payments;
+DROP TABLE top_ups;
+DROP TABLE users;
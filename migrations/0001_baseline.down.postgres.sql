@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS transfers;
+DROP TABLE IF EXISTS payments;
+DROP TABLE IF EXISTS top_ups;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,56 @@
+-- Baseline schema for MySQL, equivalent to the Postgres one. UUIDs are
+-- stored in their 36-character text form and timestamps with microseconds.
+
+CREATE TABLE users
+(
+    id           CHAR(36)     NOT NULL PRIMARY KEY,
+    first_name   VARCHAR(255),
+    last_name    VARCHAR(255),
+    phone_number VARCHAR(32),
+    address      TEXT,
+    pin          VARCHAR(255),
+    created_date DATETIME(6),
+    balance      DECIMAL(20, 2),
+    CONSTRAINT uni_users_phone_number UNIQUE (phone_number)
+);
+
+CREATE TABLE top_ups
+(
+    id             CHAR(36) NOT NULL PRIMARY KEY,
+    user_id        CHAR(36),
+    amount         DECIMAL(20, 2),
+    balance_before DECIMAL(20, 2),
+    balance_after  DECIMAL(20, 2),
+    created_date   DATETIME(6),
+    INDEX idx_top_ups_user_id (user_id),
+    CONSTRAINT fk_users_top_ups FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE payments
+(
+    id             CHAR(36) NOT NULL PRIMARY KEY,
+    user_id        CHAR(36),
+    amount         DECIMAL(20, 2),
+    remarks        TEXT,
+    balance_before DECIMAL(20, 2),
+    balance_after  DECIMAL(20, 2),
+    created_date   DATETIME(6),
+    INDEX idx_payments_user_id (user_id),
+    CONSTRAINT fk_payments_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE transfers
+(
+    id             CHAR(36) NOT NULL PRIMARY KEY,
+    from_user_id   CHAR(36),
+    to_user_id     CHAR(36),
+    amount         DECIMAL(20, 2),
+    remarks        TEXT,
+    balance_before DECIMAL(20, 2),
+    balance_after  DECIMAL(20, 2),
+    created_date   DATETIME(6),
+    INDEX idx_transfers_to_user_id (to_user_id),
+    INDEX idx_transfers_from_user_id (from_user_id),
+    CONSTRAINT fk_transfers_from_user FOREIGN KEY (from_user_id) REFERENCES users (id),
+    CONSTRAINT fk_transfers_to_user FOREIGN KEY (to_user_id) REFERENCES users (id)
+);
@@ -0,0 +1,55 @@
+-- Baseline: the schema as documented in example_data/ddl_and_sql_insert/DDL.sql.
+-- IF NOT EXISTS lets databases created earlier by AutoMigrate or by hand adopt
+-- the migrations without losing data.
+
+CREATE TABLE IF NOT EXISTS users
+(
+    id           uuid NOT NULL PRIMARY KEY,
+    first_name   text,
+    last_name    text,
+    phone_number text CONSTRAINT uni_users_phone_number UNIQUE,
+    address      text,
+    pin          text,
+    created_date timestamp with time zone,
+    balance      numeric
+);
+
+CREATE TABLE IF NOT EXISTS top_ups
+(
+    id             uuid NOT NULL PRIMARY KEY,
+    user_id        uuid CONSTRAINT fk_users_top_ups REFERENCES users,
+    amount         numeric,
+    balance_before numeric,
+    balance_after  numeric,
+    created_date   timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS idx_top_ups_user_id ON top_ups (user_id);
+
+CREATE TABLE IF NOT EXISTS payments
+(
+    id             uuid NOT NULL PRIMARY KEY,
+    user_id        uuid CONSTRAINT fk_payments_user REFERENCES users,
+    amount         numeric,
+    remarks        text,
+    balance_before numeric,
+    balance_after  numeric,
+    created_date   timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_user_id ON payments (user_id);
+
+CREATE TABLE IF NOT EXISTS transfers
+(
+    id             uuid NOT NULL PRIMARY KEY,
+    from_user_id   uuid CONSTRAINT fk_transfers_from_user REFERENCES users,
+    to_user_id     uuid CONSTRAINT fk_transfers_to_user REFERENCES users,
+    amount         numeric,
+    remarks        text,
+    balance_before numeric,
+    balance_after  numeric,
+    created_date   timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfers_to_user_id ON transfers (to_user_id);
+CREATE INDEX IF NOT EXISTS idx_transfers_from_user_id ON transfers (from_user_id);
@@ -0,0 +1,54 @@
+-- Baseline schema for SQLite, equivalent to the Postgres one. UUIDs are
+-- stored as text and amounts as REAL, matching the float64 fields.
+
+CREATE TABLE users
+(
+    id           TEXT NOT NULL PRIMARY KEY,
+    first_name   TEXT,
+    last_name    TEXT,
+    phone_number TEXT CONSTRAINT uni_users_phone_number UNIQUE,
+    address      TEXT,
+    pin          TEXT,
+    created_date DATETIME,
+    balance      REAL
+);
+
+CREATE TABLE top_ups
+(
+    id             TEXT NOT NULL PRIMARY KEY,
+    user_id        TEXT CONSTRAINT fk_users_top_ups REFERENCES users,
+    amount         REAL,
+    balance_before REAL,
+    balance_after  REAL,
+    created_date   DATETIME
+);
+
+CREATE INDEX idx_top_ups_user_id ON top_ups (user_id);
+
+CREATE TABLE payments
+(
+    id             TEXT NOT NULL PRIMARY KEY,
+    user_id        TEXT CONSTRAINT fk_payments_user REFERENCES users,
+    amount         REAL,
+    remarks        TEXT,
+    balance_before REAL,
+    balance_after  REAL,
+    created_date   DATETIME
+);
+
+CREATE INDEX idx_payments_user_id ON payments (user_id);
+
+CREATE TABLE transfers
+(
+    id             TEXT NOT NULL PRIMARY KEY,
+    from_user_id   TEXT CONSTRAINT fk_transfers_from_user REFERENCES users,
+    to_user_id     TEXT CONSTRAINT fk_transfers_to_user REFERENCES users,
+    amount         REAL,
+    remarks        TEXT,
+    balance_before REAL,
+    balance_after  REAL,
+    created_date   DATETIME
+);
+
+CREATE INDEX idx_transfers_to_user_id ON transfers (to_user_id);
+CREATE INDEX idx_transfers_from_user_id ON transfers (from_user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE transfers DROP COLUMN status;
+ALTER TABLE users DROP COLUMN version, DROP COLUMN language, DROP COLUMN updated_date;
@@ -0,0 +1,4 @@
+ALTER TABLE transfers DROP COLUMN IF EXISTS status;
+ALTER TABLE users DROP COLUMN IF EXISTS version;
+ALTER TABLE users DROP COLUMN IF EXISTS language;
+ALTER TABLE users DROP COLUMN IF EXISTS updated_date;
@@ -0,0 +1,4 @@
+ALTER TABLE transfers DROP COLUMN status;
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE users DROP COLUMN language;
+ALTER TABLE users DROP COLUMN updated_date;
@@ -0,0 +1,9 @@
+-- MySQL databases start from the baseline, so there is no drift to
+-- reconcile; this adds the same columns as on Postgres.
+
+ALTER TABLE users
+    ADD COLUMN updated_date DATETIME(6),
+    ADD COLUMN language     VARCHAR(8),
+    ADD COLUMN version      BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE transfers ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'SUCCESS';
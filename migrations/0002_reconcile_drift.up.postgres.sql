@@ -0,0 +1,12 @@
+-- Columns that AutoMigrate added in some environments but not in others.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_date timestamp with time zone;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language varchar(8);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+
+-- Production already has transfers.status, left NULL for every row. Every
+-- stored transfer has completed, so backfill them as such.
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS status varchar(16);
+UPDATE transfers SET status = 'SUCCESS' WHERE status IS NULL;
+ALTER TABLE transfers ALTER COLUMN status SET DEFAULT 'SUCCESS';
+ALTER TABLE transfers ALTER COLUMN status SET NOT NULL;
@@ -0,0 +1,8 @@
+-- SQLite databases start from the baseline, so there is no drift to
+-- reconcile; this adds the same columns as on Postgres.
+
+ALTER TABLE users ADD COLUMN updated_date DATETIME;
+ALTER TABLE users ADD COLUMN language VARCHAR(8);
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE transfers ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'SUCCESS';
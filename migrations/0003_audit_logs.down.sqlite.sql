@@ -0,0 +1,2 @@
+DROP TABLE audit_logs;
+ALTER TABLE users DROP COLUMN frozen_at;
@@ -0,0 +1,16 @@
+-- Audit trail of account changes, and administrative account freezes.
+
+ALTER TABLE users ADD COLUMN frozen_at DATETIME(6);
+
+CREATE TABLE audit_logs
+(
+    id           CHAR(36)    NOT NULL PRIMARY KEY,
+    actor        VARCHAR(64) NOT NULL,
+    user_id      CHAR(36),
+    action       VARCHAR(64) NOT NULL,
+    reason       TEXT,
+    details      TEXT,
+    created_date DATETIME(6),
+    INDEX idx_audit_logs_user_id (user_id),
+    CONSTRAINT fk_audit_logs_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
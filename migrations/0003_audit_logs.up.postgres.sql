@@ -0,0 +1,16 @@
+-- Audit trail of account changes, and administrative account freezes.
+
+ALTER TABLE users ADD COLUMN frozen_at timestamp with time zone;
+
+CREATE TABLE audit_logs
+(
+    id           uuid        NOT NULL PRIMARY KEY,
+    actor        varchar(64) NOT NULL,
+    user_id      uuid CONSTRAINT fk_audit_logs_user REFERENCES users,
+    action       varchar(64) NOT NULL,
+    reason       text,
+    details      text,
+    created_date timestamp with time zone
+);
+
+CREATE INDEX idx_audit_logs_user_id ON audit_logs (user_id);
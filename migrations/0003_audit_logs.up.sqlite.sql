@@ -0,0 +1,16 @@
+-- Audit trail of account changes, and administrative account freezes.
+
+ALTER TABLE users ADD COLUMN frozen_at DATETIME;
+
+CREATE TABLE audit_logs
+(
+    id           TEXT        NOT NULL PRIMARY KEY,
+    actor        VARCHAR(64) NOT NULL,
+    user_id      TEXT CONSTRAINT fk_audit_logs_user REFERENCES users,
+    action       VARCHAR(64) NOT NULL,
+    reason       TEXT,
+    details      TEXT,
+    created_date DATETIME
+);
+
+CREATE INDEX idx_audit_logs_user_id ON audit_logs (user_id);
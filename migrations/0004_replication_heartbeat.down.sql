@@ -0,0 +1 @@
+DROP TABLE replication_heartbeat;
@@ -0,0 +1,10 @@
+-- A single row the primary updates every few seconds. Read back from a
+-- replica, it tells how far that replica lags behind.
+
+CREATE TABLE replication_heartbeat
+(
+    id      INT         NOT NULL PRIMARY KEY,
+    beat_at DATETIME(6) NOT NULL
+);
+
+INSERT INTO replication_heartbeat (id, beat_at) VALUES (1, UTC_TIMESTAMP(6));
@@ -0,0 +1,9 @@
+DROP TABLE fx_quotes;
+DROP TABLE balances;
+ALTER TABLE transfers DROP COLUMN quote_id;
+ALTER TABLE transfers DROP COLUMN rate;
+ALTER TABLE transfers DROP COLUMN to_amount;
+ALTER TABLE transfers DROP COLUMN to_currency;
+ALTER TABLE transfers DROP COLUMN currency;
+ALTER TABLE payments DROP COLUMN currency;
+ALTER TABLE top_ups DROP COLUMN currency;
@@ -0,0 +1,41 @@
+-- Currencies besides the rupiah. users.balance stays the rupiah balance and
+-- balances holds every other currency a user has. Records made so far were
+-- all in rupiah; transfers also record what the recipient was credited, in
+-- which currency and at which rate. Rates are DOUBLE, as they need far more
+-- than the two decimal places of amounts.
+
+ALTER TABLE top_ups ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE payments ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE transfers ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE transfers ADD COLUMN to_currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE transfers ADD COLUMN to_amount DECIMAL(20, 2) NOT NULL DEFAULT 0;
+ALTER TABLE transfers ADD COLUMN rate DOUBLE NOT NULL DEFAULT 1;
+ALTER TABLE transfers ADD COLUMN quote_id CHAR(36);
+UPDATE transfers SET to_amount = amount;
+
+CREATE TABLE balances
+(
+    user_id      CHAR(36)       NOT NULL,
+    currency     VARCHAR(3)     NOT NULL,
+    amount       DECIMAL(20, 2) NOT NULL DEFAULT 0,
+    updated_date DATETIME(6),
+    PRIMARY KEY (user_id, currency),
+    CONSTRAINT fk_balances_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE fx_quotes
+(
+    id            CHAR(36)    NOT NULL PRIMARY KEY,
+    user_id       CHAR(36),
+    from_currency VARCHAR(3)  NOT NULL,
+    to_currency   VARCHAR(3)  NOT NULL,
+    mid_rate      DOUBLE      NOT NULL,
+    spread        DOUBLE      NOT NULL,
+    rate          DOUBLE      NOT NULL,
+    created_date  DATETIME(6),
+    expires_at    DATETIME(6) NOT NULL,
+    used_at       DATETIME(6),
+    INDEX idx_fx_quotes_user_id (user_id),
+    CONSTRAINT fk_fx_quotes_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
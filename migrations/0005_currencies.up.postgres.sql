@@ -0,0 +1,39 @@
+-- Currencies besides the rupiah. users.balance stays the rupiah balance and
+-- balances holds every other currency a user has. Records made so far were
+-- all in rupiah; transfers also record what the recipient was credited, in
+-- which currency and at which rate.
+
+ALTER TABLE top_ups ADD COLUMN currency varchar(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE payments ADD COLUMN currency varchar(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE transfers ADD COLUMN currency varchar(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE transfers ADD COLUMN to_currency varchar(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE transfers ADD COLUMN to_amount numeric NOT NULL DEFAULT 0;
+ALTER TABLE transfers ADD COLUMN rate numeric NOT NULL DEFAULT 1;
+ALTER TABLE transfers ADD COLUMN quote_id uuid;
+UPDATE transfers SET to_amount = amount;
+
+CREATE TABLE balances
+(
+    user_id      uuid       NOT NULL CONSTRAINT fk_balances_user REFERENCES users,
+    currency     varchar(3) NOT NULL,
+    amount       numeric    NOT NULL DEFAULT 0,
+    updated_date timestamp with time zone,
+    PRIMARY KEY (user_id, currency)
+);
+
+CREATE TABLE fx_quotes
+(
+    id            uuid                     NOT NULL PRIMARY KEY,
+    user_id       uuid CONSTRAINT fk_fx_quotes_user REFERENCES users,
+    from_currency varchar(3)               NOT NULL,
+    to_currency   varchar(3)               NOT NULL,
+    mid_rate      numeric                  NOT NULL,
+    spread        numeric                  NOT NULL,
+    rate          numeric                  NOT NULL,
+    created_date  timestamp with time zone,
+    expires_at    timestamp with time zone NOT NULL,
+    used_at       timestamp with time zone
+);
+
+CREATE INDEX idx_fx_quotes_user_id ON fx_quotes (user_id);
@@ -0,0 +1,39 @@
+-- Currencies besides the rupiah. users.balance stays the rupiah balance and
+-- balances holds every other currency a user has. Records made so far were
+-- all in rupiah; transfers also record what the recipient was credited, in
+-- which currency and at which rate.
+
+ALTER TABLE top_ups ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE payments ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE transfers ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE transfers ADD COLUMN to_currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE transfers ADD COLUMN to_amount REAL NOT NULL DEFAULT 0;
+ALTER TABLE transfers ADD COLUMN rate REAL NOT NULL DEFAULT 1;
+ALTER TABLE transfers ADD COLUMN quote_id TEXT;
+UPDATE transfers SET to_amount = amount;
+
+CREATE TABLE balances
+(
+    user_id      TEXT       NOT NULL CONSTRAINT fk_balances_user REFERENCES users,
+    currency     VARCHAR(3) NOT NULL,
+    amount       REAL       NOT NULL DEFAULT 0,
+    updated_date DATETIME,
+    PRIMARY KEY (user_id, currency)
+);
+
+CREATE TABLE fx_quotes
+(
+    id            TEXT       NOT NULL PRIMARY KEY,
+    user_id       TEXT CONSTRAINT fk_fx_quotes_user REFERENCES users,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency   VARCHAR(3) NOT NULL,
+    mid_rate      REAL       NOT NULL,
+    spread        REAL       NOT NULL,
+    rate          REAL       NOT NULL,
+    created_date  DATETIME,
+    expires_at    DATETIME   NOT NULL,
+    used_at       DATETIME
+);
+
+CREATE INDEX idx_fx_quotes_user_id ON fx_quotes (user_id);
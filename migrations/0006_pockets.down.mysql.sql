@@ -0,0 +1,6 @@
+ALTER TABLE payments DROP FOREIGN KEY fk_payments_pocket;
+ALTER TABLE payments DROP COLUMN pocket_id;
+ALTER TABLE top_ups DROP FOREIGN KEY fk_top_ups_pocket;
+ALTER TABLE top_ups DROP COLUMN pocket_id;
+DROP TABLE pocket_movements;
+DROP TABLE pockets;
@@ -0,0 +1,4 @@
+ALTER TABLE payments DROP COLUMN pocket_id;
+ALTER TABLE top_ups DROP COLUMN pocket_id;
+DROP TABLE pocket_movements;
+DROP TABLE pockets;
@@ -0,0 +1,39 @@
+-- Pockets: named sub-balances next to the main balance, and the movements
+-- of money between them. Top-ups and payments record the pocket they
+-- credited or debited, which is NULL for the main balance.
+
+CREATE TABLE pockets
+(
+    id           CHAR(36)       NOT NULL PRIMARY KEY,
+    user_id      CHAR(36),
+    name         VARCHAR(50)    NOT NULL,
+    currency     VARCHAR(3)     NOT NULL DEFAULT 'IDR',
+    balance      DECIMAL(20, 2) NOT NULL DEFAULT 0,
+    created_date DATETIME(6),
+    updated_date DATETIME(6),
+    closed_at    DATETIME(6),
+    INDEX idx_pockets_user_id (user_id),
+    CONSTRAINT fk_pockets_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE pocket_movements
+(
+    id             CHAR(36)   NOT NULL PRIMARY KEY,
+    user_id        CHAR(36),
+    from_pocket_id CHAR(36),
+    to_pocket_id   CHAR(36),
+    amount         DECIMAL(20, 2),
+    currency       VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    balance_before DECIMAL(20, 2),
+    balance_after  DECIMAL(20, 2),
+    created_date   DATETIME(6),
+    INDEX idx_pocket_movements_user_id (user_id),
+    CONSTRAINT fk_pocket_movements_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_pocket_movements_from FOREIGN KEY (from_pocket_id) REFERENCES pockets (id),
+    CONSTRAINT fk_pocket_movements_to FOREIGN KEY (to_pocket_id) REFERENCES pockets (id)
+);
+
+ALTER TABLE top_ups ADD COLUMN pocket_id CHAR(36);
+ALTER TABLE top_ups ADD CONSTRAINT fk_top_ups_pocket FOREIGN KEY (pocket_id) REFERENCES pockets (id);
+ALTER TABLE payments ADD COLUMN pocket_id CHAR(36);
+ALTER TABLE payments ADD CONSTRAINT fk_payments_pocket FOREIGN KEY (pocket_id) REFERENCES pockets (id);
@@ -0,0 +1,35 @@
+-- Pockets: named sub-balances next to the main balance, and the movements
+-- of money between them. Top-ups and payments record the pocket they
+-- credited or debited, which is NULL for the main balance.
+
+CREATE TABLE pockets
+(
+    id           uuid        NOT NULL PRIMARY KEY,
+    user_id      uuid CONSTRAINT fk_pockets_user REFERENCES users,
+    name         varchar(50) NOT NULL,
+    currency     varchar(3)  NOT NULL DEFAULT 'IDR',
+    balance      numeric     NOT NULL DEFAULT 0,
+    created_date timestamp with time zone,
+    updated_date timestamp with time zone,
+    closed_at    timestamp with time zone
+);
+
+CREATE INDEX idx_pockets_user_id ON pockets (user_id);
+
+CREATE TABLE pocket_movements
+(
+    id             uuid       NOT NULL PRIMARY KEY,
+    user_id        uuid CONSTRAINT fk_pocket_movements_user REFERENCES users,
+    from_pocket_id uuid CONSTRAINT fk_pocket_movements_from REFERENCES pockets,
+    to_pocket_id   uuid CONSTRAINT fk_pocket_movements_to REFERENCES pockets,
+    amount         numeric,
+    currency       varchar(3) NOT NULL DEFAULT 'IDR',
+    balance_before numeric,
+    balance_after  numeric,
+    created_date   timestamp with time zone
+);
+
+CREATE INDEX idx_pocket_movements_user_id ON pocket_movements (user_id);
+
+ALTER TABLE top_ups ADD COLUMN pocket_id uuid CONSTRAINT fk_top_ups_pocket REFERENCES pockets;
+ALTER TABLE payments ADD COLUMN pocket_id uuid CONSTRAINT fk_payments_pocket REFERENCES pockets;
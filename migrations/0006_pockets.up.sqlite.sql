@@ -0,0 +1,36 @@
+-- Pockets: named sub-balances next to the main balance, and the movements
+-- of money between them. Top-ups and payments record the pocket they
+-- credited or debited, which is NULL for the main balance. SQLite cannot drop
+-- a column with a foreign key, so those two have none here.
+
+CREATE TABLE pockets
+(
+    id           TEXT        NOT NULL PRIMARY KEY,
+    user_id      TEXT CONSTRAINT fk_pockets_user REFERENCES users,
+    name         VARCHAR(50) NOT NULL,
+    currency     VARCHAR(3)  NOT NULL DEFAULT 'IDR',
+    balance      REAL        NOT NULL DEFAULT 0,
+    created_date DATETIME,
+    updated_date DATETIME,
+    closed_at    DATETIME
+);
+
+CREATE INDEX idx_pockets_user_id ON pockets (user_id);
+
+CREATE TABLE pocket_movements
+(
+    id             TEXT       NOT NULL PRIMARY KEY,
+    user_id        TEXT CONSTRAINT fk_pocket_movements_user REFERENCES users,
+    from_pocket_id TEXT CONSTRAINT fk_pocket_movements_from REFERENCES pockets,
+    to_pocket_id   TEXT CONSTRAINT fk_pocket_movements_to REFERENCES pockets,
+    amount         REAL,
+    currency       VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    balance_before REAL,
+    balance_after  REAL,
+    created_date   DATETIME
+);
+
+CREATE INDEX idx_pocket_movements_user_id ON pocket_movements (user_id);
+
+ALTER TABLE top_ups ADD COLUMN pocket_id TEXT;
+ALTER TABLE payments ADD COLUMN pocket_id TEXT;
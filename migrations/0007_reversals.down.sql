@@ -0,0 +1,5 @@
+DROP TABLE reversals;
+ALTER TABLE transfers DROP COLUMN reversed_at;
+ALTER TABLE top_ups DROP COLUMN reversed_at;
+ALTER TABLE payments DROP COLUMN refunded_amount;
+ALTER TABLE payments DROP COLUMN merchant;
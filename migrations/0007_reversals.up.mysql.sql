@@ -0,0 +1,35 @@
+-- Refunds of payments and reversals of top-ups and transfers. Payments keep
+-- the sum refunded so far, which a guarded update keeps within the amount
+-- paid, and name the merchant paid. Top-ups and transfers can be reversed
+-- once.
+
+ALTER TABLE payments ADD COLUMN merchant VARCHAR(50);
+ALTER TABLE payments ADD COLUMN refunded_amount DECIMAL(20, 2) NOT NULL DEFAULT 0;
+ALTER TABLE top_ups ADD COLUMN reversed_at DATETIME(6);
+ALTER TABLE transfers ADD COLUMN reversed_at DATETIME(6);
+
+CREATE TABLE reversals
+(
+    id               CHAR(36)       NOT NULL PRIMARY KEY,
+    user_id          CHAR(36),
+    payment_id       CHAR(36),
+    top_up_id        CHAR(36),
+    transfer_id      CHAR(36),
+    transaction_type VARCHAR(8)     NOT NULL,
+    amount           DECIMAL(20, 2),
+    currency         VARCHAR(3)     NOT NULL DEFAULT 'IDR',
+    pocket_id        CHAR(36),
+    reason           TEXT,
+    actor            VARCHAR(64)    NOT NULL,
+    status           VARCHAR(16)    NOT NULL DEFAULT 'SUCCESS',
+    balance_before   DECIMAL(20, 2),
+    balance_after    DECIMAL(20, 2),
+    created_date     DATETIME(6),
+    INDEX idx_reversals_user_id (user_id),
+    INDEX idx_reversals_payment_id (payment_id),
+    CONSTRAINT fk_reversals_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_reversals_payment FOREIGN KEY (payment_id) REFERENCES payments (id),
+    CONSTRAINT fk_reversals_top_up FOREIGN KEY (top_up_id) REFERENCES top_ups (id),
+    CONSTRAINT fk_reversals_transfer FOREIGN KEY (transfer_id) REFERENCES transfers (id),
+    CONSTRAINT fk_reversals_pocket FOREIGN KEY (pocket_id) REFERENCES pockets (id)
+);
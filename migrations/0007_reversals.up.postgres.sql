@@ -0,0 +1,31 @@
+-- Refunds of payments and reversals of top-ups and transfers. Payments keep
+-- the sum refunded so far, which a guarded update keeps within the amount
+-- paid, and name the merchant paid. Top-ups and transfers can be reversed
+-- once.
+
+ALTER TABLE payments ADD COLUMN merchant varchar(50);
+ALTER TABLE payments ADD COLUMN refunded_amount numeric NOT NULL DEFAULT 0;
+ALTER TABLE top_ups ADD COLUMN reversed_at timestamp with time zone;
+ALTER TABLE transfers ADD COLUMN reversed_at timestamp with time zone;
+
+CREATE TABLE reversals
+(
+    id               uuid        NOT NULL PRIMARY KEY,
+    user_id          uuid CONSTRAINT fk_reversals_user REFERENCES users,
+    payment_id       uuid CONSTRAINT fk_reversals_payment REFERENCES payments,
+    top_up_id        uuid CONSTRAINT fk_reversals_top_up REFERENCES top_ups,
+    transfer_id      uuid CONSTRAINT fk_reversals_transfer REFERENCES transfers,
+    transaction_type varchar(8)  NOT NULL,
+    amount           numeric,
+    currency         varchar(3)  NOT NULL DEFAULT 'IDR',
+    pocket_id        uuid CONSTRAINT fk_reversals_pocket REFERENCES pockets,
+    reason           text,
+    actor            varchar(64) NOT NULL,
+    status           varchar(16) NOT NULL DEFAULT 'SUCCESS',
+    balance_before   numeric,
+    balance_after    numeric,
+    created_date     timestamp with time zone
+);
+
+CREATE INDEX idx_reversals_user_id ON reversals (user_id);
+CREATE INDEX idx_reversals_payment_id ON reversals (payment_id);
@@ -0,0 +1,31 @@
+-- Refunds of payments and reversals of top-ups and transfers. Payments keep
+-- the sum refunded so far, which a guarded update keeps within the amount
+-- paid, and name the merchant paid. Top-ups and transfers can be reversed
+-- once.
+
+ALTER TABLE payments ADD COLUMN merchant VARCHAR(50);
+ALTER TABLE payments ADD COLUMN refunded_amount REAL NOT NULL DEFAULT 0;
+ALTER TABLE top_ups ADD COLUMN reversed_at DATETIME;
+ALTER TABLE transfers ADD COLUMN reversed_at DATETIME;
+
+CREATE TABLE reversals
+(
+    id               TEXT        NOT NULL PRIMARY KEY,
+    user_id          TEXT CONSTRAINT fk_reversals_user REFERENCES users,
+    payment_id       TEXT CONSTRAINT fk_reversals_payment REFERENCES payments,
+    top_up_id        TEXT CONSTRAINT fk_reversals_top_up REFERENCES top_ups,
+    transfer_id      TEXT CONSTRAINT fk_reversals_transfer REFERENCES transfers,
+    transaction_type VARCHAR(8)  NOT NULL,
+    amount           REAL,
+    currency         VARCHAR(3)  NOT NULL DEFAULT 'IDR',
+    pocket_id        TEXT CONSTRAINT fk_reversals_pocket REFERENCES pockets,
+    reason           TEXT,
+    actor            VARCHAR(64) NOT NULL,
+    status           VARCHAR(16) NOT NULL DEFAULT 'SUCCESS',
+    balance_before   REAL,
+    balance_after    REAL,
+    created_date     DATETIME
+);
+
+CREATE INDEX idx_reversals_user_id ON reversals (user_id);
+CREATE INDEX idx_reversals_payment_id ON reversals (payment_id);
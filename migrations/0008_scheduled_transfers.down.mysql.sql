@@ -0,0 +1,5 @@
+ALTER TABLE transfers DROP FOREIGN KEY fk_transfers_schedule;
+DROP INDEX idx_transfers_schedule ON transfers;
+ALTER TABLE transfers DROP COLUMN scheduled_for;
+ALTER TABLE transfers DROP COLUMN schedule_id;
+DROP TABLE scheduled_transfers;
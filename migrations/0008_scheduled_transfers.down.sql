@@ -0,0 +1,4 @@
+DROP INDEX idx_transfers_schedule;
+ALTER TABLE transfers DROP COLUMN scheduled_for;
+ALTER TABLE transfers DROP COLUMN schedule_id;
+DROP TABLE scheduled_transfers;
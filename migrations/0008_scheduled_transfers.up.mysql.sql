@@ -0,0 +1,36 @@
+-- Scheduled transfers: one-off or recurring transfers made by the
+-- scheduler. A transfer names the schedule and occurrence that made it, and
+-- the unique index on both makes every occurrence run at most once, however
+-- many instances race for it.
+
+CREATE TABLE scheduled_transfers
+(
+    id           CHAR(36)       NOT NULL PRIMARY KEY,
+    user_id      CHAR(36),
+    to_user_id   CHAR(36),
+    amount       DECIMAL(20, 2),
+    currency     VARCHAR(3)     NOT NULL DEFAULT 'IDR',
+    to_currency  VARCHAR(3)     NOT NULL DEFAULT 'IDR',
+    remarks      TEXT,
+    frequency    VARCHAR(8)     NOT NULL,
+    cron         VARCHAR(100),
+    start_at     DATETIME(6),
+    next_run_at  DATETIME(6),
+    attempt_at   DATETIME(6),
+    attempts     INT            NOT NULL DEFAULT 0,
+    last_error   VARCHAR(64),
+    last_run_at  DATETIME(6),
+    status       VARCHAR(16)    NOT NULL DEFAULT 'ACTIVE',
+    version      INT            NOT NULL DEFAULT 1,
+    created_date DATETIME(6),
+    updated_date DATETIME(6),
+    INDEX idx_scheduled_transfers_user_id (user_id),
+    INDEX idx_scheduled_transfers_attempt_at (attempt_at),
+    CONSTRAINT fk_scheduled_transfers_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_scheduled_transfers_to_user FOREIGN KEY (to_user_id) REFERENCES users (id)
+);
+
+ALTER TABLE transfers ADD COLUMN schedule_id CHAR(36);
+ALTER TABLE transfers ADD COLUMN scheduled_for DATETIME(6);
+CREATE UNIQUE INDEX idx_transfers_schedule ON transfers (schedule_id, scheduled_for);
+ALTER TABLE transfers ADD CONSTRAINT fk_transfers_schedule FOREIGN KEY (schedule_id) REFERENCES scheduled_transfers (id);
@@ -0,0 +1,34 @@
+-- Scheduled transfers: one-off or recurring transfers made by the
+-- scheduler. A transfer names the schedule and occurrence that made it, and
+-- the unique index on both makes every occurrence run at most once, however
+-- many instances race for it.
+
+CREATE TABLE scheduled_transfers
+(
+    id           uuid         NOT NULL PRIMARY KEY,
+    user_id      uuid CONSTRAINT fk_scheduled_transfers_user REFERENCES users,
+    to_user_id   uuid CONSTRAINT fk_scheduled_transfers_to_user REFERENCES users,
+    amount       numeric,
+    currency     varchar(3)   NOT NULL DEFAULT 'IDR',
+    to_currency  varchar(3)   NOT NULL DEFAULT 'IDR',
+    remarks      text,
+    frequency    varchar(8)   NOT NULL,
+    cron         varchar(100),
+    start_at     timestamp with time zone,
+    next_run_at  timestamp with time zone,
+    attempt_at   timestamp with time zone,
+    attempts     integer      NOT NULL DEFAULT 0,
+    last_error   varchar(64),
+    last_run_at  timestamp with time zone,
+    status       varchar(16)  NOT NULL DEFAULT 'ACTIVE',
+    version      integer      NOT NULL DEFAULT 1,
+    created_date timestamp with time zone,
+    updated_date timestamp with time zone
+);
+
+CREATE INDEX idx_scheduled_transfers_user_id ON scheduled_transfers (user_id);
+CREATE INDEX idx_scheduled_transfers_attempt_at ON scheduled_transfers (attempt_at);
+
+ALTER TABLE transfers ADD COLUMN schedule_id uuid CONSTRAINT fk_transfers_schedule REFERENCES scheduled_transfers;
+ALTER TABLE transfers ADD COLUMN scheduled_for timestamp with time zone;
+CREATE UNIQUE INDEX idx_transfers_schedule ON transfers (schedule_id, scheduled_for);
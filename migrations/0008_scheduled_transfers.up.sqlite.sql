@@ -0,0 +1,35 @@
+-- Scheduled transfers: one-off or recurring transfers made by the
+-- scheduler. A transfer names the schedule and occurrence that made it, and
+-- the unique index on both makes every occurrence run at most once, however
+-- many instances race for it.
+
+CREATE TABLE scheduled_transfers
+(
+    id           TEXT         NOT NULL PRIMARY KEY,
+    user_id      TEXT CONSTRAINT fk_scheduled_transfers_user REFERENCES users,
+    to_user_id   TEXT CONSTRAINT fk_scheduled_transfers_to_user REFERENCES users,
+    amount       REAL,
+    currency     VARCHAR(3)   NOT NULL DEFAULT 'IDR',
+    to_currency  VARCHAR(3)   NOT NULL DEFAULT 'IDR',
+    remarks      TEXT,
+    frequency    VARCHAR(8)   NOT NULL,
+    cron         VARCHAR(100),
+    start_at     DATETIME,
+    next_run_at  DATETIME,
+    attempt_at   DATETIME,
+    attempts     INTEGER      NOT NULL DEFAULT 0,
+    last_error   VARCHAR(64),
+    last_run_at  DATETIME,
+    status       VARCHAR(16)  NOT NULL DEFAULT 'ACTIVE',
+    version      INTEGER      NOT NULL DEFAULT 1,
+    created_date DATETIME,
+    updated_date DATETIME
+);
+
+CREATE INDEX idx_scheduled_transfers_user_id ON scheduled_transfers (user_id);
+CREATE INDEX idx_scheduled_transfers_attempt_at ON scheduled_transfers (attempt_at);
+
+-- SQLite cannot drop a column with a foreign key, so schedule_id has none.
+ALTER TABLE transfers ADD COLUMN schedule_id TEXT;
+ALTER TABLE transfers ADD COLUMN scheduled_for DATETIME;
+CREATE UNIQUE INDEX idx_transfers_schedule ON transfers (schedule_id, scheduled_for);
@@ -0,0 +1 @@
+DROP TABLE money_requests;
@@ -0,0 +1,23 @@
+-- Money requests: an amount one user asks another to pay them. Accepting
+-- one makes a transfer, which the request names. Pending requests past
+-- expires_at have expired.
+
+CREATE TABLE money_requests
+(
+    id           CHAR(36)       NOT NULL PRIMARY KEY,
+    requester_id CHAR(36),
+    payer_id     CHAR(36),
+    amount       DECIMAL(20, 2),
+    currency     VARCHAR(3)     NOT NULL DEFAULT 'IDR',
+    remarks      TEXT,
+    status       VARCHAR(16)    NOT NULL DEFAULT 'PENDING',
+    transfer_id  CHAR(36),
+    expires_at   DATETIME(6),
+    responded_at DATETIME(6),
+    created_date DATETIME(6),
+    INDEX idx_money_requests_requester_id (requester_id),
+    INDEX idx_money_requests_payer_id (payer_id),
+    CONSTRAINT fk_money_requests_requester FOREIGN KEY (requester_id) REFERENCES users (id),
+    CONSTRAINT fk_money_requests_payer FOREIGN KEY (payer_id) REFERENCES users (id),
+    CONSTRAINT fk_money_requests_transfer FOREIGN KEY (transfer_id) REFERENCES transfers (id)
+);
@@ -0,0 +1,21 @@
+-- Money requests: an amount one user asks another to pay them. Accepting
+-- one makes a transfer, which the request names. Pending requests past
+-- expires_at have expired.
+
+CREATE TABLE money_requests
+(
+    id           uuid        NOT NULL PRIMARY KEY,
+    requester_id uuid CONSTRAINT fk_money_requests_requester REFERENCES users,
+    payer_id     uuid CONSTRAINT fk_money_requests_payer REFERENCES users,
+    amount       numeric,
+    currency     varchar(3)  NOT NULL DEFAULT 'IDR',
+    remarks      text,
+    status       varchar(16) NOT NULL DEFAULT 'PENDING',
+    transfer_id  uuid CONSTRAINT fk_money_requests_transfer REFERENCES transfers,
+    expires_at   timestamp with time zone,
+    responded_at timestamp with time zone,
+    created_date timestamp with time zone
+);
+
+CREATE INDEX idx_money_requests_requester_id ON money_requests (requester_id);
+CREATE INDEX idx_money_requests_payer_id ON money_requests (payer_id);
@@ -0,0 +1,16 @@
+// Package migrations embeds the versioned SQL migrations of the schema.
+//
+// Files are named NNNN_description.up.<dialect>.sql and
+// NNNN_description.down.<dialect>.sql, with one pair per supported database
+// (postgres, mysql and sqlite) for every version; a script without a dialect
+// serves all of them. Every database must end up with the same tables and
+// columns.
+// Applied migrations must never be edited: the runner records a checksum of
+// every up script and refuses to continue when one has changed. Fix
+// mistakes with a new migration instead.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditLog records a change to an account, made through the API or by an
+// administrator.
+type AuditLog struct {
+	ID uuid.UUID `gorm:"primaryKey" json:"audit_log_id"`
+	// Actor is "user:<id>" for requests made by the account holder,
+	// "admin:<name>" for the admin CLI and "seed" for generated demo data.
+	Actor  string    `gorm:"size:64;not null" json:"actor"`
+	UserID uuid.UUID `gorm:"index" json:"user_id"`
+	Action string    `gorm:"size:64;not null" json:"action"`
+	Reason string    `json:"reason,omitempty"`
+	// Details is a JSON object describing the change. It never holds PINs
+	// or other secrets.
+	Details     string    `json:"details,omitempty"`
+	CreatedDate time.Time `json:"created_date"`
+}
+
+func (log *AuditLog) BeforeCreate(tx *gorm.DB) (err error) {
+	log.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultCurrency is the currency of User.Balance, and of every record made
+// before wallets held other currencies.
+const DefaultCurrency = "IDR"
+
+// Balance is what a user holds in a currency other than DefaultCurrency.
+type Balance struct {
+	UserID      uuid.UUID `gorm:"primaryKey" json:"user_id"`
+	Currency    string    `gorm:"primaryKey;size:3" json:"currency"`
+	Amount      float64   `gorm:"not null;default:0" json:"amount"`
+	UpdatedDate time.Time `json:"updated_date"`
+}
+
+// Quote is an exchange rate offered to a user, which they may use for one
+// transfer until it expires. Rate is MidRate less the Spread.
+type Quote struct {
+	ID           uuid.UUID  `gorm:"primaryKey" json:"quote_id"`
+	UserID       uuid.UUID  `gorm:"index" json:"user_id"`
+	FromCurrency string     `gorm:"size:3;not null" json:"from_currency"`
+	ToCurrency   string     `gorm:"size:3;not null" json:"to_currency"`
+	MidRate      float64    `gorm:"not null" json:"mid_rate"`
+	Spread       float64    `gorm:"not null" json:"spread"`
+	Rate         float64    `gorm:"not null" json:"rate"`
+	CreatedDate  time.Time  `json:"created_date"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at"`
+}
+
+func (Quote) TableName() string {
+	return "fx_quotes"
+}
+
+func (quote *Quote) BeforeCreate(tx *gorm.DB) (err error) {
+	quote.ID = uuid.New()
+	return nil
+}
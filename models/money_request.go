@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MoneyRequest is an amount one user asked another to pay them. The payer
+// accepts it, which makes the transfer named by TransferID, or declines it;
+// the requester may cancel it until then. Requests left pending expire at
+// ExpiresAt.
+type MoneyRequest struct {
+	ID          uuid.UUID  `gorm:"primaryKey" json:"request_id"`
+	RequesterID uuid.UUID  `gorm:"index" json:"requester_id"`
+	PayerID     uuid.UUID  `gorm:"index" json:"payer_id"`
+	Amount      float64    `json:"amount"`
+	Currency    string     `gorm:"size:3;not null;default:IDR" json:"currency"`
+	Remarks     string     `json:"remarks"`
+	Status      string     `gorm:"size:16;not null;default:PENDING" json:"status"`
+	TransferID  *uuid.UUID `json:"transfer_id,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	CreatedDate time.Time  `json:"created_date"`
+}
+
+// Money request statuses. Expired requests are stored as pending; StatusAt
+// tells them apart.
+const (
+	RequestPending   = "PENDING"
+	RequestAccepted  = "ACCEPTED"
+	RequestDeclined  = "DECLINED"
+	RequestCancelled = "CANCELLED"
+	RequestExpired   = "EXPIRED"
+)
+
+func (request *MoneyRequest) BeforeCreate(tx *gorm.DB) (err error) {
+	request.ID = uuid.New()
+	return nil
+}
+
+// StatusAt returns the status of the request at now, which is
+// RequestExpired for a pending request past its expiry.
+func (request MoneyRequest) StatusAt(now time.Time) string {
+	if request.Status == RequestPending && !now.Before(request.ExpiresAt) {
+		return RequestExpired
+	}
+	return request.Status
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Pocket is a named sub-balance a user keeps apart from their main balance,
+// e.g. for savings. Every pocket holds one currency. The main balance, in
+// User.Balance or a Balance record, is the default pocket that money goes
+// to and comes from when no pocket is chosen.
+type Pocket struct {
+	ID          uuid.UUID `gorm:"primaryKey" json:"pocket_id"`
+	UserID      uuid.UUID `gorm:"index" json:"user_id"`
+	Name        string    `gorm:"size:50;not null" json:"name"`
+	Currency    string    `gorm:"size:3;not null;default:IDR" json:"currency"`
+	Balance     float64   `gorm:"not null;default:0" json:"balance"`
+	CreatedDate time.Time `json:"created_date"`
+	UpdatedDate time.Time `json:"updated_date"`
+	// ClosedAt is set once the pocket is closed; closed pockets keep their
+	// history but hold no money.
+	ClosedAt *time.Time `json:"-"`
+}
+
+func (pocket *Pocket) BeforeCreate(tx *gorm.DB) (err error) {
+	pocket.ID = uuid.New()
+	return nil
+}
+
+// Closed reports whether the pocket has been closed.
+func (pocket Pocket) Closed() bool {
+	return pocket.ClosedAt != nil
+}
+
+// PocketMovement is money a user moved between two of their own pockets. A
+// nil pocket ID is the main balance. The balances are those of the pocket
+// the money came from.
+type PocketMovement struct {
+	ID            uuid.UUID  `gorm:"primaryKey" json:"movement_id"`
+	UserID        uuid.UUID  `gorm:"index" json:"user_id"`
+	FromPocketID  *uuid.UUID `json:"from_pocket_id"`
+	ToPocketID    *uuid.UUID `json:"to_pocket_id"`
+	Amount        float64    `json:"amount"`
+	Currency      string     `gorm:"size:3;not null;default:IDR" json:"currency"`
+	BalanceBefore float64    `json:"balance_before"`
+	BalanceAfter  float64    `json:"balance_after"`
+	CreatedDate   time.Time  `json:"created_date"`
+}
+
+func (movement *PocketMovement) BeforeCreate(tx *gorm.DB) (err error) {
+	movement.ID = uuid.New()
+	return nil
+}
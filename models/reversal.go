@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Reversal gives back, in full or in part, the money of an earlier record:
+// a payment refunded by its merchant or an administrator, or a top-up or
+// transfer reversed by an administrator. Exactly one of PaymentID, TopUpID
+// and TransferID is set. A transfer reversal writes one Reversal for each
+// party, so that both see it in their history.
+type Reversal struct {
+	ID         uuid.UUID  `gorm:"primaryKey" json:"reversal_id"`
+	UserID     uuid.UUID  `gorm:"index" json:"user_id"`
+	PaymentID  *uuid.UUID `gorm:"index" json:"payment_id,omitempty"`
+	TopUpID    *uuid.UUID `json:"top_up_id,omitempty"`
+	TransferID *uuid.UUID `json:"transfer_id,omitempty"`
+	// TransactionType is CREDIT or DEBIT, from UserID's point of view.
+	TransactionType string     `gorm:"size:8;not null" json:"transaction_type"`
+	Amount          float64    `json:"amount"`
+	Currency        string     `gorm:"size:3;not null;default:IDR" json:"currency"`
+	PocketID        *uuid.UUID `json:"pocket_id,omitempty"`
+	Reason          string     `json:"reason"`
+	// Actor is who asked for it, e.g. "merchant:TOKO" or "admin:budi".
+	Actor         string    `gorm:"size:64;not null" json:"actor"`
+	Status        string    `gorm:"size:16;not null;default:SUCCESS" json:"status"`
+	BalanceBefore float64   `json:"balance_before"`
+	BalanceAfter  float64   `json:"balance_after"`
+	CreatedDate   time.Time `json:"created_date"`
+}
+
+// Reversal transaction types.
+const (
+	ReversalCredit = "CREDIT"
+	ReversalDebit  = "DEBIT"
+)
+
+// ReversalSucceeded is the status of a completed reversal.
+const ReversalSucceeded = "SUCCESS"
+
+func (reversal *Reversal) BeforeCreate(tx *gorm.DB) (err error) {
+	reversal.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ScheduledTransfer is a transfer a user set up to be made later, once or
+// again and again, e.g. for monthly rent. Frequency and Cron are those of a
+// schedule.Rule starting at StartAt.
+//
+// NextRunAt is the occurrence due next; every transfer it makes records it
+// in Transfer.ScheduledFor, which is unique per schedule. AttemptAt is when
+// the scheduler next tries that occurrence: NextRunAt itself, or later once
+// an attempt failed.
+type ScheduledTransfer struct {
+	ID         uuid.UUID `gorm:"primaryKey" json:"schedule_id"`
+	UserID     uuid.UUID `gorm:"index" json:"user_id"`
+	ToUserID   uuid.UUID `json:"to_user_id"`
+	Amount     float64   `json:"amount"`
+	Currency   string    `gorm:"size:3;not null;default:IDR" json:"currency"`
+	ToCurrency string    `gorm:"size:3;not null;default:IDR" json:"to_currency"`
+	Remarks    string    `json:"remarks"`
+	Frequency  string    `gorm:"size:8;not null" json:"frequency"`
+	Cron       string    `gorm:"size:100" json:"cron,omitempty"`
+	StartAt    time.Time `json:"start_at"`
+	// NextRunAt and AttemptAt are nil once no occurrence is left.
+	NextRunAt *time.Time `json:"next_run_at"`
+	AttemptAt *time.Time `gorm:"index" json:"-"`
+	// Attempts counts the failed attempts at NextRunAt, and LastError holds
+	// the error code of the last one.
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	LastError   string     `gorm:"size:64" json:"last_error,omitempty"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	Status      string     `gorm:"size:16;not null;default:ACTIVE" json:"status"`
+	Version     int        `gorm:"not null;default:1" json:"-"`
+	CreatedDate time.Time  `json:"created_date"`
+	UpdatedDate time.Time  `json:"updated_date"`
+}
+
+// Scheduled transfer statuses. Only active schedules are run.
+const (
+	ScheduleActive    = "ACTIVE"
+	SchedulePaused    = "PAUSED"
+	ScheduleCompleted = "COMPLETED"
+	ScheduleFailed    = "FAILED"
+	ScheduleCancelled = "CANCELLED"
+)
+
+func (schedule *ScheduledTransfer) BeforeCreate(tx *gorm.DB) (err error) {
+	schedule.ID = uuid.New()
+	return nil
+}
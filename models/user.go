@@ -1,6 +1,7 @@
 package models
 
 import (
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,32 +9,55 @@ import (
 )
 
 type User struct {
-	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	ID          uuid.UUID `gorm:"primaryKey" json:"user_id"`
 	FirstName   string    `json:"first_name"`
 	LastName    string    `json:"last_name"`
 	PhoneNumber string    `gorm:"unique" json:"phone_number"`
 	Address     string    `json:"address"`
-	PIN         string    `json:"pin"`
+	PIN         string    `json:"-"`
+	// Balance is in DefaultCurrency; other currencies are Balance records.
 	Balance     float64   `json:"balance"`
+	Language    string    `gorm:"size:8" json:"language"`      // Preferred language; empty means negotiate per request
+	Version     int64     `gorm:"not null;default:1" json:"-"` // Bumped on every profile change, exposed as the ETag
 	CreatedDate time.Time `json:"created_date"`
 	UpdatedDate time.Time `json:"update_date"`
+	// FrozenAt is set while an administrator has frozen the account.
+	FrozenAt *time.Time `json:"-"`
 
 	TopUps []TopUp `gorm:"foreignKey:UserID" json:"-"`
 }
 
 func (user *User) BeforeCreate(tx *gorm.DB) (err error) {
 	user.ID = uuid.New()
+	if user.Version == 0 {
+		user.Version = 1
+	}
 	return
 }
 
+// Frozen reports whether the account may not log in or move money out.
+func (user User) Frozen() bool {
+	return user.FrozenAt != nil
+}
+
+// LogValue keeps personal data out of logs when a whole user is logged.
+func (user User) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("user_id", user.ID.String()))
+}
+
 type TopUp struct {
-	ID            uuid.UUID `gorm:"type:uuid;primaryKey" json:"top_up_id"`
-	UserID        uuid.UUID `gorm:"type:uuid;index" json:"user_id"`
+	ID            uuid.UUID `gorm:"primaryKey" json:"top_up_id"`
+	UserID        uuid.UUID `gorm:"index" json:"user_id"`
 	User          User      `gorm:"foreignKey:UserID" json:"-"`
 	Amount        float64   `json:"amount"`
+	Currency      string    `gorm:"size:3;not null;default:IDR" json:"currency"`
 	BalanceBefore float64   `json:"balance_before"`
 	BalanceAfter  float64   `json:"balance_after"`
 	CreatedDate   time.Time `json:"created_date"`
+	// PocketID is the pocket credited, nil for the main balance.
+	PocketID *uuid.UUID `json:"pocket_id,omitempty"`
+	// ReversedAt is set once an administrator reversed the top-up.
+	ReversedAt *time.Time `json:"reversed_at,omitempty"`
 }
 
 func (topUp *TopUp) BeforeCreate(tx *gorm.DB) (err error) {
@@ -42,14 +66,22 @@ func (topUp *TopUp) BeforeCreate(tx *gorm.DB) (err error) {
 }
 
 type Payment struct {
-	ID            uuid.UUID `gorm:"type:uuid;primaryKey" json:"payment_id"`
-	UserID        uuid.UUID `gorm:"type:uuid;index" json:"-"`
+	ID            uuid.UUID `gorm:"primaryKey" json:"payment_id"`
+	UserID        uuid.UUID `gorm:"index" json:"-"`
 	User          User      `gorm:"foreignKey:UserID" json:"-"`
 	Amount        float64   `json:"amount"`
+	Currency      string    `gorm:"size:3;not null;default:IDR" json:"currency"`
 	Remarks       string    `json:"remarks"`
 	BalanceBefore float64   `json:"balance_before"`
 	BalanceAfter  float64   `json:"balance_after"`
 	CreatedDate   time.Time `json:"created_date"`
+	// PocketID is the pocket debited, nil for the main balance.
+	PocketID *uuid.UUID `json:"pocket_id,omitempty"`
+	// Merchant is the code of the merchant paid, if any, who may refund it.
+	Merchant string `gorm:"size:50" json:"merchant,omitempty"`
+	// RefundedAmount is the sum of the payment's refunds, which never
+	// exceeds Amount.
+	RefundedAmount float64 `gorm:"not null;default:0" json:"refunded_amount"`
 }
 
 func (payment *Payment) BeforeCreate(tx *gorm.DB) (err error) {
@@ -58,25 +90,47 @@ func (payment *Payment) BeforeCreate(tx *gorm.DB) (err error) {
 }
 
 type Transfer struct {
-	ID            uuid.UUID `gorm:"type:uuid;primaryKey" json:"transfer_id"`
-	FromUserID    uuid.UUID `gorm:"type:uuid;index" json:"-"`
-	FromUser      User      `gorm:"foreignKey:FromUserID" json:"-"`
-	ToUserID      uuid.UUID `gorm:"type:uuid;index" json:"-"`
-	ToUser        User      `gorm:"foreignKey:ToUserID" json:"-"`
-	Amount        float64   `json:"amount"`
-	Remarks       string    `json:"remarks"`
-	BalanceBefore float64   `json:"balance_before"`
-	BalanceAfter  float64   `json:"balance_after"`
-	CreatedDate   time.Time `json:"created_date"`
+	ID         uuid.UUID `gorm:"primaryKey" json:"transfer_id"`
+	FromUserID uuid.UUID `gorm:"index" json:"-"`
+	FromUser   User      `gorm:"foreignKey:FromUserID" json:"-"`
+	ToUserID   uuid.UUID `gorm:"index" json:"-"`
+	ToUser     User      `gorm:"foreignKey:ToUserID" json:"-"`
+	// Amount is debited from the sender in Currency, and ToAmount credited
+	// to the recipient in ToCurrency. Rate converts the one into the other
+	// and is 1 when both currencies are the same.
+	Amount     float64 `json:"amount"`
+	Currency   string  `gorm:"size:3;not null;default:IDR" json:"currency"`
+	ToAmount   float64 `gorm:"not null;default:0" json:"to_amount"`
+	ToCurrency string  `gorm:"size:3;not null;default:IDR" json:"to_currency"`
+	Rate       float64 `gorm:"not null;default:1" json:"rate"`
+	// QuoteID is the quote whose rate was used, if the sender had one.
+	QuoteID       *uuid.UUID `json:"quote_id,omitempty"`
+	Remarks       string     `json:"remarks"`
+	BalanceBefore float64    `json:"balance_before"`
+	BalanceAfter  float64    `json:"balance_after"`
+	Status        string     `gorm:"size:16;not null;default:SUCCESS" json:"status"`
+	CreatedDate   time.Time  `json:"created_date"`
+	// ReversedAt is set once an administrator reversed the transfer.
+	ReversedAt *time.Time `json:"reversed_at,omitempty"`
+	// ScheduleID and ScheduledFor name the scheduled transfer and the
+	// occurrence that made the transfer, which is made at most once.
+	ScheduleID   *uuid.UUID `gorm:"uniqueIndex:idx_transfers_schedule" json:"schedule_id,omitempty"`
+	ScheduledFor *time.Time `gorm:"uniqueIndex:idx_transfers_schedule" json:"scheduled_for,omitempty"`
 }
 
+// Transfer statuses.
+const (
+	TransferSucceeded = "SUCCESS"
+	TransferReversed  = "REVERSED"
+)
+
 func (transfer *Transfer) BeforeCreate(tx *gorm.DB) (err error) {
 	transfer.ID = uuid.New()
 	return nil
 }
 
 type Transaction struct {
-	UserID          uuid.UUID `gorm:"type:uuid;index" json:"-"`
+	UserID          uuid.UUID `gorm:"index" json:"-"`
 	User            User      `gorm:"foreignKey:UserID" json:"-"`
 	TransactionType string    `json:"transaction_type"` // CREDIT or DEBIT
 	Amount          float64   `json:"amount"`
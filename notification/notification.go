@@ -0,0 +1,47 @@
+// Package notification delivers user-facing messages about wallet activity.
+// Texts come from the i18n catalogues in the recipient's language.
+package notification
+
+import (
+	"context"
+	"log/slog"
+
+	"myapp/i18n"
+
+	"github.com/google/uuid"
+)
+
+// Notification is a message for a single user. Key is looked up under
+// "notification." in the message catalogues.
+type Notification struct {
+	UserID   uuid.UUID
+	Language i18n.Language
+	Key      string
+	Params   i18n.Params
+}
+
+// Text renders the notification in its recipient's language.
+func (n Notification) Text() string {
+	return i18n.T(n.Language, "notification."+n.Key, n.Params)
+}
+
+// Notifier sends notifications through some channel such as push or SMS.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier writes notifications to the application log. It is the
+// default until a real delivery channel is configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	slog.InfoContext(ctx, "notification", "user_id", n.UserID.String(), "key", n.Key, "text", n.Text())
+	return nil
+}
+
+// Discard drops every notification, e.g. while generating demo data.
+type Discard struct{}
+
+func (Discard) Notify(ctx context.Context, n Notification) error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"myapp/apperrors"
+	"myapp/middleware"
+	"myapp/render"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KeyFunc names the client a request is counted against.
+type KeyFunc func(c *gin.Context) string
+
+// ByIP identifies clients by IP address, for anonymous routes.
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByUser identifies clients by the user ID in their token, falling back to
+// the IP address for requests without a valid one.
+func ByUser(c *gin.Context) string {
+	if userID := middleware.GetUserID(c); userID != "" {
+		return "user:" + userID
+	}
+	return ByIP(c)
+}
+
+// Limiter applies the configured policies to routes.
+type Limiter struct {
+	store    Store
+	policies map[string]Policy
+}
+
+func New(store Store, policies map[string]Policy) *Limiter {
+	return &Limiter{store: store, policies: policies}
+}
+
+// Limit returns a middleware counting requests against the named policy,
+// with one bucket per route and client. A nil Limiter lets every request
+// through, which is how rate limiting is switched off.
+//
+// The RateLimit-* headers follow the IETF draft: the limit, the remaining
+// requests and the seconds until the bucket is full. Rejected requests get
+// 429 RATE_LIMITED with Retry-After. Store failures are logged and the
+// request is let through, so an outage of the store does not take the API
+// down with it.
+func (l *Limiter) Limit(name string, key KeyFunc) gin.HandlerFunc {
+	if l == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	policy, ok := l.policies[name]
+	if !ok {
+		panic("ratelimit: unknown policy " + name)
+	}
+
+	return func(c *gin.Context) {
+		bucket := policy.Name + ":" + c.FullPath() + ":" + key(c)
+		result, err := l.store.Take(c.Request.Context(), bucket, policy)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "rate limit store unavailable", "policy", policy.Name, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", seconds(result.Reset))
+		c.Header("RateLimit-Policy", strconv.Itoa(policy.Limit)+";w="+seconds(policy.Period))
+
+		if !result.Allowed {
+			c.Header("Retry-After", seconds(result.RetryAfter))
+			render.Error(c, apperrors.ErrRateLimited)
+			return
+		}
+		c.Next()
+	}
+}
+
+// seconds rounds d up to whole seconds, as the headers require.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"myapp/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func limitedRouter(limiter *Limiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.POST("/login", limiter.Limit("login", ByIP), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func login(router *gin.Engine, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLimitSetsHeadersAndRejects(t *testing.T) {
+	limiter := New(NewMemoryStore(), map[string]Policy{
+		"login": {Name: "login", Limit: 2, Period: time.Minute},
+	})
+	router := limitedRouter(limiter)
+
+	w := login(router, "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+
+	login(router, "10.0.0.1")
+	w = login(router, "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "RATE_LIMITED")
+
+	assert.Equal(t, http.StatusOK, login(router, "10.0.0.2").Code, "other clients are unaffected")
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func TestLimitFailsOpen(t *testing.T) {
+	limiter := New(failingStore{}, map[string]Policy{
+		"login": {Name: "login", Limit: 1, Period: time.Minute},
+	})
+
+	assert.Equal(t, http.StatusOK, login(limitedRouter(limiter), "10.0.0.1").Code)
+}
+
+func TestNilLimiterDisablesLimits(t *testing.T) {
+	var limiter *Limiter
+	router := limitedRouter(limiter)
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, login(router, "10.0.0.1").Code)
+	}
+}
@@ -0,0 +1,79 @@
+// Package ratelimit throttles clients with token buckets. Each bucket holds
+// up to Policy.Limit requests and refills continuously over Policy.Period,
+// so short bursts are allowed while the sustained rate stays bounded.
+// Buckets live in a Store: in memory for a single instance, or in Redis so
+// several instances share the same limits.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"myapp/config"
+)
+
+type Policy struct {
+	// Name appears in bucket keys so that policies never share a bucket.
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// rate is the refill speed in tokens per millisecond.
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / float64(p.Period.Milliseconds())
+}
+
+// Result describes the bucket after a request has been counted.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request would be allowed; zero
+	// when the request was allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets and takes one token from the bucket under key.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// Policies returns the route policies from the configuration, by name.
+func Policies(cfg config.RateLimitConfig) map[string]Policy {
+	policies := map[string]Policy{}
+	for name, policy := range map[string]config.RateLimitPolicy{
+		"login":    cfg.Login,
+		"register": cfg.Register,
+		"wallet":   cfg.Wallet,
+		"default":  cfg.Default,
+	} {
+		policies[name] = Policy{Name: name, Limit: policy.Limit, Period: policy.Period}
+	}
+	return policies
+}
+
+// take applies one request to a bucket holding tokens, last refilled
+// elapsed milliseconds ago, and returns the new token count.
+func take(policy Policy, tokens, elapsed float64) (float64, Result) {
+	rate := policy.rate()
+	tokens = math.Min(float64(policy.Limit), tokens+math.Max(0, elapsed)*rate)
+
+	result := Result{Limit: policy.Limit}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = milliseconds(math.Ceil((1 - tokens) / rate))
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = milliseconds(math.Ceil((float64(policy.Limit) - tokens) / rate))
+	return tokens, result
+}
+
+func milliseconds(ms float64) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+var testPolicy = Policy{Name: "test", Limit: 3, Period: 3 * time.Second}
+
+type fakeClock struct{ now time.Time }
+
+func (f *fakeClock) Now() time.Time { return f.now }
+
+func (f *fakeClock) Advance(d time.Duration) { f.now = f.now.Add(d) }
+
+// stores returns every Store implementation driven by the same clock.
+func stores(t *testing.T, clock *fakeClock) map[string]Store {
+	memory := NewMemoryStore()
+	memory.now = clock.Now
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	shared := NewRedisStore(client, "test:")
+	shared.now = clock.Now
+
+	return map[string]Store{"memory": memory, "redis": shared}
+}
+
+func TestBucketAllowsBurstThenRefills(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	for name, store := range stores(t, clock) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for i := 2; i >= 0; i-- {
+				result, err := store.Take(ctx, "client", testPolicy)
+				assert.NoError(t, err)
+				assert.True(t, result.Allowed)
+				assert.Equal(t, i, result.Remaining)
+			}
+
+			result, err := store.Take(ctx, "client", testPolicy)
+			assert.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.Equal(t, time.Second, result.RetryAfter)
+			assert.Equal(t, 3*time.Second, result.Reset)
+
+			clock.Advance(time.Second)
+			result, err = store.Take(ctx, "client", testPolicy)
+			assert.NoError(t, err)
+			assert.True(t, result.Allowed, "one token refills per second")
+			assert.Equal(t, 0, result.Remaining)
+
+			other, err := store.Take(ctx, "other-client", testPolicy)
+			assert.NoError(t, err)
+			assert.True(t, other.Allowed, "buckets are per key")
+		})
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+
+	_, _ = store.Take(context.Background(), "client", testPolicy)
+	clock.Advance(2 * sweepInterval)
+	_, _ = store.Take(context.Background(), "other-client", testPolicy)
+
+	assert.Len(t, store.buckets, 1)
+	assert.Contains(t, store.buckets, "other-client")
+}
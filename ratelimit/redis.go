@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript updates a bucket atomically. The bucket is a hash holding the
+// token count and the time of the last refill, and expires once it would
+// be full again anyway.
+var takeScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or limit
+local last = tonumber(state[2]) or now
+
+local rate = limit / period
+tokens = math.min(limit, tokens + math.max(0, now - last) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", now)
+redis.call("PEXPIRE", KEYS[1], period)
+
+return {allowed, math.floor(tokens), retry, math.ceil((limit - tokens) / rate)}
+`)
+
+// RedisStore keeps buckets in Redis or any server speaking its protocol, so
+// every instance of the service draws from the same buckets.
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+	now    func() time.Time
+}
+
+// NewRedisStore stores buckets under keys starting with prefix.
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix, now: time.Now}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	reply, err := takeScript.Run(ctx, s.client, []string{s.prefix + key},
+		policy.Limit, policy.Period.Milliseconds(), s.now().UnixMilli()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:    reply[0] == 1,
+		Limit:      policy.Limit,
+		Remaining:  int(reply[1]),
+		RetryAfter: time.Duration(reply[2]) * time.Millisecond,
+		Reset:      time.Duration(reply[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,69 @@
+// Package render writes API responses in the shared envelope format.
+package render
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+
+	"myapp/apperrors"
+	"myapp/dto"
+	"myapp/i18n"
+	"myapp/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error aborts the request with the error envelope for err. The HTTP status
+// is derived from the error code and messages are in the request language;
+// errors that are not domain errors are
+// logged and reported as INTERNAL_ERROR without their details.
+func Error(c *gin.Context, err error) {
+	appErr := apperrors.From(err)
+	requestID := middleware.GetRequestID(c)
+	lang := middleware.GetLanguage(c)
+
+	if appErr.Code == apperrors.CodeInternal {
+		slog.ErrorContext(c.Request.Context(), "request failed", "error", err)
+	}
+	// Attached for middleware that reports outcomes, such as metrics.
+	_ = c.Error(err)
+
+	c.AbortWithStatusJSON(appErr.Code.Status(), dto.ErrorResponse{
+		Status:    dto.StatusFailed,
+		RequestID: requestID,
+		Error: dto.ErrorDetail{
+			Code:    string(appErr.Code),
+			Message: message(lang, appErr),
+			Fields:  fieldMessages(lang, appErr.Fields),
+		},
+	})
+}
+
+// Recover turns a panic in a handler into an INTERNAL_ERROR response. The
+// panic and its stack trace are logged with the request context.
+func Recover() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		Error(c, fmt.Errorf("panic: %v\n%s", recovered, debug.Stack()))
+	})
+}
+
+func message(lang i18n.Language, appErr *apperrors.Error) string {
+	if text, ok := i18n.Lookup(lang, "error."+string(appErr.Code), nil); ok {
+		return text
+	}
+	return appErr.Message
+}
+
+func fieldMessages(lang i18n.Language, fields map[string]apperrors.FieldError) map[string]string {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	messages := make(map[string]string, len(fields))
+	for name, field := range fields {
+		messages[name] = i18n.T(lang, "validation."+field.Rule, i18n.Params{"param": field.Param})
+	}
+	return messages
+}
@@ -0,0 +1,94 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"myapp/apperrors"
+	"myapp/dto"
+	"myapp/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func performError(err error) (*httptest.ResponseRecorder, dto.ErrorResponse) {
+	return performErrorIn("en", err)
+}
+
+func performErrorIn(acceptLanguage string, err error) (*httptest.ResponseRecorder, dto.ErrorResponse) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.Locale())
+	router.GET("/", func(c *gin.Context) {
+		Error(c, err)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	req.Header.Set("Accept-Language", acceptLanguage)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response dto.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+func TestErrorUsesCatalogueStatus(t *testing.T) {
+	w, response := performError(apperrors.ErrInsufficientBalance)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "FAILED", response.Status)
+	assert.Equal(t, "req-1", response.RequestID)
+	assert.Equal(t, "INSUFFICIENT_BALANCE", response.Error.Code)
+	assert.Equal(t, "Balance is not enough", response.Error.Message)
+}
+
+func TestErrorDoesNotLeakInternalErrors(t *testing.T) {
+	w, response := performError(errors.New("ERROR: relation \"users\" does not exist (SQLSTATE 42P01)"))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "INTERNAL_ERROR", response.Error.Code)
+	assert.NotContains(t, w.Body.String(), "SQLSTATE")
+}
+
+func TestErrorIncludesFieldErrors(t *testing.T) {
+	w, response := performError(apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+		"amount": {Rule: "max", Param: "1000"},
+	}))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "must be at most 1000", response.Error.Fields["amount"])
+}
+
+func TestErrorIsLocalised(t *testing.T) {
+	w, response := performErrorIn("id-ID,id;q=0.9,en;q=0.8", apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+		"pin": {Rule: "len", Param: "6"},
+	}))
+
+	assert.Equal(t, "id", w.Header().Get("Content-Language"))
+	assert.Equal(t, "Validasi permintaan gagal", response.Error.Message)
+	assert.Equal(t, "harus tepat 6 karakter", response.Error.Fields["pin"])
+}
+
+func TestRecoverRespondsWithInternalError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestID(), Recover())
+	router.GET("/", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var response dto.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "INTERNAL_ERROR", response.Error.Code)
+	assert.NotEmpty(t, response.RequestID)
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"myapp/cache"
+	"myapp/models"
+
+	"github.com/google/uuid"
+)
+
+// CachedStore serves user lookups by ID through Reader from a cache, and
+// invalidates a user's entry whenever their account changes through this
+// store. Repositories and Transaction never consult the cache, so writes
+// and the balance checks deciding them always see the database.
+//
+// Entries are loaded from the primary, never from a replica. A lookup that
+// races with a write may still cache the old account; such an entry lives
+// until its TTL runs out.
+type CachedStore struct {
+	store Store
+	cache cache.Store
+	ttl   time.Duration
+}
+
+func NewCachedStore(store Store, c cache.Store, ttl time.Duration) *CachedStore {
+	return &CachedStore{store: store, cache: c, ttl: ttl}
+}
+
+func (s *CachedStore) Repositories() Repositories {
+	repos := s.store.Repositories()
+	repos.Users = invalidatingUsers{repos.Users, func(ctx context.Context, id uuid.UUID) {
+		s.invalidate(ctx, id)
+	}}
+	return repos
+}
+
+func (s *CachedStore) Reader(userID uuid.UUID) Repositories {
+	repos := s.store.Reader(userID)
+	repos.Users = cachedUsers{UserRepository: repos.Users, store: s}
+	return repos
+}
+
+func (s *CachedStore) Transaction(ctx context.Context, fn func(repos Repositories) error) error {
+	var changed []uuid.UUID
+	err := s.store.Transaction(ctx, func(repos Repositories) error {
+		repos.Users = invalidatingUsers{repos.Users, func(ctx context.Context, id uuid.UUID) {
+			changed = append(changed, id)
+		}}
+		return fn(repos)
+	})
+	// Invalidating after a rollback is harmless, and after a commit it
+	// must happen even if the caller has gone away.
+	s.invalidate(context.WithoutCancel(ctx), changed...)
+	return err
+}
+
+func userKey(id uuid.UUID) string {
+	return "user:" + id.String()
+}
+
+func (s *CachedStore) invalidate(ctx context.Context, ids ...uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = userKey(id)
+	}
+	if err := s.cache.Delete(ctx, keys...); err != nil {
+		slog.ErrorContext(ctx, "unable to invalidate cached users; they stay stale until they expire",
+			"error", err, "ttl", s.ttl)
+	}
+}
+
+// cachedUser is what the cache keeps of a user: everything but the PIN,
+// which never leaves the database.
+type cachedUser struct {
+	ID          uuid.UUID  `json:"id"`
+	FirstName   string     `json:"first_name"`
+	LastName    string     `json:"last_name"`
+	PhoneNumber string     `json:"phone_number"`
+	Address     string     `json:"address"`
+	Balance     float64    `json:"balance"`
+	Language    string     `json:"language"`
+	Version     int64      `json:"version"`
+	CreatedDate time.Time  `json:"created_date"`
+	UpdatedDate time.Time  `json:"updated_date"`
+	FrozenAt    *time.Time `json:"frozen_at"`
+}
+
+func newCachedUser(user models.User) cachedUser {
+	return cachedUser{
+		ID:          user.ID,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		PhoneNumber: user.PhoneNumber,
+		Address:     user.Address,
+		Balance:     user.Balance,
+		Language:    user.Language,
+		Version:     user.Version,
+		CreatedDate: user.CreatedDate,
+		UpdatedDate: user.UpdatedDate,
+		FrozenAt:    user.FrozenAt,
+	}
+}
+
+func (c cachedUser) user() models.User {
+	return models.User{
+		ID:          c.ID,
+		FirstName:   c.FirstName,
+		LastName:    c.LastName,
+		PhoneNumber: c.PhoneNumber,
+		Address:     c.Address,
+		Balance:     c.Balance,
+		Language:    c.Language,
+		Version:     c.Version,
+		CreatedDate: c.CreatedDate,
+		UpdatedDate: c.UpdatedDate,
+		FrozenAt:    c.FrozenAt,
+	}
+}
+
+type cachedUsers struct {
+	UserRepository
+	store *CachedStore
+}
+
+// FindByID returns the cached user, loading it from the primary on a miss.
+// Cache failures are logged and fall back to the database.
+func (r cachedUsers) FindByID(ctx context.Context, id uuid.UUID) (models.User, error) {
+	key := userKey(id)
+	data, ok, err := r.store.cache.Get(ctx, key)
+	if err != nil {
+		slog.WarnContext(ctx, "unable to read user cache", "error", err)
+	}
+	var cached cachedUser
+	if ok && json.Unmarshal(data, &cached) == nil {
+		return cached.user(), nil
+	}
+
+	user, err := r.store.store.Repositories().Users.FindByID(ctx, id)
+	if err != nil {
+		return models.User{}, err
+	}
+	data, err = json.Marshal(newCachedUser(user))
+	if err == nil {
+		err = r.store.cache.Set(ctx, key, data, r.store.ttl)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "unable to cache user", "error", err)
+	}
+	return user, nil
+}
+
+// invalidatingUsers reports every user whose account it changed.
+type invalidatingUsers struct {
+	UserRepository
+	changed func(ctx context.Context, id uuid.UUID)
+}
+
+func (r invalidatingUsers) Create(ctx context.Context, user *models.User) error {
+	if err := r.UserRepository.Create(ctx, user); err != nil {
+		return err
+	}
+	r.changed(ctx, user.ID)
+	return nil
+}
+
+func (r invalidatingUsers) UpdateProfile(ctx context.Context, user *models.User, changes map[string]interface{}) error {
+	if err := r.UserRepository.UpdateProfile(ctx, user, changes); err != nil {
+		return err
+	}
+	r.changed(ctx, user.ID)
+	return nil
+}
+
+func (r invalidatingUsers) AdjustBalance(ctx context.Context, id uuid.UUID, delta float64) (models.User, error) {
+	user, err := r.UserRepository.AdjustBalance(ctx, id, delta)
+	if err == nil {
+		r.changed(ctx, id)
+	}
+	return user, err
+}
+
+func (r invalidatingUsers) SetFrozenAt(ctx context.Context, id uuid.UUID, frozenAt *time.Time) (models.User, error) {
+	user, err := r.UserRepository.SetFrozenAt(ctx, id, frozenAt)
+	if err == nil {
+		r.changed(ctx, id)
+	}
+	return user, err
+}
@@ -0,0 +1,81 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"myapp/cache"
+	"myapp/models"
+	"myapp/repository"
+	"myapp/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedStoreInvalidatesOnChanges(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.DB(t)
+	user := testutil.NewUser().WithBalance(1000).Create(t, db)
+	lru := cache.NewLRU(10)
+	store := repository.NewCachedStore(testutil.Store(t), lru, time.Minute)
+
+	cached, err := store.Reader(user.ID).Users.FindByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000.0, cached.Balance)
+	assert.Equal(t, 1, lru.Len())
+
+	// Changes made behind the store's back are not seen until invalidated
+	db.Model(&models.User{}).Where("id = ?", user.ID).Update("first_name", "Behind")
+	cached, _ = store.Reader(user.ID).Users.FindByID(ctx, user.ID)
+	assert.Equal(t, user.FirstName, cached.FirstName)
+	assert.Empty(t, cached.PIN, "the PIN is never cached")
+
+	// Balance mutations invalidate
+	err = store.Transaction(ctx, func(repos repository.Repositories) error {
+		_, err := repos.Users.AdjustBalance(ctx, user.ID, -250)
+		return err
+	})
+	assert.NoError(t, err)
+	cached, _ = store.Reader(user.ID).Users.FindByID(ctx, user.ID)
+	assert.Equal(t, 750.0, cached.Balance)
+	assert.Equal(t, "Behind", cached.FirstName)
+
+	// So do profile updates
+	cached.FirstName = "Updated"
+	err = store.Transaction(ctx, func(repos repository.Repositories) error {
+		return repos.Users.UpdateProfile(ctx, &cached, map[string]interface{}{"first_name": "Updated"})
+	})
+	assert.NoError(t, err)
+	cached, _ = store.Reader(user.ID).Users.FindByID(ctx, user.ID)
+	assert.Equal(t, "Updated", cached.FirstName)
+
+	// And freezes outside a transaction
+	now := time.Now()
+	_, err = store.Repositories().Users.SetFrozenAt(ctx, user.ID, &now)
+	assert.NoError(t, err)
+	cached, _ = store.Reader(user.ID).Users.FindByID(ctx, user.ID)
+	assert.True(t, cached.Frozen())
+}
+
+func TestCachedStoreNeverAuthorisesFromCache(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.DB(t)
+	user := testutil.NewUser().WithBalance(1000).Create(t, db)
+	store := repository.NewCachedStore(testutil.Store(t), cache.NewLRU(10), time.Minute)
+
+	if _, err := store.Reader(user.ID).Users.FindByID(ctx, user.ID); err != nil {
+		t.Fatalf("Failed to warm the cache: %v", err)
+	}
+	db.Model(&models.User{}).Where("id = ?", user.ID).Update("balance", 100)
+
+	err := store.Transaction(ctx, func(repos repository.Repositories) error {
+		current, err := repos.Users.FindByID(ctx, user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 100.0, current.Balance)
+		_, err = repos.Users.AdjustBalance(ctx, user.ID, -500)
+		return err
+	})
+	assert.True(t, errors.Is(err, repository.ErrInsufficientFunds), "the cached balance of 1000 must not count")
+}
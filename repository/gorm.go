@@ -0,0 +1,588 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"myapp/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormStore implements Store on a GORM database. The database must be
+// opened with TranslateError so that unique violations can be recognised.
+type GormStore struct {
+	db *gorm.DB
+	// replicas is nil when every read goes to db.
+	replicas *Replicas
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// WithReplicas returns a store that serves Reader from replicas.
+func (s *GormStore) WithReplicas(replicas *Replicas) *GormStore {
+	return &GormStore{db: s.db, replicas: replicas}
+}
+
+func (s *GormStore) Repositories() Repositories {
+	var wrote func(id uuid.UUID)
+	if s.replicas != nil {
+		wrote = func(id uuid.UUID) { s.replicas.wrote(id) }
+	}
+	return repositoriesFor(s.db, wrote)
+}
+
+func (s *GormStore) Reader(userID uuid.UUID) Repositories {
+	if s.replicas == nil {
+		return repositoriesFor(s.db, nil)
+	}
+	return repositoriesFor(s.replicas.forRead(userID), nil)
+}
+
+func (s *GormStore) Transaction(ctx context.Context, fn func(repos Repositories) error) error {
+	var written []uuid.UUID
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(repositoriesFor(tx, func(id uuid.UUID) { written = append(written, id) }))
+	})
+	if err == nil && s.replicas != nil {
+		s.replicas.wrote(written...)
+	}
+	return err
+}
+
+// repositoriesFor binds the repositories to db. wrote, when not nil, is
+// told about every user whose account changed.
+func repositoriesFor(db *gorm.DB, wrote func(id uuid.UUID)) Repositories {
+	if wrote == nil {
+		wrote = func(uuid.UUID) {}
+	}
+	return Repositories{
+		Users:     gormUsers{db, wrote},
+		Balances:  gormBalances{db, wrote},
+		Quotes:    gormQuotes{db},
+		Pockets:   gormPockets{db, wrote},
+		Movements: gormMovements{db},
+		TopUps:    gormTopUps{db},
+		Payments:  gormPayments{db},
+		Transfers: gormTransfers{db},
+		Reversals: gormReversals{db},
+		Schedules: gormSchedules{db},
+		Requests:  gormRequests{db},
+		Audit:     gormAudit{db},
+	}
+}
+
+// translate maps GORM errors onto the package errors, keeping the original
+// in the chain for logging.
+func translate(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return errors.Join(ErrNotFound, err)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return errors.Join(ErrDuplicate, err)
+	}
+	return err
+}
+
+type gormUsers struct {
+	db    *gorm.DB
+	wrote func(id uuid.UUID)
+}
+
+func (r gormUsers) FindByID(ctx context.Context, id uuid.UUID) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
+	return user, translate(err)
+}
+
+func (r gormUsers) FindForUpdate(ctx context.Context, id uuid.UUID) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).First(&user).Error
+	return user, translate(err)
+}
+
+func (r gormUsers) FindByPhone(ctx context.Context, phoneNumber string) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("phone_number = ?", phoneNumber).First(&user).Error
+	return user, translate(err)
+}
+
+func (r gormUsers) Create(ctx context.Context, user *models.User) error {
+	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+		return translate(err)
+	}
+	r.wrote(user.ID)
+	return nil
+}
+
+func (r gormUsers) UpdateProfile(ctx context.Context, user *models.User, changes map[string]interface{}) error {
+	user.UpdatedDate = time.Now()
+	changes["updated_date"] = user.UpdatedDate
+	changes["version"] = gorm.Expr("version + 1")
+
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND version = ?", user.ID, user.Version).
+		Updates(changes)
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStale
+	}
+
+	user.Version++
+	r.wrote(user.ID)
+	return nil
+}
+
+func (r gormUsers) AdjustBalance(ctx context.Context, id uuid.UUID, delta float64) (models.User, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND balance + ? >= 0", id, delta).
+		Updates(map[string]interface{}{
+			"balance":      gorm.Expr("balance + ?", delta),
+			"updated_date": time.Now(),
+		})
+	if result.Error != nil {
+		return models.User{}, translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// Either the user is gone or the balance is too low.
+		if _, err := r.FindByID(ctx, id); err != nil {
+			return models.User{}, err
+		}
+		return models.User{}, ErrInsufficientFunds
+	}
+	r.wrote(id)
+	return r.FindByID(ctx, id)
+}
+
+func (r gormUsers) SetFrozenAt(ctx context.Context, id uuid.UUID, frozenAt *time.Time) (models.User, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"frozen_at":    frozenAt,
+			"updated_date": time.Now(),
+		})
+	if result.Error != nil {
+		return models.User{}, translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.User{}, ErrNotFound
+	}
+	r.wrote(id)
+	return r.FindByID(ctx, id)
+}
+
+type gormBalances struct {
+	db    *gorm.DB
+	wrote func(id uuid.UUID)
+}
+
+func (r gormBalances) Adjust(ctx context.Context, userID uuid.UUID, currency string, delta float64) (models.Balance, error) {
+	db := r.db.WithContext(ctx)
+	now := time.Now()
+	if delta >= 0 {
+		// Credits open the balance when there is none yet.
+		balance := models.Balance{UserID: userID, Currency: currency, Amount: delta, UpdatedDate: now}
+		err := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}, {Name: "currency"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"amount":       gorm.Expr("balances.amount + ?", delta),
+				"updated_date": now,
+			}),
+		}).Create(&balance).Error
+		if err != nil {
+			return models.Balance{}, translate(err)
+		}
+	} else {
+		result := db.Model(&models.Balance{}).
+			Where("user_id = ? AND currency = ? AND amount + ? >= 0", userID, currency, delta).
+			Updates(map[string]interface{}{
+				"amount":       gorm.Expr("amount + ?", delta),
+				"updated_date": now,
+			})
+		if result.Error != nil {
+			return models.Balance{}, translate(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			// A missing balance is an empty one.
+			return models.Balance{}, ErrInsufficientFunds
+		}
+	}
+	r.wrote(userID)
+	return r.find(ctx, userID, currency)
+}
+
+func (r gormBalances) find(ctx context.Context, userID uuid.UUID, currency string) (models.Balance, error) {
+	var balance models.Balance
+	err := r.db.WithContext(ctx).Where("user_id = ? AND currency = ?", userID, currency).First(&balance).Error
+	return balance, translate(err)
+}
+
+func (r gormBalances) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Balance, error) {
+	var balances []models.Balance
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("currency").Find(&balances).Error
+	return balances, translate(err)
+}
+
+type gormQuotes struct{ db *gorm.DB }
+
+func (r gormQuotes) Create(ctx context.Context, quote *models.Quote) error {
+	return translate(r.db.WithContext(ctx).Create(quote).Error)
+}
+
+func (r gormQuotes) FindByID(ctx context.Context, id uuid.UUID) (models.Quote, error) {
+	var quote models.Quote
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&quote).Error
+	return quote, translate(err)
+}
+
+func (r gormQuotes) MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.Quote{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStale
+	}
+	return nil
+}
+
+type gormPockets struct {
+	db    *gorm.DB
+	wrote func(id uuid.UUID)
+}
+
+func (r gormPockets) Create(ctx context.Context, pocket *models.Pocket) error {
+	if err := r.db.WithContext(ctx).Create(pocket).Error; err != nil {
+		return translate(err)
+	}
+	r.wrote(pocket.UserID)
+	return nil
+}
+
+func (r gormPockets) FindByID(ctx context.Context, id uuid.UUID) (models.Pocket, error) {
+	var pocket models.Pocket
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&pocket).Error
+	return pocket, translate(err)
+}
+
+func (r gormPockets) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Pocket, error) {
+	var pockets []models.Pocket
+	err := r.db.WithContext(ctx).Where("user_id = ? AND closed_at IS NULL", userID).
+		Order("created_date, id").Find(&pockets).Error
+	return pockets, translate(err)
+}
+
+func (r gormPockets) Rename(ctx context.Context, id uuid.UUID, name string) (models.Pocket, error) {
+	return r.update(ctx, id, map[string]interface{}{"name": name}, ErrStale, "closed_at IS NULL")
+}
+
+func (r gormPockets) AdjustBalance(ctx context.Context, id uuid.UUID, delta float64) (models.Pocket, error) {
+	return r.update(ctx, id, map[string]interface{}{"balance": gorm.Expr("balance + ?", delta)},
+		ErrInsufficientFunds, "closed_at IS NULL AND balance + ? >= 0", delta)
+}
+
+func (r gormPockets) Close(ctx context.Context, id uuid.UUID, closedAt time.Time) (models.Pocket, error) {
+	return r.update(ctx, id, map[string]interface{}{"closed_at": closedAt}, ErrStale, "closed_at IS NULL AND balance = 0")
+}
+
+// update applies changes to pocket id if it matches guard, which must only
+// match open pockets. When nothing matches, a missing or closed pocket is
+// ErrNotFound and any other pocket failed.
+func (r gormPockets) update(ctx context.Context, id uuid.UUID, changes map[string]interface{}, failed error, guard string, args ...interface{}) (models.Pocket, error) {
+	changes["updated_date"] = time.Now()
+	result := r.db.WithContext(ctx).Model(&models.Pocket{}).
+		Where("id = ?", id).Where(guard, args...).
+		Updates(changes)
+	if result.Error != nil {
+		return models.Pocket{}, translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		pocket, err := r.FindByID(ctx, id)
+		switch {
+		case err != nil:
+			return models.Pocket{}, err
+		case pocket.Closed():
+			return models.Pocket{}, ErrNotFound
+		}
+		return models.Pocket{}, failed
+	}
+	pocket, err := r.FindByID(ctx, id)
+	if err != nil {
+		return models.Pocket{}, err
+	}
+	r.wrote(pocket.UserID)
+	return pocket, nil
+}
+
+type gormMovements struct{ db *gorm.DB }
+
+func (r gormMovements) Create(ctx context.Context, movement *models.PocketMovement) error {
+	return translate(r.db.WithContext(ctx).Create(movement).Error)
+}
+
+func (r gormMovements) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.PocketMovement, error) {
+	var movements []models.PocketMovement
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&movements).Error
+	return movements, translate(err)
+}
+
+type gormTopUps struct{ db *gorm.DB }
+
+func (r gormTopUps) Create(ctx context.Context, topUp *models.TopUp) error {
+	return translate(r.db.WithContext(ctx).Create(topUp).Error)
+}
+
+func (r gormTopUps) FindByID(ctx context.Context, id uuid.UUID) (models.TopUp, error) {
+	var topUp models.TopUp
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&topUp).Error
+	return topUp, translate(err)
+}
+
+func (r gormTopUps) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.TopUp, error) {
+	var topUps []models.TopUp
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&topUps).Error
+	return topUps, translate(err)
+}
+
+func (r gormTopUps) MarkReversed(ctx context.Context, id uuid.UUID, reversedAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.TopUp{}).
+		Where("id = ? AND reversed_at IS NULL", id).
+		Update("reversed_at", reversedAt)
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStale
+	}
+	return nil
+}
+
+type gormPayments struct{ db *gorm.DB }
+
+func (r gormPayments) Create(ctx context.Context, payment *models.Payment) error {
+	return translate(r.db.WithContext(ctx).Create(payment).Error)
+}
+
+func (r gormPayments) FindByID(ctx context.Context, id uuid.UUID) (models.Payment, error) {
+	var payment models.Payment
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&payment).Error
+	return payment, translate(err)
+}
+
+func (r gormPayments) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&payments).Error
+	return payments, translate(err)
+}
+
+func (r gormPayments) AddRefund(ctx context.Context, id uuid.UUID, amount float64) (models.Payment, error) {
+	// Amounts are in cents, so half a cent of slack absorbs the rounding of
+	// databases that store them as floats.
+	result := r.db.WithContext(ctx).Model(&models.Payment{}).
+		Where("id = ? AND refunded_amount + ? <= amount + 0.005", id, amount).
+		Update("refunded_amount", gorm.Expr("refunded_amount + ?", amount))
+	if result.Error != nil {
+		return models.Payment{}, translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// Either the payment is gone or too much would be refunded.
+		if _, err := r.FindByID(ctx, id); err != nil {
+			return models.Payment{}, err
+		}
+		return models.Payment{}, ErrInsufficientFunds
+	}
+	return r.FindByID(ctx, id)
+}
+
+type gormTransfers struct{ db *gorm.DB }
+
+func (r gormTransfers) Create(ctx context.Context, transfer *models.Transfer) error {
+	return translate(r.db.WithContext(ctx).Create(transfer).Error)
+}
+
+func (r gormTransfers) FindByID(ctx context.Context, id uuid.UUID) (models.Transfer, error) {
+	var transfer models.Transfer
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&transfer).Error
+	return transfer, translate(err)
+}
+
+func (r gormTransfers) ListBySender(ctx context.Context, userID uuid.UUID) ([]models.Transfer, error) {
+	var transfers []models.Transfer
+	err := r.db.WithContext(ctx).Where("from_user_id = ?", userID).Find(&transfers).Error
+	return transfers, translate(err)
+}
+
+func (r gormTransfers) ListByRecipient(ctx context.Context, userID uuid.UUID) ([]models.Transfer, error) {
+	var transfers []models.Transfer
+	err := r.db.WithContext(ctx).Where("to_user_id = ?", userID).Find(&transfers).Error
+	return transfers, translate(err)
+}
+
+func (r gormTransfers) MarkReversed(ctx context.Context, id uuid.UUID, reversedAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.Transfer{}).
+		Where("id = ? AND status = ? AND reversed_at IS NULL", id, models.TransferSucceeded).
+		Updates(map[string]interface{}{
+			"status":      models.TransferReversed,
+			"reversed_at": reversedAt,
+		})
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStale
+	}
+	return nil
+}
+
+type gormReversals struct{ db *gorm.DB }
+
+func (r gormReversals) Create(ctx context.Context, reversal *models.Reversal) error {
+	return translate(r.db.WithContext(ctx).Create(reversal).Error)
+}
+
+func (r gormReversals) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Reversal, error) {
+	var reversals []models.Reversal
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&reversals).Error
+	return reversals, translate(err)
+}
+
+func (r gormReversals) ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]models.Reversal, error) {
+	var reversals []models.Reversal
+	err := r.db.WithContext(ctx).Where("payment_id = ?", paymentID).
+		Order("created_date").Find(&reversals).Error
+	return reversals, translate(err)
+}
+
+type gormSchedules struct{ db *gorm.DB }
+
+func (r gormSchedules) Create(ctx context.Context, schedule *models.ScheduledTransfer) error {
+	return translate(r.db.WithContext(ctx).Create(schedule).Error)
+}
+
+func (r gormSchedules) FindByID(ctx context.Context, id uuid.UUID) (models.ScheduledTransfer, error) {
+	var schedule models.ScheduledTransfer
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&schedule).Error
+	return schedule, translate(err)
+}
+
+func (r gormSchedules) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.ScheduledTransfer, error) {
+	var schedules []models.ScheduledTransfer
+	err := r.db.WithContext(ctx).Where("user_id = ? AND status <> ?", userID, models.ScheduleCancelled).
+		Order("created_date").Find(&schedules).Error
+	return schedules, translate(err)
+}
+
+func (r gormSchedules) ListDue(ctx context.Context, now time.Time, limit int) ([]models.ScheduledTransfer, error) {
+	var schedules []models.ScheduledTransfer
+	err := r.db.WithContext(ctx).Where("status = ? AND attempt_at <= ?", models.ScheduleActive, now).
+		Order("attempt_at").Limit(limit).Find(&schedules).Error
+	return schedules, translate(err)
+}
+
+func (r gormSchedules) Update(ctx context.Context, schedule *models.ScheduledTransfer) error {
+	schedule.UpdatedDate = time.Now()
+	result := r.db.WithContext(ctx).Model(&models.ScheduledTransfer{}).
+		Where("id = ? AND version = ?", schedule.ID, schedule.Version).
+		Updates(map[string]interface{}{
+			"to_user_id":   schedule.ToUserID,
+			"amount":       schedule.Amount,
+			"currency":     schedule.Currency,
+			"to_currency":  schedule.ToCurrency,
+			"remarks":      schedule.Remarks,
+			"frequency":    schedule.Frequency,
+			"cron":         schedule.Cron,
+			"start_at":     schedule.StartAt,
+			"next_run_at":  schedule.NextRunAt,
+			"attempt_at":   schedule.AttemptAt,
+			"attempts":     schedule.Attempts,
+			"last_error":   schedule.LastError,
+			"last_run_at":  schedule.LastRunAt,
+			"status":       schedule.Status,
+			"updated_date": schedule.UpdatedDate,
+			"version":      gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStale
+	}
+
+	schedule.Version++
+	return nil
+}
+
+type gormRequests struct{ db *gorm.DB }
+
+func (r gormRequests) Create(ctx context.Context, request *models.MoneyRequest) error {
+	return translate(r.db.WithContext(ctx).Create(request).Error)
+}
+
+func (r gormRequests) FindByID(ctx context.Context, id uuid.UUID) (models.MoneyRequest, error) {
+	var request models.MoneyRequest
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&request).Error
+	return request, translate(err)
+}
+
+func (r gormRequests) ListByRequester(ctx context.Context, requesterID uuid.UUID, since time.Time) ([]models.MoneyRequest, error) {
+	var requests []models.MoneyRequest
+	err := r.db.WithContext(ctx).Where("requester_id = ? AND created_date >= ?", requesterID, since).
+		Order("created_date DESC").Find(&requests).Error
+	return requests, translate(err)
+}
+
+func (r gormRequests) ListPendingByPayer(ctx context.Context, payerID uuid.UUID, now time.Time) ([]models.MoneyRequest, error) {
+	var requests []models.MoneyRequest
+	err := r.db.WithContext(ctx).
+		Where("payer_id = ? AND status = ? AND expires_at > ?", payerID, models.RequestPending, now).
+		Order("created_date").Find(&requests).Error
+	return requests, translate(err)
+}
+
+func (r gormRequests) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.MoneyRequest, error) {
+	var requests []models.MoneyRequest
+	err := r.db.WithContext(ctx).Where("requester_id = ? OR payer_id = ?", userID, userID).Find(&requests).Error
+	return requests, translate(err)
+}
+
+func (r gormRequests) Respond(ctx context.Context, id uuid.UUID, status string, respondedAt time.Time, transferID *uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&models.MoneyRequest{}).
+		Where("id = ? AND status = ? AND expires_at > ?", id, models.RequestPending, respondedAt).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"responded_at": respondedAt,
+			"transfer_id":  transferID,
+		})
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStale
+	}
+	return nil
+}
+
+type gormAudit struct{ db *gorm.DB }
+
+func (r gormAudit) Create(ctx context.Context, log *models.AuditLog) error {
+	return translate(r.db.WithContext(ctx).Create(log).Error)
+}
+
+func (r gormAudit) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]models.AuditLog, error) {
+	var logs []models.AuditLog
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("created_date DESC").Limit(limit).Find(&logs).Error
+	return logs, translate(err)
+}
@@ -0,0 +1,362 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"myapp/models"
+	"myapp/repository"
+	"myapp/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsersCreateRejectsDuplicatePhone(t *testing.T) {
+	users := testutil.Store(t).Repositories().Users
+	ctx := context.Background()
+
+	first := testutil.NewUser().Build()
+	assert.NoError(t, users.Create(ctx, &first))
+
+	second := testutil.NewUser().WithPhone(first.PhoneNumber).Build()
+	err := users.Create(ctx, &second)
+	assert.True(t, errors.Is(err, repository.ErrDuplicate))
+}
+
+func TestUsersFindReportsNotFound(t *testing.T) {
+	users := testutil.Store(t).Repositories().Users
+
+	_, err := users.FindByPhone(context.Background(), "+62800000000")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+	_, err = users.FindForUpdate(context.Background(), uuid.New())
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+}
+
+func TestFindForUpdateReturnsTheUser(t *testing.T) {
+	db := testutil.DB(t)
+	user := testutil.NewUser().WithBalance(500).Create(t, db)
+	store := repository.NewGormStore(db)
+
+	err := store.Transaction(context.Background(), func(repos repository.Repositories) error {
+		locked, err := repos.Users.FindForUpdate(context.Background(), user.ID)
+		assert.Equal(t, user.ID, locked.ID)
+		assert.Equal(t, 500.0, locked.Balance)
+		return err
+	})
+	assert.NoError(t, err)
+}
+
+func TestAdjustBalanceNeverGoesNegative(t *testing.T) {
+	db := testutil.DB(t)
+	users := repository.NewGormStore(db).Repositories().Users
+	user := testutil.NewUser().WithBalance(100).Create(t, db)
+	ctx := context.Background()
+
+	updated, err := users.AdjustBalance(ctx, user.ID, -60)
+	assert.NoError(t, err)
+	assert.Equal(t, 40.0, updated.Balance)
+
+	_, err = users.AdjustBalance(ctx, user.ID, -60)
+	assert.True(t, errors.Is(err, repository.ErrInsufficientFunds))
+
+	_, err = users.AdjustBalance(ctx, testutil.NewUser().Build().ID, 10)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+}
+
+func TestUpdateProfileChecksVersion(t *testing.T) {
+	db := testutil.DB(t)
+	users := repository.NewGormStore(db).Repositories().Users
+	user := testutil.NewUser().Create(t, db)
+	ctx := context.Background()
+
+	stale := user
+	assert.NoError(t, users.UpdateProfile(ctx, &user, map[string]interface{}{"first_name": "Budi"}))
+	assert.Equal(t, int64(2), user.Version)
+
+	err := users.UpdateProfile(ctx, &stale, map[string]interface{}{"first_name": "Andi"})
+	assert.True(t, errors.Is(err, repository.ErrStale))
+}
+
+func TestTransactionRollsBack(t *testing.T) {
+	db := testutil.DB(t)
+	store := repository.NewGormStore(db)
+	user := testutil.NewUser().WithBalance(100).Create(t, db)
+	ctx := context.Background()
+
+	failure := errors.New("abort")
+	err := store.Transaction(ctx, func(repos repository.Repositories) error {
+		if _, err := repos.Users.AdjustBalance(ctx, user.ID, 50); err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.Equal(t, failure, err)
+
+	var stored models.User
+	assert.NoError(t, db.First(&stored, "id = ?", user.ID).Error)
+	assert.Equal(t, 100.0, stored.Balance)
+}
+
+func TestFreezeAndAuditTrail(t *testing.T) {
+	db := testutil.DB(t)
+	repos := repository.NewGormStore(db).Repositories()
+	user := testutil.NewUser().Create(t, db)
+	ctx := context.Background()
+
+	frozenAt := time.Now()
+	frozen, err := repos.Users.SetFrozenAt(ctx, user.ID, &frozenAt)
+	assert.NoError(t, err)
+	assert.True(t, frozen.Frozen())
+
+	unfrozen, err := repos.Users.SetFrozenAt(ctx, user.ID, nil)
+	assert.NoError(t, err)
+	assert.False(t, unfrozen.Frozen())
+
+	for i, action := range []string{"user.freeze", "user.unfreeze"} {
+		entry := models.AuditLog{Actor: "admin:ops", UserID: user.ID, Action: action, CreatedDate: frozenAt.Add(time.Duration(i) * time.Second)}
+		assert.NoError(t, repos.Audit.Create(ctx, &entry))
+	}
+
+	logs, err := repos.Audit.ListByUser(ctx, user.ID, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "user.unfreeze", logs[0].Action)
+	}
+}
+
+func TestBalancesOpenOnCreditAndNeverGoNegative(t *testing.T) {
+	db := testutil.DB(t)
+	balances := repository.NewGormStore(db).Repositories().Balances
+	user := testutil.NewUser().Create(t, db)
+	ctx := context.Background()
+
+	_, err := balances.Adjust(ctx, user.ID, "USD", -1)
+	assert.True(t, errors.Is(err, repository.ErrInsufficientFunds), "a missing balance is empty")
+
+	balance, err := balances.Adjust(ctx, user.ID, "USD", 25.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 25.5, balance.Amount)
+	balance, err = balances.Adjust(ctx, user.ID, "USD", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 35.5, balance.Amount)
+
+	_, err = balances.Adjust(ctx, user.ID, "USD", -40)
+	assert.True(t, errors.Is(err, repository.ErrInsufficientFunds))
+	balance, err = balances.Adjust(ctx, user.ID, "USD", -35.5)
+	assert.NoError(t, err)
+	assert.Zero(t, balance.Amount)
+
+	_, err = balances.Adjust(ctx, user.ID, "EUR", 5)
+	assert.NoError(t, err)
+	list, err := balances.ListByUser(ctx, user.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "EUR", list[0].Currency)
+		assert.Equal(t, "USD", list[1].Currency)
+	}
+}
+
+func TestQuotesAreUsedOnce(t *testing.T) {
+	db := testutil.DB(t)
+	quotes := repository.NewGormStore(db).Repositories().Quotes
+	user := testutil.NewUser().Create(t, db)
+	ctx := context.Background()
+
+	now := time.Now()
+	quote := models.Quote{UserID: user.ID, FromCurrency: "USD", ToCurrency: "IDR", MidRate: 16000, Spread: 0.01, Rate: 15840, CreatedDate: now, ExpiresAt: now.Add(time.Minute)}
+	assert.NoError(t, quotes.Create(ctx, &quote))
+
+	stored, err := quotes.FindByID(ctx, quote.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 15840.0, stored.Rate)
+	assert.Nil(t, stored.UsedAt)
+
+	assert.NoError(t, quotes.MarkUsed(ctx, quote.ID, now))
+	assert.True(t, errors.Is(quotes.MarkUsed(ctx, quote.ID, now), repository.ErrStale))
+}
+
+func TestPocketsKeepTheirBalanceAndCloseOnlyWhenEmpty(t *testing.T) {
+	db := testutil.DB(t)
+	pockets := repository.NewGormStore(db).Repositories().Pockets
+	user := testutil.NewUser().Create(t, db)
+	ctx := context.Background()
+	now := time.Now()
+
+	savings := models.Pocket{UserID: user.ID, Name: "Savings", Currency: "IDR", CreatedDate: now}
+	if err := pockets.Create(ctx, &savings); err != nil {
+		t.Fatalf("Failed to create pocket: %v", err)
+	}
+	holiday := models.Pocket{UserID: user.ID, Name: "Holiday", Currency: "USD", CreatedDate: now.Add(time.Second)}
+	if err := pockets.Create(ctx, &holiday); err != nil {
+		t.Fatalf("Failed to create pocket: %v", err)
+	}
+
+	pocket, err := pockets.AdjustBalance(ctx, savings.ID, 500)
+	assert.NoError(t, err)
+	assert.Equal(t, 500.0, pocket.Balance)
+	_, err = pockets.AdjustBalance(ctx, savings.ID, -501)
+	assert.True(t, errors.Is(err, repository.ErrInsufficientFunds))
+
+	pocket, err = pockets.Rename(ctx, savings.ID, "Rainy day")
+	assert.NoError(t, err)
+	assert.Equal(t, "Rainy day", pocket.Name)
+
+	_, err = pockets.Close(ctx, savings.ID, now)
+	assert.True(t, errors.Is(err, repository.ErrStale), "pockets holding money stay open")
+	_, err = pockets.AdjustBalance(ctx, savings.ID, -500)
+	assert.NoError(t, err)
+	pocket, err = pockets.Close(ctx, savings.ID, now)
+	assert.NoError(t, err)
+	assert.True(t, pocket.Closed())
+
+	_, err = pockets.AdjustBalance(ctx, savings.ID, 1)
+	assert.True(t, errors.Is(err, repository.ErrNotFound), "closed pockets take no money")
+	_, err = pockets.Rename(ctx, savings.ID, "Again")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	list, err := pockets.ListByUser(ctx, user.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, "Holiday", list[0].Name)
+	}
+}
+
+func TestRefundsNeverExceedThePaymentAndReversalsHappenOnce(t *testing.T) {
+	db := testutil.DB(t)
+	repos := repository.NewGormStore(db).Repositories()
+	user := testutil.NewUser().Create(t, db)
+	ctx := context.Background()
+	now := time.Now()
+
+	payment := models.Payment{UserID: user.ID, Amount: 100, Currency: "IDR", CreatedDate: now}
+	if err := repos.Payments.Create(ctx, &payment); err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	updated, err := repos.Payments.AddRefund(ctx, payment.ID, 60)
+	assert.NoError(t, err)
+	assert.Equal(t, 60.0, updated.RefundedAmount)
+	_, err = repos.Payments.AddRefund(ctx, payment.ID, 40.01)
+	assert.True(t, errors.Is(err, repository.ErrInsufficientFunds))
+	updated, err = repos.Payments.AddRefund(ctx, payment.ID, 40)
+	assert.NoError(t, err)
+	assert.Equal(t, 100.0, updated.RefundedAmount)
+
+	topUp := models.TopUp{UserID: user.ID, Amount: 100, Currency: "IDR", CreatedDate: now}
+	if err := repos.TopUps.Create(ctx, &topUp); err != nil {
+		t.Fatalf("Failed to create top-up: %v", err)
+	}
+	assert.NoError(t, repos.TopUps.MarkReversed(ctx, topUp.ID, now))
+	assert.True(t, errors.Is(repos.TopUps.MarkReversed(ctx, topUp.ID, now), repository.ErrStale))
+
+	other := testutil.NewUser().Create(t, db)
+	transfer := models.Transfer{FromUserID: user.ID, ToUserID: other.ID, Amount: 10, Status: models.TransferSucceeded, CreatedDate: now}
+	if err := repos.Transfers.Create(ctx, &transfer); err != nil {
+		t.Fatalf("Failed to create transfer: %v", err)
+	}
+	assert.NoError(t, repos.Transfers.MarkReversed(ctx, transfer.ID, now))
+	assert.True(t, errors.Is(repos.Transfers.MarkReversed(ctx, transfer.ID, now), repository.ErrStale))
+	reversed, err := repos.Transfers.FindByID(ctx, transfer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransferReversed, reversed.Status)
+}
+
+func TestSchedulesAreClaimedAndMadeOnce(t *testing.T) {
+	db := testutil.DB(t)
+	repos := repository.NewGormStore(db).Repositories()
+	user := testutil.NewUser().Create(t, db)
+	other := testutil.NewUser().Create(t, db)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	due := now.Add(-time.Minute)
+	later := now.Add(time.Hour)
+	for _, attemptAt := range []time.Time{later, due} {
+		attemptAt := attemptAt
+		scheduled := models.ScheduledTransfer{
+			UserID: user.ID, ToUserID: other.ID, Amount: 10, Frequency: "DAILY", StartAt: due,
+			NextRunAt: &attemptAt, AttemptAt: &attemptAt, CreatedDate: now, UpdatedDate: now,
+		}
+		if err := repos.Schedules.Create(ctx, &scheduled); err != nil {
+			t.Fatalf("Failed to create schedule: %v", err)
+		}
+	}
+
+	list, err := repos.Schedules.ListDue(ctx, now, 10)
+	assert.NoError(t, err)
+	if !assert.Len(t, list, 1) {
+		return
+	}
+	claimed, stale := list[0], list[0]
+	assert.Equal(t, 1, claimed.Version)
+	next := due.Add(24 * time.Hour)
+	claimed.NextRunAt, claimed.AttemptAt = &next, &next
+	assert.NoError(t, repos.Schedules.Update(ctx, &claimed))
+	assert.Equal(t, 2, claimed.Version)
+	assert.True(t, errors.Is(repos.Schedules.Update(ctx, &stale), repository.ErrStale), "only one instance claims an occurrence")
+	list, err = repos.Schedules.ListDue(ctx, now, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	transfer := models.Transfer{
+		FromUserID: user.ID, ToUserID: other.ID, Amount: 10, Status: models.TransferSucceeded,
+		ScheduleID: &claimed.ID, ScheduledFor: &due, CreatedDate: now,
+	}
+	again := transfer
+	assert.NoError(t, repos.Transfers.Create(ctx, &transfer))
+	assert.True(t, errors.Is(repos.Transfers.Create(ctx, &again), repository.ErrDuplicate),
+		"an occurrence is made once")
+}
+
+func TestMoneyRequestsAreAnsweredOnceBeforeTheyExpire(t *testing.T) {
+	db := testutil.DB(t)
+	repos := repository.NewGormStore(db).Repositories()
+	requester := testutil.NewUser().Create(t, db)
+	payer := testutil.NewUser().Create(t, db)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	var requests []models.MoneyRequest
+	for i, created := range []time.Time{now.Add(-8 * 24 * time.Hour), now.Add(-time.Hour), now} {
+		request := models.MoneyRequest{
+			RequesterID: requester.ID, PayerID: payer.ID, Amount: float64(10 * (i + 1)), Remarks: "Lunch",
+			Status: models.RequestPending, ExpiresAt: created.Add(7 * 24 * time.Hour), CreatedDate: created,
+		}
+		if err := repos.Requests.Create(ctx, &request); err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		requests = append(requests, request)
+	}
+	expired, older, newest := requests[0], requests[1], requests[2]
+
+	recent, err := repos.Requests.ListByRequester(ctx, requester.ID, now.Add(-24*time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, recent, 2) {
+		assert.Equal(t, newest.ID, recent[0].ID, "newest first")
+	}
+	pending, err := repos.Requests.ListPendingByPayer(ctx, payer.ID, now)
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 2) {
+		assert.Equal(t, older.ID, pending[0].ID, "oldest first")
+	}
+
+	transfer := models.Transfer{FromUserID: payer.ID, ToUserID: requester.ID, Amount: 20, Status: models.TransferSucceeded, CreatedDate: now}
+	if err := repos.Transfers.Create(ctx, &transfer); err != nil {
+		t.Fatalf("Failed to create transfer: %v", err)
+	}
+	assert.NoError(t, repos.Requests.Respond(ctx, older.ID, models.RequestAccepted, now, &transfer.ID))
+	assert.True(t, errors.Is(repos.Requests.Respond(ctx, older.ID, models.RequestDeclined, now, nil), repository.ErrStale),
+		"a request is answered once")
+	assert.True(t, errors.Is(repos.Requests.Respond(ctx, expired.ID, models.RequestAccepted, now, nil), repository.ErrStale),
+		"expired requests cannot be answered")
+	accepted, err := repos.Requests.FindByID(ctx, older.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RequestAccepted, accepted.Status)
+	assert.Equal(t, &transfer.ID, accepted.TransferID)
+
+	all, err := repos.Requests.ListByUser(ctx, payer.ID)
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Replica is a read-only copy of the primary database.
+type Replica struct {
+	Name string
+	DB   *gorm.DB
+}
+
+type ReplicaOptions struct {
+	// MaxLag is the replication lag beyond which a replica stops serving
+	// reads until it catches up.
+	MaxLag time.Duration
+	// ReadYourWrites keeps a user's reads on the primary for this long after
+	// they changed something. Zero disables it.
+	ReadYourWrites time.Duration
+}
+
+// ReplicaStatus is the state of one replica as of the last check.
+type ReplicaStatus struct {
+	Name    string
+	Healthy bool
+	Lag     time.Duration
+}
+
+// Replicas routes reads that tolerate lag to healthy replicas, round robin,
+// and falls back to the primary when none is healthy.
+//
+// Lag is measured with a heartbeat: every Check reads the heartbeat row from
+// each replica, compares it with the one written to the primary by the
+// previous Check, then writes a new one. A replica is unhealthy until its
+// first measurement, so reads start on the primary. Recent writers are
+// tracked per process, so with several instances a user only reads their
+// own writes on the instance that made them.
+type Replicas struct {
+	primary  *gorm.DB
+	replicas []*replica
+	opts     ReplicaOptions
+	now      func() time.Time
+
+	mu       sync.RWMutex
+	next     int
+	lastBeat time.Time
+	// writes holds when each user last changed something.
+	writes map[uuid.UUID]time.Time
+}
+
+type replica struct {
+	Replica
+	healthy bool
+	lag     time.Duration
+}
+
+func NewReplicas(primary *gorm.DB, replicas []Replica, opts ReplicaOptions) *Replicas {
+	r := &Replicas{primary: primary, opts: opts, now: time.Now, writes: map[uuid.UUID]time.Time{}}
+	for _, rep := range replicas {
+		r.replicas = append(r.replicas, &replica{Replica: rep})
+	}
+	return r
+}
+
+// forRead returns the database to read userID's data from.
+func (r *Replicas) forRead(userID uuid.UUID) *gorm.DB {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if wrote, ok := r.writes[userID]; ok && r.now().Sub(wrote) < r.opts.ReadYourWrites {
+		return r.primary
+	}
+	for range r.replicas {
+		rep := r.replicas[r.next%len(r.replicas)]
+		r.next++
+		if rep.healthy {
+			return rep.DB
+		}
+	}
+	return r.primary
+}
+
+// wrote records that the users changed something just now.
+func (r *Replicas) wrote(userIDs ...uuid.UUID) {
+	if r.opts.ReadYourWrites <= 0 || len(userIDs) == 0 {
+		return
+	}
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range userIDs {
+		r.writes[id] = now
+	}
+}
+
+// Status reports every replica as of the last check.
+func (r *Replicas) Status() []ReplicaStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	statuses := make([]ReplicaStatus, len(r.replicas))
+	for i, rep := range r.replicas {
+		statuses[i] = ReplicaStatus{Name: rep.Name, Healthy: rep.healthy, Lag: rep.lag}
+	}
+	return statuses
+}
+
+// Check measures the lag of every replica, takes those lagging more than
+// MaxLag or failing to answer out of rotation and writes the next
+// heartbeat. It reports the replicas that could not be checked.
+func (r *Replicas) Check(ctx context.Context) error {
+	r.mu.RLock()
+	previous := r.lastBeat
+	r.mu.RUnlock()
+
+	var errs []error
+	for _, rep := range r.replicas {
+		lag, err := r.measure(ctx, rep, previous)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("replica %s: %w", rep.Name, err))
+		}
+		healthy := err == nil && !previous.IsZero() && lag <= r.opts.MaxLag
+
+		r.mu.Lock()
+		if rep.healthy != healthy {
+			if healthy {
+				slog.InfoContext(ctx, "replica back in rotation", "replica", rep.Name, "lag", lag)
+			} else {
+				slog.WarnContext(ctx, "replica out of rotation", "replica", rep.Name, "lag", lag, "error", err)
+			}
+		}
+		rep.healthy, rep.lag = healthy, lag
+		r.mu.Unlock()
+	}
+
+	beat := r.now().UTC().Truncate(time.Microsecond)
+	if err := r.primary.WithContext(ctx).
+		Exec(`UPDATE replication_heartbeat SET beat_at = ? WHERE id = 1`, beat).Error; err != nil {
+		errs = append(errs, fmt.Errorf("write heartbeat: %w", err))
+	} else {
+		r.mu.Lock()
+		r.lastBeat = beat
+		r.mu.Unlock()
+	}
+
+	r.forget()
+	return errors.Join(errs...)
+}
+
+// measure returns how far the heartbeat on rep is behind previous, the
+// heartbeat written by the last check.
+func (r *Replicas) measure(ctx context.Context, rep *replica, previous time.Time) (time.Duration, error) {
+	var beatAt time.Time
+	if err := rep.DB.WithContext(ctx).
+		Raw(`SELECT beat_at FROM replication_heartbeat WHERE id = 1`).Row().Scan(&beatAt); err != nil {
+		return 0, err
+	}
+	if previous.IsZero() || !beatAt.Before(previous) {
+		return 0, nil
+	}
+	return previous.Sub(beatAt), nil
+}
+
+// forget drops writers whose read-your-writes window has passed.
+func (r *Replicas) forget() {
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, wrote := range r.writes {
+		if now.Sub(wrote) >= r.opts.ReadYourWrites {
+			delete(r.writes, id)
+		}
+	}
+}
+
+// Run checks the replicas every interval until ctx is done.
+func (r *Replicas) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Check(ctx); err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "replica check failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"myapp/config"
+	"myapp/database"
+	"myapp/repository"
+	"myapp/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// openSQLite returns a migrated SQLite database in a file of its own, so a
+// test can play primary and replica with two of them.
+func openSQLite(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	db, err := database.Open(config.DatabaseConfig{
+		Driver: config.DriverSQLite,
+		Path:   filepath.Join(t.TempDir(), name+".db"),
+	}, &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", name, err)
+	}
+	migrator, err := database.Migrator(db)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// replicate copies the heartbeat from primary to replica, as replication
+// would.
+func replicate(t *testing.T, primary, replica *gorm.DB) {
+	t.Helper()
+	var beatAt time.Time
+	if err := primary.Raw(`SELECT beat_at FROM replication_heartbeat WHERE id = 1`).Row().Scan(&beatAt); err != nil {
+		t.Fatalf("Failed to read heartbeat: %v", err)
+	}
+	if err := replica.Exec(`UPDATE replication_heartbeat SET beat_at = ? WHERE id = 1`, beatAt).Error; err != nil {
+		t.Fatalf("Failed to copy heartbeat: %v", err)
+	}
+}
+
+func TestReadsGoToHealthyReplicas(t *testing.T) {
+	ctx := context.Background()
+	primary := openSQLite(t, "primary")
+	replica := openSQLite(t, "replica")
+
+	// The replica has a user the primary lacks, so reads show where they
+	// were served.
+	onlyOnReplica := testutil.NewUser().Create(t, replica)
+	shared := testutil.NewUser().WithBalance(1000).Build()
+	if err := primary.Create(&shared).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	stale := shared
+	stale.FirstName = "Stale"
+	if err := replica.Session(&gorm.Session{SkipHooks: true}).Create(&stale).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	routing := repository.NewReplicas(primary, []repository.Replica{{Name: "replica-1", DB: replica}},
+		repository.ReplicaOptions{MaxLag: 5 * time.Second, ReadYourWrites: time.Minute})
+	store := repository.NewGormStore(primary).WithReplicas(routing)
+
+	// Unmeasured replicas serve nothing
+	_, err := store.Reader(onlyOnReplica.ID).Users.FindByID(ctx, onlyOnReplica.ID)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	assert.NoError(t, routing.Check(ctx))
+	replicate(t, primary, replica)
+	assert.NoError(t, routing.Check(ctx))
+	if assert.Len(t, routing.Status(), 1) {
+		assert.True(t, routing.Status()[0].Healthy)
+		assert.Zero(t, routing.Status()[0].Lag)
+	}
+
+	_, err = store.Reader(onlyOnReplica.ID).Users.FindByID(ctx, onlyOnReplica.ID)
+	assert.NoError(t, err)
+	user, err := store.Reader(shared.ID).Users.FindByID(ctx, shared.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Stale", user.FirstName)
+
+	// Balance checks stay on the primary
+	user, err = store.Repositories().Users.FindByID(ctx, shared.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, shared.FirstName, user.FirstName)
+
+	// A user reads their own writes
+	err = store.Transaction(ctx, func(repos repository.Repositories) error {
+		_, err := repos.Users.AdjustBalance(ctx, shared.ID, -100)
+		return err
+	})
+	assert.NoError(t, err)
+	user, err = store.Reader(shared.ID).Users.FindByID(ctx, shared.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, shared.FirstName, user.FirstName)
+	assert.Equal(t, 900.0, user.Balance)
+	_, err = store.Reader(onlyOnReplica.ID).Users.FindByID(ctx, onlyOnReplica.ID)
+	assert.NoError(t, err, "other users still read the replica")
+
+	// A lagging replica is taken out of rotation
+	if err := replica.Exec(`UPDATE replication_heartbeat SET beat_at = ? WHERE id = 1`, time.Now().Add(-time.Hour)).Error; err != nil {
+		t.Fatalf("Failed to age heartbeat: %v", err)
+	}
+	assert.NoError(t, routing.Check(ctx))
+	if assert.Len(t, routing.Status(), 1) {
+		assert.False(t, routing.Status()[0].Healthy)
+		assert.Greater(t, routing.Status()[0].Lag, 59*time.Minute)
+	}
+	_, err = store.Reader(onlyOnReplica.ID).Users.FindByID(ctx, onlyOnReplica.ID)
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+}
+
+func TestUnreachableReplicaFallsBackToPrimary(t *testing.T) {
+	ctx := context.Background()
+	primary := openSQLite(t, "primary")
+	replica := openSQLite(t, "replica")
+	routing := repository.NewReplicas(primary, []repository.Replica{{Name: "replica-1", DB: replica}},
+		repository.ReplicaOptions{MaxLag: 5 * time.Second})
+	assert.NoError(t, routing.Check(ctx))
+	replicate(t, primary, replica)
+	assert.NoError(t, routing.Check(ctx))
+
+	sqlDB, err := replica.DB()
+	if err != nil {
+		t.Fatalf("Failed to access replica pool: %v", err)
+	}
+	sqlDB.Close()
+
+	assert.ErrorContains(t, routing.Check(ctx), "replica replica-1")
+	if assert.Len(t, routing.Status(), 1) {
+		assert.False(t, routing.Status()[0].Healthy)
+	}
+
+	user := testutil.NewUser().Create(t, primary)
+	store := repository.NewGormStore(primary).WithReplicas(routing)
+	_, err = store.Reader(user.ID).Users.FindByID(ctx, user.ID)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,192 @@
+// Package repository abstracts persistence of the wallet models. Services
+// depend on the interfaces here rather than on a database handle, so the
+// same business rules run behind HTTP handlers, the CLI or tests with fakes.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"myapp/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned when no record matches a lookup.
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a unique constraint is violated.
+	ErrDuplicate = errors.New("duplicate record")
+	// ErrStale is returned when a conditional update finds that the record
+	// has changed since it was read.
+	ErrStale = errors.New("record has been modified")
+	// ErrInsufficientFunds is returned when a debit would make a balance
+	// negative.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+type UserRepository interface {
+	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
+	// FindForUpdate is FindByID that also locks the user's row until the
+	// transaction ends, so that transactions doing the same for the user
+	// run one after another. SQLite has no row locks and reads under a
+	// shared lock, so two transactions could both read before either
+	// writes, and the second writer would fail with SQLITE_BUSY rather
+	// than wait; database.Open gives SQLite a single connection, which
+	// runs transactions one at a time instead.
+	FindForUpdate(ctx context.Context, id uuid.UUID) (models.User, error)
+	FindByPhone(ctx context.Context, phoneNumber string) (models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	// UpdateProfile writes changes only if the stored version still equals
+	// user.Version, then bumps the version on both the row and user.
+	UpdateProfile(ctx context.Context, user *models.User, changes map[string]interface{}) error
+	// AdjustBalance adds delta to the balance in a single statement, refusing
+	// to take it below zero, and returns the updated user.
+	AdjustBalance(ctx context.Context, id uuid.UUID, delta float64) (models.User, error)
+	// SetFrozenAt freezes the account at the given time, or unfreezes it
+	// when frozenAt is nil, and returns the updated user.
+	SetFrozenAt(ctx context.Context, id uuid.UUID, frozenAt *time.Time) (models.User, error)
+}
+
+// BalanceRepository keeps the balances in currencies other than
+// models.DefaultCurrency, which is User.Balance.
+type BalanceRepository interface {
+	// Adjust adds delta to the user's balance in currency, opening it at
+	// zero when needed and refusing to take it below zero, and returns the
+	// updated balance.
+	Adjust(ctx context.Context, userID uuid.UUID, currency string, delta float64) (models.Balance, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Balance, error)
+}
+
+type QuoteRepository interface {
+	Create(ctx context.Context, quote *models.Quote) error
+	FindByID(ctx context.Context, id uuid.UUID) (models.Quote, error)
+	// MarkUsed records that the quote was used at usedAt, failing with
+	// ErrStale when it already had been.
+	MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+type PocketRepository interface {
+	Create(ctx context.Context, pocket *models.Pocket) error
+	FindByID(ctx context.Context, id uuid.UUID) (models.Pocket, error)
+	// ListByUser returns the user's open pockets, oldest first.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Pocket, error)
+	// Rename renames an open pocket and returns it.
+	Rename(ctx context.Context, id uuid.UUID, name string) (models.Pocket, error)
+	// AdjustBalance adds delta to the balance of an open pocket in a single
+	// statement, refusing to take it below zero, and returns the updated
+	// pocket.
+	AdjustBalance(ctx context.Context, id uuid.UUID, delta float64) (models.Pocket, error)
+	// Close closes an open pocket at closedAt, failing with ErrStale unless
+	// it is empty, and returns it.
+	Close(ctx context.Context, id uuid.UUID, closedAt time.Time) (models.Pocket, error)
+}
+
+type PocketMovementRepository interface {
+	Create(ctx context.Context, movement *models.PocketMovement) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.PocketMovement, error)
+}
+
+type TopUpRepository interface {
+	Create(ctx context.Context, topUp *models.TopUp) error
+	FindByID(ctx context.Context, id uuid.UUID) (models.TopUp, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.TopUp, error)
+	// MarkReversed records that the top-up was reversed at reversedAt,
+	// failing with ErrStale when it already had been.
+	MarkReversed(ctx context.Context, id uuid.UUID, reversedAt time.Time) error
+}
+
+type PaymentRepository interface {
+	Create(ctx context.Context, payment *models.Payment) error
+	FindByID(ctx context.Context, id uuid.UUID) (models.Payment, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Payment, error)
+	// AddRefund adds amount to what has been refunded of the payment in a
+	// single statement, refusing to refund more than was paid, and returns
+	// the updated payment.
+	AddRefund(ctx context.Context, id uuid.UUID, amount float64) (models.Payment, error)
+}
+
+type TransferRepository interface {
+	Create(ctx context.Context, transfer *models.Transfer) error
+	FindByID(ctx context.Context, id uuid.UUID) (models.Transfer, error)
+	ListBySender(ctx context.Context, userID uuid.UUID) ([]models.Transfer, error)
+	ListByRecipient(ctx context.Context, userID uuid.UUID) ([]models.Transfer, error)
+	// MarkReversed records that the transfer was reversed at reversedAt,
+	// failing with ErrStale unless it had succeeded and was not reversed
+	// yet.
+	MarkReversed(ctx context.Context, id uuid.UUID, reversedAt time.Time) error
+}
+
+type ReversalRepository interface {
+	Create(ctx context.Context, reversal *models.Reversal) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Reversal, error)
+	ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]models.Reversal, error)
+}
+
+type ScheduledTransferRepository interface {
+	Create(ctx context.Context, schedule *models.ScheduledTransfer) error
+	FindByID(ctx context.Context, id uuid.UUID) (models.ScheduledTransfer, error)
+	// ListByUser returns the user's schedules that were not cancelled,
+	// oldest first.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.ScheduledTransfer, error)
+	// ListDue returns up to limit active schedules whose next attempt is
+	// due at now, the longest overdue first.
+	ListDue(ctx context.Context, now time.Time, limit int) ([]models.ScheduledTransfer, error)
+	// Update writes the schedule only if the stored version still equals
+	// schedule.Version, then bumps the version on both the row and
+	// schedule.
+	Update(ctx context.Context, schedule *models.ScheduledTransfer) error
+}
+
+type MoneyRequestRepository interface {
+	Create(ctx context.Context, request *models.MoneyRequest) error
+	FindByID(ctx context.Context, id uuid.UUID) (models.MoneyRequest, error)
+	// ListByRequester returns the requests the user made since since,
+	// newest first.
+	ListByRequester(ctx context.Context, requesterID uuid.UUID, since time.Time) ([]models.MoneyRequest, error)
+	// ListPendingByPayer returns the requests to the user that are still
+	// pending and unexpired at now, oldest first.
+	ListPendingByPayer(ctx context.Context, payerID uuid.UUID, now time.Time) ([]models.MoneyRequest, error)
+	// ListByUser returns the requests the user made or was asked to pay.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.MoneyRequest, error)
+	// Respond closes the request with status at respondedAt, naming the
+	// transfer that paid it if any, failing with ErrStale unless it was
+	// pending and unexpired then.
+	Respond(ctx context.Context, id uuid.UUID, status string, respondedAt time.Time, transferID *uuid.UUID) error
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, log *models.AuditLog) error
+	// ListByUser returns the latest entries about a user, newest first.
+	ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]models.AuditLog, error)
+}
+
+// Repositories bundles the repositories of one unit of work.
+type Repositories struct {
+	Users     UserRepository
+	Balances  BalanceRepository
+	Quotes    QuoteRepository
+	Pockets   PocketRepository
+	Movements PocketMovementRepository
+	TopUps    TopUpRepository
+	Payments  PaymentRepository
+	Transfers TransferRepository
+	Reversals ReversalRepository
+	Schedules ScheduledTransferRepository
+	Requests  MoneyRequestRepository
+	Audit     AuditRepository
+}
+
+// Store hands out repositories and runs work atomically.
+type Store interface {
+	Repositories() Repositories
+	// Reader returns repositories for reads about userID that tolerate
+	// replication lag, such as histories and profiles. They may be served
+	// by a read replica, so they must not be used for writes or for
+	// reads that decide one, such as balance checks.
+	Reader(userID uuid.UUID) Repositories
+	// Transaction runs fn with repositories bound to one database
+	// transaction, committed when fn returns nil and rolled back otherwise.
+	Transaction(ctx context.Context, fn func(repos Repositories) error) error
+}
@@ -1,21 +1,105 @@
 package routers
 
 import (
+	"myapp/background"
 	"myapp/controllers"
+	"myapp/fx"
+	"myapp/health"
+	"myapp/metrics"
+	"myapp/middleware"
+	"myapp/notification"
+	"myapp/ratelimit"
+	"myapp/render"
+	"myapp/repository"
+	"myapp/services"
+	"myapp/tracing"
+	"myapp/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter() *gin.Engine {
-	r := gin.Default()
+// Options carries the dependencies SetupRouter wires into the handlers.
+type Options struct {
+	Store    repository.Store
+	Notifier notification.Notifier
+	// Workers runs background transfers; the caller drains it on shutdown.
+	Workers *background.Group
+	Probes  *health.Health
+	// Limiter may be nil to disable rate limiting.
+	Limiter *ratelimit.Limiter
+	// Exchange may be nil to keep wallets in the default currency only.
+	Exchange *fx.Exchange
+	// Merchants maps the API key of every merchant to its code. Without
+	// any, merchants cannot refund payments.
+	Merchants map[string]string
+}
+
+func SetupRouter(opts Options) *gin.Engine {
+	validation.Register()
+
+	users := services.NewUserService(opts.Store)
+	wallet := services.NewWalletService(opts.Store, opts.Notifier)
+	if opts.Exchange != nil {
+		wallet = wallet.WithExchange(opts.Exchange)
+	}
+	userController := controllers.NewUserController(users, wallet, opts.Workers)
+	profileController := controllers.NewProfileController(users)
+	pocketController := controllers.NewPocketController(wallet)
+	scheduleController := controllers.NewScheduleController(wallet)
+	requestController := controllers.NewRequestController(wallet, opts.Workers)
+	merchantController := controllers.NewMerchantController(wallet, opts.Merchants)
+
+	r := gin.New()
+	r.Use(
+		tracing.Middleware(),
+		middleware.RequestID(),
+		middleware.Identify(),
+		middleware.AccessLog(),
+		render.Recover(),
+		middleware.Locale(),
+		metrics.Middleware(),
+	)
+
+	r.GET("/healthz", opts.Probes.Liveness)
+	r.GET("/readyz", opts.Probes.Readiness)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	limiter := opts.Limiter
+	register := limiter.Limit("register", ratelimit.ByIP)
+	login := limiter.Limit("login", ratelimit.ByIP)
+	walletLimit := limiter.Limit("wallet", ratelimit.ByUser)
+	limited := limiter.Limit("default", ratelimit.ByUser)
+	// Merchants have no user token, so they are limited by address.
+	merchantLimit := limiter.Limit("wallet", ratelimit.ByIP)
 
-	r.POST("/register", controllers.Register)
-	r.POST("/login", controllers.Login)
-	r.POST("/topup", controllers.TopUp)
-	r.POST("/pay", controllers.Payment)
-	r.POST("/transfer", controllers.Transfer)
-	r.GET("/transactions", controllers.Transactions)
-	r.PUT("/profile", controllers.UpdateProfile)
+	r.POST("/register", register, userController.Register)
+	r.POST("/login", login, userController.Login)
+	r.POST("/topup", metrics.WalletOperation(metrics.OperationTopUp), walletLimit, userController.TopUp)
+	r.POST("/pay", metrics.WalletOperation(metrics.OperationPayment), walletLimit, userController.Payment)
+	r.POST("/transfer", metrics.WalletOperation(metrics.OperationTransfer), walletLimit, userController.Transfer)
+	r.GET("/transactions", limited, userController.Transactions)
+	r.GET("/balances", limited, userController.Balances)
+	r.POST("/quotes", limited, userController.Quote)
+	r.GET("/pockets", limited, pocketController.List)
+	r.POST("/pockets", limited, pocketController.Create)
+	r.POST("/pockets/move", walletLimit, pocketController.Move)
+	r.PUT("/pockets/:id", limited, pocketController.Rename)
+	r.DELETE("/pockets/:id", walletLimit, pocketController.Close)
+	r.GET("/scheduled-transfers", limited, scheduleController.List)
+	r.POST("/scheduled-transfers", limited, scheduleController.Create)
+	r.GET("/scheduled-transfers/:id", limited, scheduleController.Get)
+	r.PUT("/scheduled-transfers/:id", limited, scheduleController.Update)
+	r.DELETE("/scheduled-transfers/:id", limited, scheduleController.Cancel)
+	r.POST("/money-requests", limited, requestController.Create)
+	r.GET("/money-requests/incoming", limited, requestController.Incoming)
+	r.GET("/money-requests/outgoing", limited, requestController.Outgoing)
+	r.POST("/money-requests/:id/accept", metrics.WalletOperation(metrics.OperationTransfer), walletLimit, requestController.Accept)
+	r.POST("/money-requests/:id/decline", limited, requestController.Decline)
+	r.DELETE("/money-requests/:id", limited, requestController.Cancel)
+	r.GET("/profile", limited, profileController.GetProfile)
+	r.PUT("/profile", limited, profileController.UpdateProfile)
+	r.PATCH("/profile", limited, profileController.PatchProfile)
+	r.POST("/merchant/payments/:id/refunds", metrics.WalletOperation(metrics.OperationRefund), merchantLimit, merchantController.Refund)
 
 	return r
 }
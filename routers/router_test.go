@@ -0,0 +1,501 @@
+package routers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"myapp/background"
+	"myapp/fx"
+	"myapp/health"
+	"myapp/models"
+	"myapp/notification"
+	"myapp/ratelimit"
+	"myapp/services"
+	"myapp/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// api drives the complete router against the test database.
+type api struct {
+	t      *testing.T
+	router *gin.Engine
+}
+
+func newAPI(t *testing.T, limiter *ratelimit.Limiter) *api {
+	return newAPIWith(t, Options{Limiter: limiter})
+}
+
+// newAPIWith uses the optional dependencies set in opts.
+func newAPIWith(t *testing.T, opts Options) *api {
+	gin.SetMode(gin.TestMode)
+	opts.Store = testutil.Store(t)
+	opts.Notifier = notification.LogNotifier{}
+	opts.Workers = &background.Group{}
+	opts.Probes = health.New(time.Second)
+	return &api{t: t, router: SetupRouter(opts)}
+}
+
+type apiResponse struct {
+	*httptest.ResponseRecorder
+	Body map[string]interface{}
+}
+
+func (a *api) do(method, path, token, body string, headers ...string) apiResponse {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "en")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, req)
+
+	response := apiResponse{ResponseRecorder: w}
+	_ = json.Unmarshal(w.Body.Bytes(), &response.Body)
+	return response
+}
+
+func (r apiResponse) result() map[string]interface{} {
+	result, _ := r.Body["result"].(map[string]interface{})
+	return result
+}
+
+func (r apiResponse) errorCode() string {
+	detail, _ := r.Body["error"].(map[string]interface{})
+	code, _ := detail["code"].(string)
+	return code
+}
+
+func balanceOf(t *testing.T, user models.User) float64 {
+	var stored models.User
+	if err := testutil.DB(t).First(&stored, "id = ?", user.ID).Error; err != nil {
+		t.Fatalf("Failed to reload user: %v", err)
+	}
+	return stored.Balance
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	a := newAPI(t, nil)
+	body := `{"first_name":"Budi","last_name":"Santoso","phone_number":"+62811255501","address":"Jl. Merdeka 1","pin":"123456"}`
+
+	w := a.do(http.MethodPost, "/register", "", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Budi", w.result()["first_name"])
+	assert.NotContains(t, w.Body, "pin")
+
+	w = a.do(http.MethodPost, "/register", "", body)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "DUPLICATE_PHONE", w.errorCode())
+
+	w = a.do(http.MethodPost, "/login", "", `{"phone_number":"+62811255501","pin":"123456"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.result()["access_token"])
+
+	w = a.do(http.MethodPost, "/login", "", `{"phone_number":"+62811255501","pin":"654321"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "INVALID_CREDENTIALS", w.errorCode())
+}
+
+func TestWalletOperations(t *testing.T) {
+	a := newAPI(t, nil)
+	db := testutil.DB(t)
+	sender := testutil.NewUser().WithBalance(100000).Create(t, db)
+	receiver := testutil.NewUser().Create(t, db)
+	token := testutil.Token(t, sender)
+
+	w := a.do(http.MethodPost, "/topup", token, `{"amount":50000}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 150000.0, w.result()["balance_after"])
+
+	w = a.do(http.MethodPost, "/pay", token, `{"amount":20000,"remarks":"Pulsa"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 130000.0, w.result()["balance_after"])
+
+	w = a.do(http.MethodPost, "/pay", token, `{"amount":9000000,"remarks":"Motor"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "INSUFFICIENT_BALANCE", w.errorCode())
+
+	w = a.do(http.MethodPost, "/transfer", token, `{"target_user":"`+receiver.ID.String()+`","amount":30000,"remarks":"Arisan"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 100000.0, w.result()["balance_after"])
+
+	assert.Equal(t, 100000.0, balanceOf(t, sender))
+	assert.Equal(t, 30000.0, balanceOf(t, receiver))
+
+	w = a.do(http.MethodGet, "/transactions", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, w.Body["result"], 3)
+
+	w = a.do(http.MethodGet, "/transactions?format=csv", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, strings.Split(strings.TrimSpace(w.ResponseRecorder.Body.String()), "\n"), 4)
+}
+
+func TestMultiCurrencyWallet(t *testing.T) {
+	provider := fx.NewStaticProvider("IDR", map[string]float64{"USD": 16000}, time.Time{})
+	a := newAPIWith(t, Options{Exchange: fx.NewExchange(provider, 0.01, time.Minute)})
+	db := testutil.DB(t)
+	sender := testutil.NewUser().WithBalance(100000).Create(t, db)
+	receiver := testutil.NewUser().Create(t, db)
+	token := testutil.Token(t, sender)
+
+	w := a.do(http.MethodPost, "/topup", token, `{"amount":20,"currency":"USD"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "USD", w.result()["currency"])
+	assert.Equal(t, 20.0, w.result()["balance_after"])
+
+	w = a.do(http.MethodPost, "/topup", token, `{"amount":20,"currency":"GBP"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "UNSUPPORTED_CURRENCY", w.errorCode())
+
+	w = a.do(http.MethodPost, "/quotes", token, `{"from_currency":"USD","to_currency":"IDR","amount":10}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 15840.0, w.result()["rate"])
+	assert.Equal(t, 158400.0, w.result()["converted_amount"])
+	quoteID, _ := w.result()["quote_id"].(string)
+
+	w = a.do(http.MethodPost, "/transfer", token, `{"target_user":"`+receiver.ID.String()+`","amount":10,"currency":"USD","to_currency":"IDR","quote_id":"`+quoteID+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 10.0, w.result()["balance_after"])
+	assert.Equal(t, 158400.0, w.result()["converted_amount"])
+	assert.Equal(t, 158400.0, balanceOf(t, receiver))
+
+	w = a.do(http.MethodPost, "/transfer", token, `{"target_user":"`+receiver.ID.String()+`","amount":1,"currency":"USD","to_currency":"IDR","quote_id":"`+quoteID+`"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "QUOTE_EXPIRED", w.errorCode())
+
+	w = a.do(http.MethodGet, "/balances", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"currency": "IDR", "balance": 100000.0},
+		map[string]interface{}{"currency": "USD", "balance": 10.0},
+	}, w.Body["result"])
+
+	w = a.do(http.MethodGet, "/transactions", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	for _, entry := range w.Body["result"].([]interface{}) {
+		entry := entry.(map[string]interface{})
+		if entry["transfer_id"] != nil {
+			assert.Equal(t, 10.0, entry["amount"])
+			assert.Equal(t, "USD", entry["currency"])
+			assert.Equal(t, 158400.0, entry["converted_amount"])
+			assert.Equal(t, "IDR", entry["converted_currency"])
+		}
+	}
+}
+
+func TestPockets(t *testing.T) {
+	a := newAPI(t, nil)
+	db := testutil.DB(t)
+	user := testutil.NewUser().WithBalance(100000).Create(t, db)
+	token := testutil.Token(t, user)
+
+	w := a.do(http.MethodPost, "/pockets", token, `{"name":"Savings"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "IDR", w.result()["currency"])
+	pocketID, _ := w.result()["pocket_id"].(string)
+
+	w = a.do(http.MethodPost, "/pockets", token, `{"name":"SAVINGS"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "DUPLICATE_POCKET", w.errorCode())
+
+	w = a.do(http.MethodPost, "/topup", token, `{"amount":5000,"pocket_id":"`+pocketID+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, pocketID, w.result()["pocket_id"])
+	assert.Equal(t, 5000.0, w.result()["balance_after"])
+
+	w = a.do(http.MethodPost, "/pockets/move", token, `{"to_pocket_id":"`+pocketID+`","amount":20000}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 80000.0, w.result()["balance_after"])
+	assert.Equal(t, 80000.0, balanceOf(t, user))
+
+	w = a.do(http.MethodPost, "/pay", token, `{"amount":30000,"pocket_id":"`+pocketID+`","remarks":"Shoes"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "INSUFFICIENT_BALANCE", w.errorCode())
+
+	w = a.do(http.MethodPut, "/pockets/"+pocketID, token, `{"name":"Rainy day"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Rainy day", w.result()["name"])
+
+	w = a.do(http.MethodGet, "/pockets", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	if pockets, ok := w.Body["result"].([]interface{}); assert.True(t, ok) && assert.Len(t, pockets, 1) {
+		assert.Equal(t, 25000.0, pockets[0].(map[string]interface{})["balance"])
+	}
+
+	w = a.do(http.MethodGet, "/transactions", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	moves := 0
+	for _, entry := range w.Body["result"].([]interface{}) {
+		entry := entry.(map[string]interface{})
+		if entry["movement_id"] != nil {
+			moves++
+			assert.Equal(t, "MOVE", entry["transaction_type"])
+			assert.Equal(t, pocketID, entry["to_pocket_id"])
+		}
+	}
+	assert.Equal(t, 1, moves)
+
+	w = a.do(http.MethodDelete, "/pockets/"+pocketID, token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, w.result()["movement"])
+	assert.Equal(t, 105000.0, balanceOf(t, user), "closing returns the money to the main balance")
+
+	w = a.do(http.MethodDelete, "/pockets/"+pocketID, token, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "POCKET_NOT_FOUND", w.errorCode())
+	w = a.do(http.MethodPut, "/pockets/not-a-pocket", token, `{"name":"x"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMerchantRefunds(t *testing.T) {
+	a := newAPIWith(t, Options{Merchants: map[string]string{"toko-key": "TOKO", "warung-key": "WARUNG"}})
+	db := testutil.DB(t)
+	user := testutil.NewUser().WithBalance(100000).Create(t, db)
+	token := testutil.Token(t, user)
+
+	w := a.do(http.MethodPost, "/pay", token, `{"amount":30000,"merchant":"TOKO","remarks":"Shoes"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	refunds := "/merchant/payments/" + w.result()["payment_id"].(string) + "/refunds"
+
+	w = a.do(http.MethodPost, refunds, "", `{"amount":10000}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = a.do(http.MethodPost, refunds, "", `{"amount":10000}`, "X-Merchant-Key", "warung-key")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "PAYMENT_NOT_FOUND", w.errorCode())
+
+	w = a.do(http.MethodPost, refunds, "", `{"amount":10000,"reason":"Wrong size"}`, "X-Merchant-Key", "toko-key")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 10000.0, w.result()["refunded_amount"])
+	assert.Equal(t, 20000.0, w.result()["remaining_amount"])
+	w = a.do(http.MethodPost, refunds, "", `{"amount":25000}`, "X-Merchant-Key", "toko-key")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "REFUND_EXCEEDS_PAYMENT", w.errorCode())
+	assert.Equal(t, 80000.0, balanceOf(t, user))
+
+	w = a.do(http.MethodGet, "/transactions", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var statuses []string
+	for _, entry := range w.Body["result"].([]interface{}) {
+		entry := entry.(map[string]interface{})
+		statuses = append(statuses, entry["transaction_type"].(string)+" "+entry["status"].(string))
+	}
+	assert.ElementsMatch(t, []string{"DEBIT PARTIALLY_REFUNDED", "CREDIT SUCCESS"}, statuses)
+}
+
+func TestScheduledTransfers(t *testing.T) {
+	a := newAPI(t, nil)
+	db := testutil.DB(t)
+	sender := testutil.NewUser().WithBalance(100000).Create(t, db)
+	recipient := testutil.NewUser().Create(t, db)
+	token := testutil.Token(t, sender)
+
+	w := a.do(http.MethodPost, "/scheduled-transfers", token,
+		`{"target_user":"`+recipient.ID.String()+`","amount":10000,"frequency":"CRON","cron":"0 0 31 2 *"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "VALIDATION_FAILED", w.errorCode())
+
+	w = a.do(http.MethodPost, "/scheduled-transfers", token,
+		`{"target_user":"`+recipient.ID.String()+`","amount":10000,"frequency":"DAILY","remarks":"Allowance"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ACTIVE", w.result()["status"])
+	scheduleID, _ := w.result()["schedule_id"].(string)
+
+	scheduler := services.NewScheduler(services.NewWalletService(testutil.Store(t), notification.LogNotifier{}),
+		services.SchedulerOptions{Interval: time.Minute, RetryDelay: time.Hour, MaxAttempts: 3, BatchSize: 10})
+	for i := 0; i < 2; i++ {
+		if _, err := scheduler.RunDue(context.Background()); err != nil {
+			t.Fatalf("Failed to run scheduled transfers: %v", err)
+		}
+	}
+	assert.Equal(t, 90000.0, balanceOf(t, sender), "the occurrence is made once")
+
+	w = a.do(http.MethodGet, "/scheduled-transfers/"+scheduleID, token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.result()["last_run_at"])
+	w = a.do(http.MethodGet, "/transactions", token, "")
+	if entries, ok := w.Body["result"].([]interface{}); assert.True(t, ok) && assert.Len(t, entries, 1) {
+		assert.Equal(t, scheduleID, entries[0].(map[string]interface{})["schedule_id"])
+	}
+
+	w = a.do(http.MethodPut, "/scheduled-transfers/"+scheduleID, token,
+		`{"target_user":"`+recipient.ID.String()+`","amount":5000,"frequency":"WEEKLY","start_at":"2030-01-07T09:00:00Z","paused":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "PAUSED", w.result()["status"])
+	assert.Equal(t, "2030-01-07 09:00:00", w.result()["next_run_at"])
+
+	w = a.do(http.MethodGet, "/scheduled-transfers/"+scheduleID, testutil.Token(t, recipient), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "SCHEDULED_TRANSFER_NOT_FOUND", w.errorCode())
+
+	w = a.do(http.MethodDelete, "/scheduled-transfers/"+scheduleID, token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "CANCELLED", w.result()["status"])
+	w = a.do(http.MethodGet, "/scheduled-transfers", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body["result"])
+}
+
+func TestMoneyRequests(t *testing.T) {
+	a := newAPI(t, nil)
+	db := testutil.DB(t)
+	requester := testutil.NewUser().Create(t, db)
+	payer := testutil.NewUser().WithBalance(100000).Create(t, db)
+	token, payerToken := testutil.Token(t, requester), testutil.Token(t, payer)
+
+	w := a.do(http.MethodPost, "/money-requests", token, `{"target_user":"`+payer.ID.String()+`","amount":25000}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "VALIDATION_FAILED", w.errorCode(), "requests carry remarks")
+
+	w = a.do(http.MethodPost, "/money-requests", token,
+		`{"target_user":"`+payer.ID.String()+`","amount":25000,"remarks":"Concert ticket"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "PENDING", w.result()["status"])
+	requestID, _ := w.result()["request_id"].(string)
+	w = a.do(http.MethodPost, "/money-requests", token,
+		`{"target_user":"`+payer.ID.String()+`","amount":25000,"remarks":"Concert ticket"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "DUPLICATE_MONEY_REQUEST", w.errorCode())
+
+	w = a.do(http.MethodGet, "/money-requests/incoming", payerToken, "")
+	if incoming, ok := w.Body["result"].([]interface{}); assert.True(t, ok) && assert.Len(t, incoming, 1) {
+		assert.Equal(t, requestID, incoming[0].(map[string]interface{})["request_id"])
+	}
+	w = a.do(http.MethodPost, "/money-requests/"+requestID+"/accept", token, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "MONEY_REQUEST_NOT_FOUND", w.errorCode())
+
+	w = a.do(http.MethodPost, "/money-requests/"+requestID+"/accept", payerToken, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ACCEPTED", w.result()["status"])
+	assert.NotEmpty(t, w.result()["transfer_id"])
+	assert.Equal(t, 25000.0, balanceOf(t, requester))
+	assert.Equal(t, 75000.0, balanceOf(t, payer))
+	w = a.do(http.MethodPost, "/money-requests/"+requestID+"/decline", payerToken, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "MONEY_REQUEST_CLOSED", w.errorCode())
+
+	w = a.do(http.MethodGet, "/transactions", token, "")
+	if entries, ok := w.Body["result"].([]interface{}); assert.True(t, ok) && assert.Len(t, entries, 2, "the received transfer and the request") {
+		types := map[string]interface{}{}
+		for _, entry := range entries {
+			entry := entry.(map[string]interface{})
+			types[entry["transaction_type"].(string)] = entry["counterparty_id"]
+		}
+		assert.Equal(t, map[string]interface{}{
+			"CREDIT":       payer.ID.String(),
+			"REQUEST_SENT": payer.ID.String(),
+		}, types)
+	}
+	w = a.do(http.MethodGet, "/transactions", payerToken, "")
+	if entries, ok := w.Body["result"].([]interface{}); assert.True(t, ok) {
+		assert.Len(t, entries, 2, "the transfer and the request")
+	}
+
+	w = a.do(http.MethodPost, "/money-requests", token,
+		`{"target_user":"`+payer.ID.String()+`","amount":5000,"remarks":"Parking"}`)
+	requestID, _ = w.result()["request_id"].(string)
+	w = a.do(http.MethodDelete, "/money-requests/"+requestID, token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "CANCELLED", w.result()["status"])
+	w = a.do(http.MethodGet, "/money-requests/outgoing", token, "")
+	if outgoing, ok := w.Body["result"].([]interface{}); assert.True(t, ok) {
+		assert.Len(t, outgoing, 2)
+	}
+}
+
+func TestWalletRejectsBadInput(t *testing.T) {
+	a := newAPI(t, nil)
+	user := testutil.NewUser().WithBalance(1000).Create(t, testutil.DB(t))
+	token := testutil.Token(t, user)
+
+	w := a.do(http.MethodPost, "/pay", token, `{"amount":-10}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "VALIDATION_FAILED", w.errorCode())
+
+	w = a.do(http.MethodPost, "/transfer", token, `{"target_user":"`+user.ID.String()+`","amount":10}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "VALIDATION_FAILED", w.errorCode())
+
+	w = a.do(http.MethodPost, "/transfer", token, `{"target_user":"00000000-0000-0000-0000-000000000001","amount":10}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "TARGET_USER_NOT_FOUND", w.errorCode())
+
+	w = a.do(http.MethodPost, "/topup", "not-a-token", `{"amount":10}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	assert.Equal(t, 1000.0, balanceOf(t, user))
+}
+
+func TestProfileConcurrency(t *testing.T) {
+	a := newAPI(t, nil)
+	user := testutil.NewUser().WithName("Budi", "Santoso").Create(t, testutil.DB(t))
+	token := testutil.Token(t, user)
+
+	w := a.do(http.MethodGet, "/profile", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	w = a.do(http.MethodPatch, "/profile", token, `{"first_name":"Budiman"}`)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	w = a.do(http.MethodPatch, "/profile", token, `{"first_name":"Budiman","address":null}`, "If-Match", etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Budiman", w.result()["first_name"])
+	assert.Equal(t, "Santoso", w.result()["last_name"])
+	assert.Equal(t, "", w.result()["address"])
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	w = a.do(http.MethodPatch, "/profile", token, `{"last_name":"Wijaya"}`, "If-Match", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, "VERSION_CONFLICT", w.errorCode())
+}
+
+func TestFrozenAccount(t *testing.T) {
+	a := newAPI(t, nil)
+	db := testutil.DB(t)
+	user := testutil.NewUser().WithBalance(1000).Create(t, db)
+	token := testutil.Token(t, user)
+	if err := db.Model(&user).Update("frozen_at", time.Now()).Error; err != nil {
+		t.Fatalf("Failed to freeze user: %v", err)
+	}
+
+	w := a.do(http.MethodPost, "/login", "", `{"phone_number":"`+user.PhoneNumber+`","pin":"`+testutil.DefaultPIN+`"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "ACCOUNT_FROZEN", w.errorCode())
+
+	// Tokens issued before the freeze cannot move money either
+	w = a.do(http.MethodPost, "/pay", token, `{"amount":100,"remarks":"Pulsa"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, 1000.0, balanceOf(t, user))
+}
+
+func TestLoginIsRateLimited(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), map[string]ratelimit.Policy{
+		"login":    {Name: "login", Limit: 2, Period: time.Minute},
+		"register": {Name: "register", Limit: 2, Period: time.Minute},
+		"wallet":   {Name: "wallet", Limit: 2, Period: time.Minute},
+		"default":  {Name: "default", Limit: 2, Period: time.Minute},
+	})
+	a := newAPI(t, limiter)
+
+	body := `{"phone_number":"+62811255501","pin":"123456"}`
+	a.do(http.MethodPost, "/login", "", body)
+	a.do(http.MethodPost, "/login", "", body)
+	w := a.do(http.MethodPost, "/login", "", body)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,134 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronExpression is a standard five-field cron expression: minute, hour,
+// day of month, month and day of week (0 or 7 is Sunday). Fields accept *,
+// numbers, ranges a-b, steps */n or a-b/n, and comma-separated lists of
+// these. As in Vixie cron, when both day fields are restricted a day
+// matching either is due.
+type CronExpression struct {
+	minutes, hours, days, months, weekdays bits
+	anyDay, anyWeekday                     bool
+}
+
+// bits has bit n set when the value n matches.
+type bits uint64
+
+func (b bits) has(n int) bool {
+	return b&(1<<uint(n)) != 0
+}
+
+// horizon is how far Next looks ahead before giving up.
+const horizon = 5 * 366 * 24 * time.Hour
+
+// ParseCron parses a five-field cron expression.
+func ParseCron(expression string) (CronExpression, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return CronExpression{}, fmt.Errorf("schedule: cron expression needs 5 fields, got %d", len(fields))
+	}
+
+	var expr CronExpression
+	var err error
+	for i, spec := range []struct {
+		into     *bits
+		min, max int
+	}{
+		{&expr.minutes, 0, 59},
+		{&expr.hours, 0, 23},
+		{&expr.days, 1, 31},
+		{&expr.months, 1, 12},
+		{&expr.weekdays, 0, 7},
+	} {
+		if *spec.into, err = parseField(fields[i], spec.min, spec.max); err != nil {
+			return CronExpression{}, fmt.Errorf("schedule: cron field %d: %w", i+1, err)
+		}
+	}
+	if expr.weekdays.has(7) {
+		expr.weekdays |= 1
+	}
+	expr.anyDay = fields[2] == "*"
+	expr.anyWeekday = fields[4] == "*"
+	return expr, nil
+}
+
+func parseField(field string, min, max int) (bits, error) {
+	var matched bits
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart = part[:i]
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value in %q", part)
+				}
+			} else if step > 1 {
+				// 5/15 means from 5 to the end in steps of 15.
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for n := low; n <= high; n += step {
+			matched |= 1 << uint(n)
+		}
+	}
+	return matched, nil
+}
+
+// Next returns the first whole minute after after that the expression
+// matches, or the zero time when there is none within five years.
+func (c CronExpression) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(horizon)
+	for t.Before(limit) {
+		switch {
+		case !c.months.has(int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !c.hours.has(t.Hour()):
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case !c.minutes.has(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c CronExpression) dayMatches(t time.Time) bool {
+	day := c.days.has(t.Day())
+	weekday := c.weekdays.has(int(t.Weekday()))
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
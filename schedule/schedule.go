@@ -0,0 +1,110 @@
+// Package schedule computes when recurring work is due: once, daily, weekly,
+// monthly, or by a cron expression. Times are in UTC.
+package schedule
+
+import (
+	"errors"
+	"time"
+)
+
+// Frequencies of a Rule.
+const (
+	Once    = "ONCE"
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Cron    = "CRON"
+)
+
+var (
+	// ErrUnknownFrequency is returned for a frequency that is not one of
+	// the constants above.
+	ErrUnknownFrequency = errors.New("schedule: unknown frequency")
+	// ErrNeverDue is returned for a rule that has no occurrence, such as a
+	// cron expression for the 30th of February.
+	ErrNeverDue = errors.New("schedule: never due")
+)
+
+// Rule says when something is due. Daily, weekly and monthly occurrences
+// fall at the time of day of Start; monthly ones on its day of the month, or
+// the last day of shorter months. Cron occurrences are those of Expression
+// from Start on.
+type Rule struct {
+	Frequency  string
+	Expression string
+	Start      time.Time
+}
+
+// Validate reports whether the rule is well-formed and ever due.
+func (r Rule) Validate() error {
+	switch r.Frequency {
+	case Once, Daily, Weekly, Monthly:
+		return nil
+	case Cron:
+		expr, err := ParseCron(r.Expression)
+		if err != nil {
+			return err
+		}
+		if expr.Next(r.Start).IsZero() {
+			return ErrNeverDue
+		}
+		return nil
+	}
+	return ErrUnknownFrequency
+}
+
+// Next returns the first occurrence strictly after after, and false when
+// there is none. Rules must have passed Validate.
+func (r Rule) Next(after time.Time) (time.Time, bool) {
+	start := r.Start.UTC()
+	after = after.UTC()
+	if after.Before(start) {
+		// The first occurrence may be Start itself.
+		after = start.Add(-time.Nanosecond)
+	}
+
+	switch r.Frequency {
+	case Once:
+		return start, start.After(after)
+	case Daily:
+		return every(start, after, 24*time.Hour), true
+	case Weekly:
+		return every(start, after, 7*24*time.Hour), true
+	case Monthly:
+		months := (after.Year()-start.Year())*12 + int(after.Month()-start.Month())
+		for n := max(months, 0); ; n++ {
+			if next := addMonths(start, n); next.After(after) {
+				return next, true
+			}
+		}
+	case Cron:
+		expr, err := ParseCron(r.Expression)
+		if err != nil {
+			return time.Time{}, false
+		}
+		next := expr.Next(after)
+		return next, !next.IsZero()
+	}
+	return time.Time{}, false
+}
+
+// every returns the first start + n*period after after.
+func every(start, after time.Time, period time.Duration) time.Time {
+	if after.Before(start) {
+		return start
+	}
+	n := after.Sub(start)/period + 1
+	return start.Add(n * period)
+}
+
+// addMonths moves t n months on, keeping its day unless the month is too
+// short for it.
+func addMonths(t time.Time, n int) time.Time {
+	year, month := t.Year(), t.Month()+time.Month(n)
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	day := t.Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
@@ -0,0 +1,77 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRuleNext(t *testing.T) {
+	start := date("2024-01-31T09:00:00Z")
+	for _, tc := range []struct {
+		frequency string
+		after     string
+		want      string
+	}{
+		{Once, "2024-01-01T00:00:00Z", "2024-01-31T09:00:00Z"},
+		{Daily, "2024-01-01T00:00:00Z", "2024-01-31T09:00:00Z"},
+		{Daily, "2024-01-31T09:00:00Z", "2024-02-01T09:00:00Z"},
+		{Weekly, "2024-02-01T00:00:00Z", "2024-02-07T09:00:00Z"},
+		{Monthly, "2024-01-31T09:00:00Z", "2024-02-29T09:00:00Z"},
+		{Monthly, "2024-02-29T09:00:00Z", "2024-03-31T09:00:00Z"},
+		{Monthly, "2024-12-31T10:00:00Z", "2025-01-31T09:00:00Z"},
+	} {
+		next, ok := Rule{Frequency: tc.frequency, Start: start}.Next(date(tc.after))
+		assert.True(t, ok, "%s after %s", tc.frequency, tc.after)
+		assert.Equal(t, date(tc.want), next, "%s after %s", tc.frequency, tc.after)
+	}
+
+	_, ok := Rule{Frequency: Once, Start: start}.Next(start)
+	assert.False(t, ok, "one-off rules are due once")
+}
+
+func TestCronRules(t *testing.T) {
+	rule := Rule{Frequency: Cron, Expression: "30 1 1,15 * *", Start: date("2024-01-10T00:00:00Z")}
+	assert.NoError(t, rule.Validate())
+
+	next, ok := rule.Next(date("2024-01-01T00:00:00Z"))
+	assert.True(t, ok)
+	assert.Equal(t, date("2024-01-15T01:30:00Z"), next, "nothing before the start")
+	next, _ = rule.Next(next)
+	assert.Equal(t, date("2024-02-01T01:30:00Z"), next)
+
+	weekdays := Rule{Frequency: Cron, Expression: "0 9 * * 1-5", Start: date("2024-01-01T00:00:00Z")}
+	next, _ = weekdays.Next(date("2024-01-05T09:00:00Z"))
+	assert.Equal(t, date("2024-01-08T09:00:00Z"), next, "Friday is followed by Monday")
+
+	assert.True(t, errors.Is(Rule{Frequency: Cron, Expression: "0 0 30 2 *"}.Validate(), ErrNeverDue))
+	assert.Error(t, Rule{Frequency: Cron, Expression: "0 0 * *"}.Validate())
+	assert.Error(t, Rule{Frequency: Cron, Expression: "61 * * * *"}.Validate())
+	assert.True(t, errors.Is(Rule{Frequency: "HOURLY"}.Validate(), ErrUnknownFrequency))
+}
+
+func TestParseCron(t *testing.T) {
+	expr, err := ParseCron("*/15 8-18/2 * * 0,7")
+	assert.NoError(t, err)
+	assert.True(t, expr.minutes.has(45))
+	assert.False(t, expr.minutes.has(50))
+	assert.True(t, expr.hours.has(10))
+	assert.False(t, expr.hours.has(11))
+	assert.True(t, expr.weekdays.has(0), "7 is Sunday too")
+
+	next := expr.Next(date("2024-01-06T23:59:00Z"))
+	assert.Equal(t, date("2024-01-07T08:00:00Z"), next)
+
+	_, err = ParseCron("* * * * */0")
+	assert.Error(t, err)
+}
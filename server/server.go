@@ -0,0 +1,117 @@
+// Package server runs the HTTP API with hardened timeouts and shuts it down
+// gracefully: on cancellation it stops accepting connections, lets in-flight
+// requests finish and then runs the registered shutdown hooks.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"myapp/config"
+)
+
+// Hook releases a resource during shutdown, such as draining background
+// workers or closing the database pool.
+type Hook struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+type Server struct {
+	http            *http.Server
+	shutdownTimeout time.Duration
+	shutdownDelay   time.Duration
+	beforeShutdown  []func()
+	hooks           []Hook
+}
+
+func New(cfg config.ServerConfig, handler http.Handler) *Server {
+	return &Server{
+		http: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+		shutdownTimeout: cfg.ShutdownTimeout,
+		shutdownDelay:   cfg.ShutdownDelay,
+	}
+}
+
+// BeforeShutdown registers fn to run as soon as shutdown starts, while the
+// server still accepts requests, e.g. to start failing readiness.
+func (s *Server) BeforeShutdown(fn func()) {
+	s.beforeShutdown = append(s.beforeShutdown, fn)
+}
+
+// OnShutdown registers a hook. Hooks run in registration order after the
+// HTTP server has drained, sharing the remaining shutdown timeout.
+func (s *Server) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.hooks = append(s.hooks, Hook{Name: name, Fn: fn})
+}
+
+// Run listens on the configured address and serves until ctx is cancelled,
+// then shuts down gracefully.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve is Run on an existing listener, which Serve takes ownership of.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.http.Serve(listener)
+	}()
+	slog.Info("listening", "addr", listener.Addr().String())
+
+	select {
+	case err := <-serveErr:
+		// The server stopped on its own, e.g. the listener failed.
+		return err
+	case <-ctx.Done():
+	}
+
+	for _, fn := range s.beforeShutdown {
+		fn()
+	}
+	if s.shutdownDelay > 0 {
+		slog.Info("shutting down after delay", "delay", s.shutdownDelay.String())
+		time.Sleep(s.shutdownDelay)
+	}
+
+	slog.Info("shutting down, draining in-flight requests")
+	return s.shutdown()
+}
+
+func (s *Server) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := s.http.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("http server: %w", err))
+	}
+
+	for _, hook := range s.hooks {
+		if err := hook.Fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hook.Name, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	slog.Info("shutdown complete")
+	return nil
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"myapp/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func startServer(t *testing.T, handler http.Handler) (*Server, string, context.CancelFunc, chan error) {
+	cfg := config.Default().Server
+	cfg.ShutdownTimeout = 2 * time.Second
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	srv := New(cfg, handler)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, listener) }()
+
+	return srv, "http://" + listener.Addr().String(), cancel, done
+}
+
+// TestShutdownDrainsInFlightRequests cancels the server mid-request and
+// expects the request to complete before the hooks run
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv, url, cancel, done := startServer(t, handler)
+
+	var order []string
+	srv.BeforeShutdown(func() {
+		order = append(order, "readiness")
+	})
+	srv.OnShutdown("workers", func(ctx context.Context) error {
+		order = append(order, "workers")
+		return nil
+	})
+	srv.OnShutdown("database", func(ctx context.Context) error {
+		order = append(order, "database")
+		return nil
+	})
+
+	response := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			response <- 0
+			return
+		}
+		resp.Body.Close()
+		response <- resp.StatusCode
+	}()
+
+	<-started
+	cancel()
+
+	assert.Equal(t, http.StatusOK, <-response)
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{"readiness", "workers", "database"}, order)
+
+	_, err := http.Get(url)
+	assert.Error(t, err, "server must not accept new connections after shutdown")
+}
+
+func TestShutdownReportsHookErrors(t *testing.T) {
+	srv, _, cancel, done := startServer(t, http.NotFoundHandler())
+	srv.OnShutdown("database", func(ctx context.Context) error {
+		return assert.AnError
+	})
+
+	cancel()
+
+	err := <-done
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.ErrorContains(t, err, "database")
+}
+
+func TestNewAppliesTimeouts(t *testing.T) {
+	cfg := config.Default().Server
+
+	srv := New(cfg, http.NotFoundHandler())
+
+	assert.Equal(t, cfg.ReadTimeout, srv.http.ReadTimeout)
+	assert.Equal(t, cfg.ReadHeaderTimeout, srv.http.ReadHeaderTimeout)
+	assert.Equal(t, cfg.WriteTimeout, srv.http.WriteTimeout)
+	assert.Equal(t, cfg.IdleTimeout, srv.http.IdleTimeout)
+}
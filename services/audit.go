@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"myapp/models"
+	"myapp/repository"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions. Every change to an account writes one entry in the same
+// transaction as the change itself.
+const (
+	ActionRegister        = "user.register"
+	ActionUpdateProfile   = "user.update_profile"
+	ActionFreeze          = "user.freeze"
+	ActionUnfreeze        = "user.unfreeze"
+	ActionTopUp           = "wallet.top_up"
+	ActionPayment         = "wallet.payment"
+	ActionTransfer        = "wallet.transfer"
+	ActionAdjust          = "balance.adjust"
+	ActionPocketCreate    = "pocket.create"
+	ActionPocketRename    = "pocket.rename"
+	ActionPocketClose     = "pocket.close"
+	ActionPocketMove      = "pocket.move"
+	ActionRefund          = "payment.refund"
+	ActionReverseTopUp    = "wallet.reverse_top_up"
+	ActionReverseTransfer = "wallet.reverse_transfer"
+	ActionScheduleCreate  = "schedule.create"
+	ActionScheduleUpdate  = "schedule.update"
+	ActionScheduleCancel  = "schedule.cancel"
+	ActionScheduleFail    = "schedule.attempt_failed"
+	ActionRequestCreate   = "request.create"
+	ActionRequestDecline  = "request.decline"
+	ActionRequestCancel   = "request.cancel"
+)
+
+type actorKey struct{}
+
+// WithActor names who is acting for the rest of ctx, e.g. "admin:budi" in
+// the admin CLI. Without it, changes are attributed to the account holder.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actor(ctx context.Context, userID uuid.UUID) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return "user:" + userID.String()
+}
+
+// audit writes an audit entry about userID. details must not hold secrets.
+func audit(ctx context.Context, repos repository.Repositories, userID uuid.UUID, action, reason string, details map[string]interface{}, now time.Time) error {
+	entry := models.AuditLog{
+		Actor:       actor(ctx, userID),
+		UserID:      userID,
+		Action:      action,
+		Reason:      reason,
+		CreatedDate: now,
+	}
+	if len(details) > 0 {
+		encoded, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		entry.Details = string(encoded)
+	}
+	return repos.Audit.Create(ctx, &entry)
+}
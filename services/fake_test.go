@@ -0,0 +1,692 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"myapp/models"
+	"myapp/notification"
+	"myapp/repository"
+
+	"github.com/google/uuid"
+)
+
+// fakeStore is an in-memory repository.Store. Transactions work on a copy
+// of the data that replaces the original only on success, and may run
+// concurrently; FindForUpdate locks a user until the transaction ends.
+type fakeStore struct {
+	data fakeData
+	// delay holds every transaction open before it commits, so that
+	// concurrent ones overlap.
+	delay time.Duration
+
+	// mu guards data while transactions copy and replace it, and rows.
+	mu   sync.Mutex
+	rows map[uuid.UUID]*sync.Mutex
+}
+
+// fakeTx holds the rows locked by one transaction.
+type fakeTx struct {
+	store  *fakeStore
+	locked []*sync.Mutex
+}
+
+// lock waits for the user's row and returns the data committed by then.
+func (tx *fakeTx) lock(id uuid.UUID) fakeData {
+	s := tx.store
+	s.mu.Lock()
+	row, ok := s.rows[id]
+	if !ok {
+		row = &sync.Mutex{}
+		s.rows[id] = row
+	}
+	s.mu.Unlock()
+
+	row.Lock()
+	tx.locked = append(tx.locked, row)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.clone()
+}
+
+func (tx *fakeTx) unlock() {
+	for _, row := range tx.locked {
+		row.Unlock()
+	}
+}
+
+type fakeData struct {
+	users     map[uuid.UUID]models.User
+	balances  map[balanceKey]models.Balance
+	quotes    map[uuid.UUID]models.Quote
+	pockets   map[uuid.UUID]models.Pocket
+	movements []models.PocketMovement
+	topUps    []models.TopUp
+	payments  []models.Payment
+	transfers []models.Transfer
+	reversals []models.Reversal
+	schedules map[uuid.UUID]models.ScheduledTransfer
+	requests  map[uuid.UUID]models.MoneyRequest
+	audit     []models.AuditLog
+}
+
+func newFakeStore(users ...models.User) *fakeStore {
+	store := &fakeStore{rows: map[uuid.UUID]*sync.Mutex{}, data: fakeData{
+		users:     map[uuid.UUID]models.User{},
+		balances:  map[balanceKey]models.Balance{},
+		quotes:    map[uuid.UUID]models.Quote{},
+		pockets:   map[uuid.UUID]models.Pocket{},
+		schedules: map[uuid.UUID]models.ScheduledTransfer{},
+		requests:  map[uuid.UUID]models.MoneyRequest{},
+	}}
+	for _, user := range users {
+		store.data.users[user.ID] = user
+	}
+	return store
+}
+
+func (d fakeData) clone() fakeData {
+	users := make(map[uuid.UUID]models.User, len(d.users))
+	for id, user := range d.users {
+		users[id] = user
+	}
+	balances := make(map[balanceKey]models.Balance, len(d.balances))
+	for key, balance := range d.balances {
+		balances[key] = balance
+	}
+	quotes := make(map[uuid.UUID]models.Quote, len(d.quotes))
+	for id, quote := range d.quotes {
+		quotes[id] = quote
+	}
+	pockets := make(map[uuid.UUID]models.Pocket, len(d.pockets))
+	for id, pocket := range d.pockets {
+		pockets[id] = pocket
+	}
+	schedules := make(map[uuid.UUID]models.ScheduledTransfer, len(d.schedules))
+	for id, schedule := range d.schedules {
+		schedules[id] = schedule
+	}
+	requests := make(map[uuid.UUID]models.MoneyRequest, len(d.requests))
+	for id, request := range d.requests {
+		requests[id] = request
+	}
+	return fakeData{
+		users:     users,
+		balances:  balances,
+		quotes:    quotes,
+		pockets:   pockets,
+		movements: append([]models.PocketMovement(nil), d.movements...),
+		topUps:    append([]models.TopUp(nil), d.topUps...),
+		payments:  append([]models.Payment(nil), d.payments...),
+		transfers: append([]models.Transfer(nil), d.transfers...),
+		reversals: append([]models.Reversal(nil), d.reversals...),
+		schedules: schedules,
+		requests:  requests,
+		audit:     append([]models.AuditLog(nil), d.audit...),
+	}
+}
+
+func (s *fakeStore) Repositories() repository.Repositories {
+	return fakeRepositories(&s.data, nil)
+}
+
+func (s *fakeStore) Reader(userID uuid.UUID) repository.Repositories {
+	return fakeRepositories(&s.data, nil)
+}
+
+func (s *fakeStore) Transaction(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	s.mu.Lock()
+	working := s.data.clone()
+	s.mu.Unlock()
+
+	tx := &fakeTx{store: s}
+	defer tx.unlock()
+	if err := fn(fakeRepositories(&working, tx)); err != nil {
+		return err
+	}
+	time.Sleep(s.delay)
+	s.mu.Lock()
+	s.data = working
+	s.mu.Unlock()
+	return nil
+}
+
+// fakeRepositories works on data, within tx if it is not nil.
+func fakeRepositories(data *fakeData, tx *fakeTx) repository.Repositories {
+	return repository.Repositories{
+		Users:     fakeUsers{data, tx},
+		Balances:  fakeBalances{data},
+		Quotes:    fakeQuotes{data},
+		Pockets:   fakePockets{data},
+		Movements: fakeMovements{data},
+		TopUps:    fakeTopUps{data},
+		Payments:  fakePayments{data},
+		Transfers: fakeTransfers{data},
+		Reversals: fakeReversals{data},
+		Schedules: fakeSchedules{data},
+		Requests:  fakeRequests{data},
+		Audit:     fakeAudit{data},
+	}
+}
+
+type fakeUsers struct {
+	data *fakeData
+	tx   *fakeTx
+}
+
+func (r fakeUsers) FindByID(ctx context.Context, id uuid.UUID) (models.User, error) {
+	user, ok := r.data.users[id]
+	if !ok {
+		return models.User{}, repository.ErrNotFound
+	}
+	return user, nil
+}
+
+// FindForUpdate sees the data committed while it waited for the lock, as a
+// database would, so the transaction must not have written before it.
+func (r fakeUsers) FindForUpdate(ctx context.Context, id uuid.UUID) (models.User, error) {
+	if r.tx != nil {
+		*r.data = r.tx.lock(id)
+	}
+	return r.FindByID(ctx, id)
+}
+
+func (r fakeUsers) FindByPhone(ctx context.Context, phoneNumber string) (models.User, error) {
+	for _, user := range r.data.users {
+		if user.PhoneNumber == phoneNumber {
+			return user, nil
+		}
+	}
+	return models.User{}, repository.ErrNotFound
+}
+
+func (r fakeUsers) Create(ctx context.Context, user *models.User) error {
+	if _, err := r.FindByPhone(ctx, user.PhoneNumber); err == nil {
+		return repository.ErrDuplicate
+	}
+	user.Version = 1
+	r.data.users[user.ID] = *user
+	return nil
+}
+
+func (r fakeUsers) UpdateProfile(ctx context.Context, user *models.User, changes map[string]interface{}) error {
+	stored, ok := r.data.users[user.ID]
+	if !ok || stored.Version != user.Version {
+		return repository.ErrStale
+	}
+	user.Version++
+	r.data.users[user.ID] = *user
+	return nil
+}
+
+func (r fakeUsers) AdjustBalance(ctx context.Context, id uuid.UUID, delta float64) (models.User, error) {
+	user, ok := r.data.users[id]
+	if !ok {
+		return models.User{}, repository.ErrNotFound
+	}
+	if user.Balance+delta < 0 {
+		return models.User{}, repository.ErrInsufficientFunds
+	}
+	user.Balance += delta
+	r.data.users[id] = user
+	return user, nil
+}
+
+func (r fakeUsers) SetFrozenAt(ctx context.Context, id uuid.UUID, frozenAt *time.Time) (models.User, error) {
+	user, ok := r.data.users[id]
+	if !ok {
+		return models.User{}, repository.ErrNotFound
+	}
+	user.FrozenAt = frozenAt
+	r.data.users[id] = user
+	return user, nil
+}
+
+type balanceKey struct {
+	userID   uuid.UUID
+	currency string
+}
+
+type fakeBalances struct{ data *fakeData }
+
+func (r fakeBalances) Adjust(ctx context.Context, userID uuid.UUID, currency string, delta float64) (models.Balance, error) {
+	key := balanceKey{userID, currency}
+	balance, ok := r.data.balances[key]
+	if !ok {
+		balance = models.Balance{UserID: userID, Currency: currency}
+	}
+	if balance.Amount+delta < 0 {
+		return models.Balance{}, repository.ErrInsufficientFunds
+	}
+	balance.Amount += delta
+	r.data.balances[key] = balance
+	return balance, nil
+}
+
+func (r fakeBalances) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Balance, error) {
+	var balances []models.Balance
+	for key, balance := range r.data.balances {
+		if key.userID == userID {
+			balances = append(balances, balance)
+		}
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Currency < balances[j].Currency })
+	return balances, nil
+}
+
+type fakeQuotes struct{ data *fakeData }
+
+func (r fakeQuotes) Create(ctx context.Context, quote *models.Quote) error {
+	quote.ID = uuid.New()
+	r.data.quotes[quote.ID] = *quote
+	return nil
+}
+
+func (r fakeQuotes) FindByID(ctx context.Context, id uuid.UUID) (models.Quote, error) {
+	quote, ok := r.data.quotes[id]
+	if !ok {
+		return models.Quote{}, repository.ErrNotFound
+	}
+	return quote, nil
+}
+
+func (r fakeQuotes) MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	quote, ok := r.data.quotes[id]
+	if !ok || quote.UsedAt != nil {
+		return repository.ErrStale
+	}
+	quote.UsedAt = &usedAt
+	r.data.quotes[id] = quote
+	return nil
+}
+
+type fakePockets struct{ data *fakeData }
+
+func (r fakePockets) Create(ctx context.Context, pocket *models.Pocket) error {
+	pocket.ID = uuid.New()
+	r.data.pockets[pocket.ID] = *pocket
+	return nil
+}
+
+func (r fakePockets) FindByID(ctx context.Context, id uuid.UUID) (models.Pocket, error) {
+	pocket, ok := r.data.pockets[id]
+	if !ok {
+		return models.Pocket{}, repository.ErrNotFound
+	}
+	return pocket, nil
+}
+
+func (r fakePockets) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Pocket, error) {
+	var pockets []models.Pocket
+	for _, pocket := range r.data.pockets {
+		if pocket.UserID == userID && !pocket.Closed() {
+			pockets = append(pockets, pocket)
+		}
+	}
+	sort.Slice(pockets, func(i, j int) bool { return pockets[i].CreatedDate.Before(pockets[j].CreatedDate) })
+	return pockets, nil
+}
+
+func (r fakePockets) Rename(ctx context.Context, id uuid.UUID, name string) (models.Pocket, error) {
+	return r.update(id, func(pocket *models.Pocket) error {
+		pocket.Name = name
+		return nil
+	})
+}
+
+func (r fakePockets) AdjustBalance(ctx context.Context, id uuid.UUID, delta float64) (models.Pocket, error) {
+	return r.update(id, func(pocket *models.Pocket) error {
+		if pocket.Balance+delta < 0 {
+			return repository.ErrInsufficientFunds
+		}
+		pocket.Balance += delta
+		return nil
+	})
+}
+
+func (r fakePockets) Close(ctx context.Context, id uuid.UUID, closedAt time.Time) (models.Pocket, error) {
+	return r.update(id, func(pocket *models.Pocket) error {
+		if pocket.Balance != 0 {
+			return repository.ErrStale
+		}
+		pocket.ClosedAt = &closedAt
+		return nil
+	})
+}
+
+func (r fakePockets) update(id uuid.UUID, change func(pocket *models.Pocket) error) (models.Pocket, error) {
+	pocket, ok := r.data.pockets[id]
+	if !ok || pocket.Closed() {
+		return models.Pocket{}, repository.ErrNotFound
+	}
+	if err := change(&pocket); err != nil {
+		return models.Pocket{}, err
+	}
+	r.data.pockets[id] = pocket
+	return pocket, nil
+}
+
+type fakeMovements struct{ data *fakeData }
+
+func (r fakeMovements) Create(ctx context.Context, movement *models.PocketMovement) error {
+	movement.ID = uuid.New()
+	r.data.movements = append(r.data.movements, *movement)
+	return nil
+}
+
+func (r fakeMovements) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.PocketMovement, error) {
+	var movements []models.PocketMovement
+	for _, movement := range r.data.movements {
+		if movement.UserID == userID {
+			movements = append(movements, movement)
+		}
+	}
+	return movements, nil
+}
+
+type fakeTopUps struct{ data *fakeData }
+
+func (r fakeTopUps) Create(ctx context.Context, topUp *models.TopUp) error {
+	topUp.ID = uuid.New()
+	r.data.topUps = append(r.data.topUps, *topUp)
+	return nil
+}
+
+func (r fakeTopUps) FindByID(ctx context.Context, id uuid.UUID) (models.TopUp, error) {
+	for _, topUp := range r.data.topUps {
+		if topUp.ID == id {
+			return topUp, nil
+		}
+	}
+	return models.TopUp{}, repository.ErrNotFound
+}
+
+func (r fakeTopUps) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.TopUp, error) {
+	var topUps []models.TopUp
+	for _, topUp := range r.data.topUps {
+		if topUp.UserID == userID {
+			topUps = append(topUps, topUp)
+		}
+	}
+	return topUps, nil
+}
+
+func (r fakeTopUps) MarkReversed(ctx context.Context, id uuid.UUID, reversedAt time.Time) error {
+	for i, topUp := range r.data.topUps {
+		if topUp.ID == id && topUp.ReversedAt == nil {
+			r.data.topUps[i].ReversedAt = &reversedAt
+			return nil
+		}
+	}
+	return repository.ErrStale
+}
+
+type fakePayments struct{ data *fakeData }
+
+func (r fakePayments) Create(ctx context.Context, payment *models.Payment) error {
+	payment.ID = uuid.New()
+	r.data.payments = append(r.data.payments, *payment)
+	return nil
+}
+
+func (r fakePayments) FindByID(ctx context.Context, id uuid.UUID) (models.Payment, error) {
+	for _, payment := range r.data.payments {
+		if payment.ID == id {
+			return payment, nil
+		}
+	}
+	return models.Payment{}, repository.ErrNotFound
+}
+
+func (r fakePayments) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Payment, error) {
+	var payments []models.Payment
+	for _, payment := range r.data.payments {
+		if payment.UserID == userID {
+			payments = append(payments, payment)
+		}
+	}
+	return payments, nil
+}
+
+func (r fakePayments) AddRefund(ctx context.Context, id uuid.UUID, amount float64) (models.Payment, error) {
+	for i, payment := range r.data.payments {
+		if payment.ID != id {
+			continue
+		}
+		if payment.RefundedAmount+amount > payment.Amount+0.005 {
+			return models.Payment{}, repository.ErrInsufficientFunds
+		}
+		r.data.payments[i].RefundedAmount += amount
+		return r.data.payments[i], nil
+	}
+	return models.Payment{}, repository.ErrNotFound
+}
+
+type fakeTransfers struct{ data *fakeData }
+
+func (r fakeTransfers) Create(ctx context.Context, transfer *models.Transfer) error {
+	if transfer.ScheduleID != nil {
+		for _, other := range r.data.transfers {
+			if other.ScheduleID != nil && *other.ScheduleID == *transfer.ScheduleID &&
+				other.ScheduledFor.Equal(*transfer.ScheduledFor) {
+				return repository.ErrDuplicate
+			}
+		}
+	}
+	transfer.ID = uuid.New()
+	r.data.transfers = append(r.data.transfers, *transfer)
+	return nil
+}
+
+func (r fakeTransfers) FindByID(ctx context.Context, id uuid.UUID) (models.Transfer, error) {
+	for _, transfer := range r.data.transfers {
+		if transfer.ID == id {
+			return transfer, nil
+		}
+	}
+	return models.Transfer{}, repository.ErrNotFound
+}
+
+func (r fakeTransfers) ListBySender(ctx context.Context, userID uuid.UUID) ([]models.Transfer, error) {
+	var transfers []models.Transfer
+	for _, transfer := range r.data.transfers {
+		if transfer.FromUserID == userID {
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers, nil
+}
+
+func (r fakeTransfers) ListByRecipient(ctx context.Context, userID uuid.UUID) ([]models.Transfer, error) {
+	var transfers []models.Transfer
+	for _, transfer := range r.data.transfers {
+		if transfer.ToUserID == userID {
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers, nil
+}
+
+func (r fakeTransfers) MarkReversed(ctx context.Context, id uuid.UUID, reversedAt time.Time) error {
+	for i, transfer := range r.data.transfers {
+		if transfer.ID == id && transfer.Status == models.TransferSucceeded && transfer.ReversedAt == nil {
+			r.data.transfers[i].Status = models.TransferReversed
+			r.data.transfers[i].ReversedAt = &reversedAt
+			return nil
+		}
+	}
+	return repository.ErrStale
+}
+
+type fakeReversals struct{ data *fakeData }
+
+func (r fakeReversals) Create(ctx context.Context, reversal *models.Reversal) error {
+	reversal.ID = uuid.New()
+	r.data.reversals = append(r.data.reversals, *reversal)
+	return nil
+}
+
+func (r fakeReversals) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Reversal, error) {
+	var reversals []models.Reversal
+	for _, reversal := range r.data.reversals {
+		if reversal.UserID == userID {
+			reversals = append(reversals, reversal)
+		}
+	}
+	return reversals, nil
+}
+
+func (r fakeReversals) ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]models.Reversal, error) {
+	var reversals []models.Reversal
+	for _, reversal := range r.data.reversals {
+		if reversal.PaymentID != nil && *reversal.PaymentID == paymentID {
+			reversals = append(reversals, reversal)
+		}
+	}
+	return reversals, nil
+}
+
+type fakeSchedules struct{ data *fakeData }
+
+func (r fakeSchedules) Create(ctx context.Context, schedule *models.ScheduledTransfer) error {
+	schedule.ID = uuid.New()
+	schedule.Version = 1
+	r.data.schedules[schedule.ID] = *schedule
+	return nil
+}
+
+func (r fakeSchedules) FindByID(ctx context.Context, id uuid.UUID) (models.ScheduledTransfer, error) {
+	schedule, ok := r.data.schedules[id]
+	if !ok {
+		return models.ScheduledTransfer{}, repository.ErrNotFound
+	}
+	return schedule, nil
+}
+
+func (r fakeSchedules) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.ScheduledTransfer, error) {
+	var schedules []models.ScheduledTransfer
+	for _, schedule := range r.data.schedules {
+		if schedule.UserID == userID && schedule.Status != models.ScheduleCancelled {
+			schedules = append(schedules, schedule)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].CreatedDate.Before(schedules[j].CreatedDate) })
+	return schedules, nil
+}
+
+func (r fakeSchedules) ListDue(ctx context.Context, now time.Time, limit int) ([]models.ScheduledTransfer, error) {
+	var schedules []models.ScheduledTransfer
+	for _, schedule := range r.data.schedules {
+		if schedule.Status == models.ScheduleActive && schedule.AttemptAt != nil && !schedule.AttemptAt.After(now) {
+			schedules = append(schedules, schedule)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].AttemptAt.Before(*schedules[j].AttemptAt) })
+	if len(schedules) > limit {
+		schedules = schedules[:limit]
+	}
+	return schedules, nil
+}
+
+func (r fakeSchedules) Update(ctx context.Context, schedule *models.ScheduledTransfer) error {
+	stored, ok := r.data.schedules[schedule.ID]
+	if !ok || stored.Version != schedule.Version {
+		return repository.ErrStale
+	}
+	schedule.Version++
+	r.data.schedules[schedule.ID] = *schedule
+	return nil
+}
+
+type fakeRequests struct{ data *fakeData }
+
+func (r fakeRequests) Create(ctx context.Context, request *models.MoneyRequest) error {
+	request.ID = uuid.New()
+	r.data.requests[request.ID] = *request
+	return nil
+}
+
+func (r fakeRequests) FindByID(ctx context.Context, id uuid.UUID) (models.MoneyRequest, error) {
+	request, ok := r.data.requests[id]
+	if !ok {
+		return models.MoneyRequest{}, repository.ErrNotFound
+	}
+	return request, nil
+}
+
+func (r fakeRequests) ListByRequester(ctx context.Context, requesterID uuid.UUID, since time.Time) ([]models.MoneyRequest, error) {
+	var requests []models.MoneyRequest
+	for _, request := range r.data.requests {
+		if request.RequesterID == requesterID && !request.CreatedDate.Before(since) {
+			requests = append(requests, request)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].CreatedDate.After(requests[j].CreatedDate) })
+	return requests, nil
+}
+
+func (r fakeRequests) ListPendingByPayer(ctx context.Context, payerID uuid.UUID, now time.Time) ([]models.MoneyRequest, error) {
+	var requests []models.MoneyRequest
+	for _, request := range r.data.requests {
+		if request.PayerID == payerID && request.StatusAt(now) == models.RequestPending {
+			requests = append(requests, request)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].CreatedDate.Before(requests[j].CreatedDate) })
+	return requests, nil
+}
+
+func (r fakeRequests) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.MoneyRequest, error) {
+	var requests []models.MoneyRequest
+	for _, request := range r.data.requests {
+		if request.RequesterID == userID || request.PayerID == userID {
+			requests = append(requests, request)
+		}
+	}
+	return requests, nil
+}
+
+func (r fakeRequests) Respond(ctx context.Context, id uuid.UUID, status string, respondedAt time.Time, transferID *uuid.UUID) error {
+	request, ok := r.data.requests[id]
+	if !ok || request.StatusAt(respondedAt) != models.RequestPending {
+		return repository.ErrStale
+	}
+	request.Status = status
+	request.RespondedAt = &respondedAt
+	request.TransferID = transferID
+	r.data.requests[id] = request
+	return nil
+}
+
+type fakeAudit struct{ data *fakeData }
+
+func (r fakeAudit) Create(ctx context.Context, log *models.AuditLog) error {
+	log.ID = uuid.New()
+	r.data.audit = append(r.data.audit, *log)
+	return nil
+}
+
+func (r fakeAudit) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]models.AuditLog, error) {
+	var logs []models.AuditLog
+	for i := len(r.data.audit) - 1; i >= 0 && len(logs) < limit; i-- {
+		if r.data.audit[i].UserID == userID {
+			logs = append(logs, r.data.audit[i])
+		}
+	}
+	return logs, nil
+}
+
+// recordingNotifier keeps every notification it is asked to send.
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []notification.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification notification.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, notification)
+	return nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"myapp/i18n"
+	"myapp/models"
+	"myapp/notification"
+)
+
+// UserLanguage returns the user's preferred language, or i18n.Default when
+// they have not chosen one.
+func UserLanguage(user models.User) i18n.Language {
+	if lang, ok := i18n.Parse(user.Language); ok {
+		return lang
+	}
+	return i18n.Default
+}
+
+// notify sends a notification to user in their own language. Delivery
+// failures are logged but never fail the operation that triggered them.
+func notify(ctx context.Context, notifier notification.Notifier, user models.User, key string, params func(lang i18n.Language) i18n.Params) {
+	lang := UserLanguage(user)
+	n := notification.Notification{
+		UserID:   user.ID,
+		Language: lang,
+		Key:      key,
+		Params:   params(lang),
+	}
+	if err := notifier.Notify(ctx, n); err != nil {
+		slog.WarnContext(ctx, "notification failed", "user_id", user.ID.String(), "key", key, "error", err)
+	}
+}
+
+func displayName(user models.User) string {
+	if user.LastName == "" {
+		return user.FirstName
+	}
+	return user.FirstName + " " + user.LastName
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"myapp/apperrors"
+	"myapp/models"
+	"myapp/repository"
+
+	"github.com/google/uuid"
+)
+
+// MaxPockets is how many open pockets a user may have.
+const MaxPockets = 10
+
+// Pockets returns the user's open pockets, oldest first. It may be served by
+// a read replica.
+func (s *WalletService) Pockets(ctx context.Context, userID uuid.UUID) ([]models.Pocket, error) {
+	repos := s.store.Reader(userID)
+	if _, err := repos.Users.FindByID(ctx, userID); err != nil {
+		return nil, lookupError(err, apperrors.ErrUserNotFound)
+	}
+	return repos.Pockets.ListByUser(ctx, userID)
+}
+
+// CreatePocket opens an empty pocket in currency, models.DefaultCurrency
+// when empty. Names are unique among the user's open pockets, ignoring
+// case; two requests racing for the same name may both succeed.
+func (s *WalletService) CreatePocket(ctx context.Context, userID uuid.UUID, name, currency string) (models.Pocket, error) {
+	name, err := pocketName(name)
+	if err != nil {
+		return models.Pocket{}, err
+	}
+	if currency, err = s.currency("currency", currency); err != nil {
+		return models.Pocket{}, err
+	}
+
+	var pocket models.Pocket
+	err = s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		if _, err := repos.Users.FindByID(ctx, userID); err != nil {
+			return lookupError(err, apperrors.ErrUserNotFound)
+		}
+		open, err := repos.Pockets.ListByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if len(open) >= MaxPockets {
+			return apperrors.ErrPocketLimitReached
+		}
+		if err := checkPocketName(open, uuid.Nil, name); err != nil {
+			return err
+		}
+
+		now := s.now()
+		pocket = models.Pocket{UserID: userID, Name: name, Currency: currency, CreatedDate: now, UpdatedDate: now}
+		if err := repos.Pockets.Create(ctx, &pocket); err != nil {
+			return err
+		}
+		return audit(ctx, repos, userID, ActionPocketCreate, "", map[string]interface{}{
+			"pocket_id": pocket.ID,
+			"name":      name,
+			"currency":  currency,
+		}, now)
+	})
+	if err != nil {
+		return models.Pocket{}, err
+	}
+	return pocket, nil
+}
+
+// RenamePocket renames one of the user's open pockets.
+func (s *WalletService) RenamePocket(ctx context.Context, userID, pocketID uuid.UUID, name string) (models.Pocket, error) {
+	name, err := pocketName(name)
+	if err != nil {
+		return models.Pocket{}, err
+	}
+
+	var pocket models.Pocket
+	err = s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		current, err := findPocket(ctx, repos, userID, pocketID)
+		if err != nil {
+			return err
+		}
+		open, err := repos.Pockets.ListByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkPocketName(open, pocketID, name); err != nil {
+			return err
+		}
+
+		if pocket, err = repos.Pockets.Rename(ctx, pocketID, name); err != nil {
+			return lookupError(err, apperrors.ErrPocketNotFound)
+		}
+		return audit(ctx, repos, userID, ActionPocketRename, "", map[string]interface{}{
+			"pocket_id": pocketID,
+			"from_name": current.Name,
+			"to_name":   name,
+		}, pocket.UpdatedDate)
+	})
+	if err != nil {
+		return models.Pocket{}, err
+	}
+	return pocket, nil
+}
+
+// ClosePocket closes one of the user's pockets, first moving whatever it
+// still holds to the main balance. The movement is returned when there was
+// one.
+func (s *WalletService) ClosePocket(ctx context.Context, userID, pocketID uuid.UUID) (models.Pocket, *models.PocketMovement, error) {
+	var pocket models.Pocket
+	var movement *models.PocketMovement
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		var err error
+		if pocket, err = findPocket(ctx, repos, userID, pocketID); err != nil {
+			return err
+		}
+		details := map[string]interface{}{"pocket_id": pocketID, "name": pocket.Name}
+		if pocket.Balance > 0 {
+			moved, err := s.move(ctx, repos, PocketMove{UserID: userID, From: pocketID, Amount: pocket.Balance})
+			if err != nil {
+				return err
+			}
+			movement = &moved
+			details["movement_id"] = moved.ID
+			details["amount"] = moved.Amount
+		}
+
+		if pocket, err = repos.Pockets.Close(ctx, pocketID, s.now()); err != nil {
+			return lookupError(err, apperrors.ErrPocketNotFound)
+		}
+		return audit(ctx, repos, userID, ActionPocketClose, "", details, *pocket.ClosedAt)
+	})
+	if err != nil {
+		return models.Pocket{}, nil, err
+	}
+	return pocket, movement, nil
+}
+
+// PocketMove is money a user moves between two of their own balances. A nil
+// From or To is the main balance in the currency of the other pocket.
+type PocketMove struct {
+	UserID uuid.UUID
+	From   uuid.UUID
+	To     uuid.UUID
+	Amount float64
+}
+
+// MovePocketMoney moves money between the user's pockets at once. Pockets in
+// different currencies cannot be moved between.
+func (s *WalletService) MovePocketMoney(ctx context.Context, move PocketMove) (models.PocketMovement, error) {
+	var movement models.PocketMovement
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		var err error
+		if movement, err = s.move(ctx, repos, move); err != nil {
+			return err
+		}
+		return audit(ctx, repos, move.UserID, ActionPocketMove, "", map[string]interface{}{
+			"movement_id":    movement.ID,
+			"from_pocket_id": movement.FromPocketID,
+			"to_pocket_id":   movement.ToPocketID,
+			"amount":         movement.Amount,
+			"currency":       movement.Currency,
+		}, movement.CreatedDate)
+	})
+	if err != nil {
+		return models.PocketMovement{}, err
+	}
+	return movement, nil
+}
+
+func (s *WalletService) move(ctx context.Context, repos repository.Repositories, move PocketMove) (models.PocketMovement, error) {
+	if move.From == move.To {
+		return models.PocketMovement{}, apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"to_pocket_id": {Rule: "invalid"},
+		})
+	}
+	currency := ""
+	for _, id := range []uuid.UUID{move.From, move.To} {
+		if id == uuid.Nil {
+			continue
+		}
+		pocket, err := findPocket(ctx, repos, move.UserID, id)
+		if err != nil {
+			return models.PocketMovement{}, err
+		}
+		if currency != "" && pocket.Currency != currency {
+			return models.PocketMovement{}, apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+				"to_pocket_id": {Rule: "invalid"},
+			})
+		}
+		currency = pocket.Currency
+	}
+
+	var from adjusted
+	debit := func() (err error) {
+		from, err = s.adjustAccount(ctx, repos, move.UserID, Account{Currency: currency, PocketID: move.From}, -move.Amount)
+		return err
+	}
+	credit := func() error {
+		_, err := s.adjustAccount(ctx, repos, move.UserID, Account{Currency: currency, PocketID: move.To}, move.Amount)
+		return err
+	}
+
+	// The main balance is always updated before pockets, and pockets in ID
+	// order, so that two opposite moves cannot deadlock.
+	steps := []func() error{debit, credit}
+	if bytes.Compare(move.To[:], move.From[:]) < 0 {
+		steps = []func() error{credit, debit}
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return models.PocketMovement{}, err
+		}
+	}
+	if from.user.Frozen() {
+		return models.PocketMovement{}, apperrors.ErrAccountFrozen
+	}
+
+	movement := models.PocketMovement{
+		UserID:        move.UserID,
+		FromPocketID:  pocketRef(move.From),
+		ToPocketID:    pocketRef(move.To),
+		Amount:        move.Amount,
+		Currency:      from.currency,
+		BalanceBefore: from.balance + move.Amount,
+		BalanceAfter:  from.balance,
+		CreatedDate:   s.now(),
+	}
+	if err := repos.Movements.Create(ctx, &movement); err != nil {
+		return models.PocketMovement{}, err
+	}
+	return movement, nil
+}
+
+// findPocket returns the user's open pocket. Closed pockets and those of
+// other users are not found.
+func findPocket(ctx context.Context, repos repository.Repositories, userID, pocketID uuid.UUID) (models.Pocket, error) {
+	pocket, err := repos.Pockets.FindByID(ctx, pocketID)
+	if err == nil && (pocket.UserID != userID || pocket.Closed()) {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		return models.Pocket{}, lookupError(err, apperrors.ErrPocketNotFound)
+	}
+	return pocket, nil
+}
+
+func pocketName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"name": {Rule: "required"},
+		})
+	}
+	return name, nil
+}
+
+// checkPocketName fails with DUPLICATE_POCKET when a pocket other than self
+// already has the name.
+func checkPocketName(open []models.Pocket, self uuid.UUID, name string) error {
+	for _, pocket := range open {
+		if pocket.ID != self && strings.EqualFold(pocket.Name, name) {
+			return apperrors.ErrDuplicatePocket
+		}
+	}
+	return nil
+}
+
+// pocketRef is the pocket ID stored on records, nil for the main balance.
+func pocketRef(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
+
+// withPocket adds the pocket to audit details when there is one.
+func withPocket(details map[string]interface{}, pocketID *uuid.UUID) map[string]interface{} {
+	if pocketID != nil {
+		details["pocket_id"] = *pocketID
+	}
+	return details
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"myapp/apperrors"
+	"myapp/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPocketsSeparateMoney(t *testing.T) {
+	wallet, store, _, users := walletFixture(1000)
+	ctx := context.Background()
+	userID := users[0].ID
+
+	savings, err := wallet.CreatePocket(ctx, userID, " Savings ", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Savings", savings.Name)
+	assert.Equal(t, models.DefaultCurrency, savings.Currency)
+	_, err = wallet.CreatePocket(ctx, userID, "savings", "")
+	assert.True(t, errors.Is(err, apperrors.ErrDuplicatePocket), "names ignore case")
+
+	// Top-ups and payments choose their pocket
+	topUp, err := wallet.TopUp(ctx, userID, 300, Account{PocketID: savings.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, &savings.ID, topUp.PocketID)
+	assert.Equal(t, 300.0, topUp.BalanceAfter)
+	assert.Equal(t, 1000.0, store.data.users[userID].Balance, "the main balance is untouched")
+
+	_, err = wallet.Pay(ctx, userID, PaymentOrder{Amount: 400, Account: Account{PocketID: savings.ID}, Remarks: "Shoes"})
+	assert.True(t, errors.Is(err, apperrors.ErrInsufficientBalance), "pockets cannot borrow from the main balance")
+	payment, err := wallet.Pay(ctx, userID, PaymentOrder{Amount: 100, Account: Account{PocketID: savings.ID}, Remarks: "Shoes"})
+	assert.NoError(t, err)
+	assert.Equal(t, 200.0, payment.BalanceAfter)
+
+	_, err = wallet.TopUp(ctx, userID, 10, Account{Currency: "USD", PocketID: savings.ID})
+	assert.True(t, errors.Is(err, apperrors.ErrValidationFailed), "the currency must be the pocket's")
+
+	// Money moves between pockets and the main balance at once
+	movement, err := wallet.MovePocketMoney(ctx, PocketMove{UserID: userID, To: savings.ID, Amount: 500})
+	assert.NoError(t, err)
+	assert.Nil(t, movement.FromPocketID)
+	assert.Equal(t, &savings.ID, movement.ToPocketID)
+	assert.Equal(t, 1000.0, movement.BalanceBefore)
+	assert.Equal(t, 500.0, movement.BalanceAfter)
+	assert.Equal(t, 500.0, store.data.users[userID].Balance)
+	assert.Equal(t, 700.0, store.data.pockets[savings.ID].Balance)
+
+	_, err = wallet.MovePocketMoney(ctx, PocketMove{UserID: userID, From: savings.ID, To: savings.ID, Amount: 1})
+	assert.True(t, errors.Is(err, apperrors.ErrValidationFailed))
+	_, err = wallet.MovePocketMoney(ctx, PocketMove{UserID: userID, From: savings.ID, Amount: 701})
+	assert.True(t, errors.Is(err, apperrors.ErrInsufficientBalance))
+
+	history, err := wallet.History(ctx, userID)
+	assert.NoError(t, err)
+	assert.Len(t, history.Movements, 1)
+}
+
+func TestPocketsOfOtherUsersAreNotFound(t *testing.T) {
+	wallet, _, _, users := walletFixture(1000, 1000)
+	ctx := context.Background()
+	theirs, err := wallet.CreatePocket(ctx, users[1].ID, "Savings", "")
+	if err != nil {
+		t.Fatalf("Failed to create pocket: %v", err)
+	}
+
+	_, err = wallet.TopUp(ctx, users[0].ID, 100, Account{PocketID: theirs.ID})
+	assert.True(t, errors.Is(err, apperrors.ErrPocketNotFound))
+	_, err = wallet.MovePocketMoney(ctx, PocketMove{UserID: users[0].ID, To: theirs.ID, Amount: 100})
+	assert.True(t, errors.Is(err, apperrors.ErrPocketNotFound))
+	_, err = wallet.RenamePocket(ctx, users[0].ID, theirs.ID, "Mine")
+	assert.True(t, errors.Is(err, apperrors.ErrPocketNotFound))
+	_, _, err = wallet.ClosePocket(ctx, users[0].ID, theirs.ID)
+	assert.True(t, errors.Is(err, apperrors.ErrPocketNotFound))
+}
+
+func TestClosingAPocketEmptiesIt(t *testing.T) {
+	wallet, store, _, users := walletFixture(0)
+	ctx := context.Background()
+	userID := users[0].ID
+	now := time.Now()
+	wallet.now = func() time.Time { now = now.Add(time.Second); return now }
+
+	holiday, err := wallet.CreatePocket(ctx, userID, "Holiday", "")
+	if err != nil {
+		t.Fatalf("Failed to create pocket: %v", err)
+	}
+	if _, err := wallet.TopUp(ctx, userID, 250, Account{PocketID: holiday.ID}); err != nil {
+		t.Fatalf("Failed to top up: %v", err)
+	}
+	renamed, err := wallet.RenamePocket(ctx, userID, holiday.ID, "Bali")
+	assert.NoError(t, err)
+	assert.Equal(t, "Bali", renamed.Name)
+
+	closed, movement, err := wallet.ClosePocket(ctx, userID, holiday.ID)
+	assert.NoError(t, err)
+	assert.True(t, closed.Closed())
+	if assert.NotNil(t, movement) {
+		assert.Equal(t, 250.0, movement.Amount)
+		assert.Nil(t, movement.ToPocketID)
+	}
+	assert.Equal(t, 250.0, store.data.users[userID].Balance, "what was left goes back to the main balance")
+
+	_, err = wallet.TopUp(ctx, userID, 10, Account{PocketID: holiday.ID})
+	assert.True(t, errors.Is(err, apperrors.ErrPocketNotFound))
+	pockets, err := wallet.Pockets(ctx, userID)
+	assert.NoError(t, err)
+	assert.Empty(t, pockets)
+
+	_, err = wallet.CreatePocket(ctx, userID, "Bali", "")
+	assert.NoError(t, err, "closed pockets free their name")
+	for i := 1; i < MaxPockets; i++ {
+		if _, err := wallet.CreatePocket(ctx, userID, uuid.NewString(), ""); err != nil {
+			t.Fatalf("Failed to create pocket: %v", err)
+		}
+	}
+	_, err = wallet.CreatePocket(ctx, userID, "One too many", "")
+	assert.True(t, errors.Is(err, apperrors.ErrPocketLimitReached))
+}
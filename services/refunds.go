@@ -0,0 +1,303 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"myapp/apperrors"
+	"myapp/fx"
+	"myapp/i18n"
+	"myapp/models"
+	"myapp/repository"
+
+	"github.com/google/uuid"
+)
+
+// RefundOrder is a refund of a payment, as its merchant or an administrator
+// asked for it.
+type RefundOrder struct {
+	PaymentID uuid.UUID
+	// Amount is refunded, or whatever is left of the payment when zero.
+	Amount float64
+	// Merchant is the merchant asking, who must be the one paid. It is
+	// empty for administrators, who are named with WithActor.
+	Merchant string
+	Reason   string
+}
+
+// RefundResult is a completed refund with the payment as it is after it.
+type RefundResult struct {
+	Refund  models.Reversal
+	Payment models.Payment
+}
+
+// Refund gives back the ordered amount of a payment to the account it was
+// paid from, or to the main balance when that pocket has been closed since.
+// Refunds of a payment never add up to more than was paid: going over fails
+// with REFUND_EXCEEDS_PAYMENT. Payments of other merchants are not found.
+func (s *WalletService) Refund(ctx context.Context, order RefundOrder) (RefundResult, error) {
+	if order.Amount < 0 {
+		return RefundResult{}, apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"amount": {Rule: "invalid"},
+		})
+	}
+	if order.Merchant != "" {
+		ctx = WithActor(ctx, "merchant:"+order.Merchant)
+	}
+
+	var result RefundResult
+	var user models.User
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		payment, err := repos.Payments.FindByID(ctx, order.PaymentID)
+		if err == nil && order.Merchant != "" && payment.Merchant != order.Merchant {
+			err = repository.ErrNotFound
+		}
+		if err != nil {
+			return lookupError(err, apperrors.ErrPaymentNotFound)
+		}
+		amount := order.Amount
+		if amount == 0 {
+			if amount = fx.Round(payment.Amount - payment.RefundedAmount); amount <= 0 {
+				return apperrors.ErrRefundExceedsPayment
+			}
+		}
+
+		if result.Payment, err = repos.Payments.AddRefund(ctx, payment.ID, amount); err != nil {
+			if errors.Is(err, repository.ErrInsufficientFunds) {
+				return apperrors.ErrRefundExceedsPayment.Wrap(err)
+			}
+			return lookupError(err, apperrors.ErrPaymentNotFound)
+		}
+		changed, err := s.restore(ctx, repos, payment.UserID, payment.Currency, payment.PocketID, amount)
+		if err != nil {
+			return err
+		}
+		user = changed.user
+
+		result.Refund = s.reversal(ctx, changed, models.ReversalCredit, amount, order.Reason)
+		result.Refund.PaymentID = &payment.ID
+		if err := repos.Reversals.Create(ctx, &result.Refund); err != nil {
+			return err
+		}
+		return audit(ctx, repos, user.ID, ActionRefund, order.Reason, withPocket(map[string]interface{}{
+			"reversal_id":     result.Refund.ID,
+			"payment_id":      payment.ID,
+			"amount":          amount,
+			"currency":        result.Refund.Currency,
+			"refunded_amount": result.Payment.RefundedAmount,
+			"balance_after":   result.Refund.BalanceAfter,
+		}, result.Refund.PocketID), result.Refund.CreatedDate)
+	})
+	if err != nil {
+		return RefundResult{}, err
+	}
+
+	refund := result.Refund
+	notify(ctx, s.notifier, user, "refund_received", func(lang i18n.Language) i18n.Params {
+		return i18n.Params{
+			"amount":  i18n.FormatMoney(lang, refund.Amount, refund.Currency),
+			"remarks": result.Payment.Remarks,
+			"balance": i18n.FormatMoney(lang, refund.BalanceAfter, refund.Currency),
+		}
+	})
+	return result, nil
+}
+
+// ReverseTopUp takes back a top-up booked in error, on an administrator's
+// behalf. The money must still be there: if the user has spent it, the
+// reversal fails with INSUFFICIENT_BALANCE.
+func (s *WalletService) ReverseTopUp(ctx context.Context, topUpID uuid.UUID, reason string) (models.Reversal, error) {
+	if err := requireReason(reason); err != nil {
+		return models.Reversal{}, err
+	}
+
+	var reversal models.Reversal
+	var user models.User
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		topUp, err := repos.TopUps.FindByID(ctx, topUpID)
+		if err != nil {
+			return lookupError(err, apperrors.ErrTransactionNotFound)
+		}
+		if err := repos.TopUps.MarkReversed(ctx, topUp.ID, s.now()); err != nil {
+			return reversedError(err)
+		}
+		changed, err := s.restore(ctx, repos, topUp.UserID, topUp.Currency, topUp.PocketID, -topUp.Amount)
+		if err != nil {
+			return err
+		}
+		user = changed.user
+
+		reversal = s.reversal(ctx, changed, models.ReversalDebit, topUp.Amount, reason)
+		reversal.TopUpID = &topUp.ID
+		if err := repos.Reversals.Create(ctx, &reversal); err != nil {
+			return err
+		}
+		return audit(ctx, repos, user.ID, ActionReverseTopUp, reason, withPocket(map[string]interface{}{
+			"reversal_id":   reversal.ID,
+			"top_up_id":     topUp.ID,
+			"amount":        reversal.Amount,
+			"currency":      reversal.Currency,
+			"balance_after": reversal.BalanceAfter,
+		}, reversal.PocketID), reversal.CreatedDate)
+	})
+	if err != nil {
+		return models.Reversal{}, err
+	}
+
+	s.notifyReversal(ctx, user, reversal)
+	return reversal, nil
+}
+
+// TransferReversal is a reversed transfer as each party sees it.
+type TransferReversal struct {
+	Sender    models.Reversal
+	Recipient models.Reversal
+}
+
+// ReverseTransfer takes a transfer made in error back from its recipient and
+// returns it to its sender, on an administrator's behalf. Each side gets
+// back exactly what moved, so a converted transfer is reversed at its own
+// rate.
+func (s *WalletService) ReverseTransfer(ctx context.Context, transferID uuid.UUID, reason string) (TransferReversal, error) {
+	if err := requireReason(reason); err != nil {
+		return TransferReversal{}, err
+	}
+
+	var result TransferReversal
+	var sender, recipient adjusted
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		transfer, err := repos.Transfers.FindByID(ctx, transferID)
+		if err != nil {
+			return lookupError(err, apperrors.ErrTransactionNotFound)
+		}
+		if err := repos.Transfers.MarkReversed(ctx, transfer.ID, s.now()); err != nil {
+			return reversedError(err)
+		}
+		// Transfers made before wallets held other currencies have no
+		// converted amount.
+		toAmount, toCurrency := transfer.ToAmount, transfer.ToCurrency
+		if toCurrency == "" {
+			toAmount, toCurrency = transfer.Amount, transfer.Currency
+		}
+
+		credit := func() (err error) {
+			sender, err = s.restore(ctx, repos, transfer.FromUserID, transfer.Currency, nil, transfer.Amount)
+			return err
+		}
+		debit := func() (err error) {
+			recipient, err = s.restore(ctx, repos, transfer.ToUserID, toCurrency, nil, -toAmount)
+			return err
+		}
+		// Rows are updated in ID order, as in Transfer.
+		steps := []func() error{credit, debit}
+		if bytes.Compare(transfer.ToUserID[:], transfer.FromUserID[:]) < 0 {
+			steps = []func() error{debit, credit}
+		}
+		for _, step := range steps {
+			if err := step(); err != nil {
+				return err
+			}
+		}
+
+		result.Sender = s.reversal(ctx, sender, models.ReversalCredit, transfer.Amount, reason)
+		result.Recipient = s.reversal(ctx, recipient, models.ReversalDebit, toAmount, reason)
+		for _, reversal := range []*models.Reversal{&result.Sender, &result.Recipient} {
+			reversal.TransferID = &transfer.ID
+			if err := repos.Reversals.Create(ctx, reversal); err != nil {
+				return err
+			}
+			if err := audit(ctx, repos, reversal.UserID, ActionReverseTransfer, reason, map[string]interface{}{
+				"reversal_id":   reversal.ID,
+				"transfer_id":   transfer.ID,
+				"amount":        reversal.Amount,
+				"currency":      reversal.Currency,
+				"balance_after": reversal.BalanceAfter,
+			}, reversal.CreatedDate); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return TransferReversal{}, err
+	}
+
+	s.notifyReversal(ctx, sender.user, result.Sender)
+	s.notifyReversal(ctx, recipient.user, result.Recipient)
+	return result, nil
+}
+
+// restore adds delta back to the account an earlier record was booked on:
+// the pocket while it is still open, the main balance in currency
+// otherwise. Unlike adjustAccount it accepts currencies the exchange no
+// longer supports, since the money is already held in them.
+func (s *WalletService) restore(ctx context.Context, repos repository.Repositories, userID uuid.UUID, currency string, pocketID *uuid.UUID, delta float64) (adjusted, error) {
+	if pocketID != nil {
+		if _, err := findPocket(ctx, repos, userID, *pocketID); err == nil {
+			return s.adjustAccount(ctx, repos, userID, Account{PocketID: *pocketID}, delta)
+		}
+	}
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	user, balance, err := adjust(ctx, repos, userID, currency, delta)
+	if err != nil {
+		return adjusted{}, balanceError(err, apperrors.ErrUserNotFound)
+	}
+	return adjusted{user: user, balance: balance, currency: currency}, nil
+}
+
+// reversal is the record of amount being credited or debited to the
+// changed account.
+func (s *WalletService) reversal(ctx context.Context, changed adjusted, transactionType string, amount float64, reason string) models.Reversal {
+	before := changed.balance - amount
+	if transactionType == models.ReversalDebit {
+		before = changed.balance + amount
+	}
+	return models.Reversal{
+		UserID:          changed.user.ID,
+		TransactionType: transactionType,
+		Amount:          amount,
+		Currency:        changed.currency,
+		PocketID:        changed.pocketID,
+		Reason:          reason,
+		Actor:           actor(ctx, changed.user.ID),
+		Status:          models.ReversalSucceeded,
+		BalanceBefore:   before,
+		BalanceAfter:    changed.balance,
+		CreatedDate:     s.now(),
+	}
+}
+
+func (s *WalletService) notifyReversal(ctx context.Context, user models.User, reversal models.Reversal) {
+	key := "reversal_credited"
+	if reversal.TransactionType == models.ReversalDebit {
+		key = "reversal_debited"
+	}
+	notify(ctx, s.notifier, user, key, func(lang i18n.Language) i18n.Params {
+		return i18n.Params{
+			"amount":  i18n.FormatMoney(lang, reversal.Amount, reversal.Currency),
+			"reason":  reversal.Reason,
+			"balance": i18n.FormatMoney(lang, reversal.BalanceAfter, reversal.Currency),
+		}
+	})
+}
+
+func requireReason(reason string) error {
+	if reason == "" {
+		return apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"reason": {Rule: "required"},
+		})
+	}
+	return nil
+}
+
+// reversedError maps a failure to mark a record reversed onto a domain
+// error.
+func reversedError(err error) error {
+	if errors.Is(err, repository.ErrStale) {
+		return apperrors.ErrAlreadyReversed.Wrap(err)
+	}
+	return err
+}
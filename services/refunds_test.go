@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"myapp/apperrors"
+	"myapp/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefundsNeverExceedThePayment(t *testing.T) {
+	wallet, store, notifier, users := walletFixture(1000)
+	ctx := context.Background()
+	userID := users[0].ID
+	payment, err := wallet.Pay(ctx, userID, PaymentOrder{Amount: 300, Merchant: "TOKO", Remarks: "Shoes"})
+	if err != nil {
+		t.Fatalf("Failed to pay: %v", err)
+	}
+
+	partial, err := wallet.Refund(ctx, RefundOrder{PaymentID: payment.ID, Amount: 100, Merchant: "TOKO", Reason: "Wrong size"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReversalCredit, partial.Refund.TransactionType)
+	assert.Equal(t, models.ReversalSucceeded, partial.Refund.Status)
+	assert.Equal(t, "merchant:TOKO", partial.Refund.Actor)
+	assert.Equal(t, 800.0, partial.Refund.BalanceAfter)
+	assert.Equal(t, 100.0, partial.Payment.RefundedAmount)
+
+	_, err = wallet.Refund(ctx, RefundOrder{PaymentID: payment.ID, Amount: 201, Merchant: "TOKO"})
+	assert.True(t, errors.Is(err, apperrors.ErrRefundExceedsPayment))
+	_, err = wallet.Refund(ctx, RefundOrder{PaymentID: payment.ID, Merchant: "WARUNG"})
+	assert.True(t, errors.Is(err, apperrors.ErrPaymentNotFound), "merchants only see their own payments")
+
+	rest, err := wallet.Refund(ctx, RefundOrder{PaymentID: payment.ID, Merchant: "TOKO"})
+	assert.NoError(t, err)
+	assert.Equal(t, 200.0, rest.Refund.Amount, "without an amount the rest is refunded")
+	assert.Equal(t, 1000.0, store.data.users[userID].Balance)
+	_, err = wallet.Refund(ctx, RefundOrder{PaymentID: payment.ID})
+	assert.True(t, errors.Is(err, apperrors.ErrRefundExceedsPayment))
+
+	history, err := wallet.History(ctx, userID)
+	assert.NoError(t, err)
+	assert.Len(t, history.Reversals, 2)
+	if assert.Len(t, notifier.sent, 3) {
+		assert.Equal(t, "refund_received", notifier.sent[2].Key)
+	}
+}
+
+func TestRefundsGoBackToTheAccountPaidFrom(t *testing.T) {
+	wallet, store, _, users := walletFixture(1000)
+	ctx := context.Background()
+	userID := users[0].ID
+	savings, err := wallet.CreatePocket(ctx, userID, "Savings", "")
+	if err != nil {
+		t.Fatalf("Failed to create pocket: %v", err)
+	}
+	if _, err := wallet.MovePocketMoney(ctx, PocketMove{UserID: userID, To: savings.ID, Amount: 500}); err != nil {
+		t.Fatalf("Failed to move money: %v", err)
+	}
+	first, err := wallet.Pay(ctx, userID, PaymentOrder{Amount: 100, Account: Account{PocketID: savings.ID}})
+	if err != nil {
+		t.Fatalf("Failed to pay: %v", err)
+	}
+	second, err := wallet.Pay(ctx, userID, PaymentOrder{Amount: 100, Account: Account{PocketID: savings.ID}})
+	if err != nil {
+		t.Fatalf("Failed to pay: %v", err)
+	}
+
+	refund, err := wallet.Refund(ctx, RefundOrder{PaymentID: first.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, &savings.ID, refund.Refund.PocketID)
+	assert.Equal(t, 400.0, store.data.pockets[savings.ID].Balance)
+
+	if _, _, err := wallet.ClosePocket(ctx, userID, savings.ID); err != nil {
+		t.Fatalf("Failed to close pocket: %v", err)
+	}
+	refund, err = wallet.Refund(ctx, RefundOrder{PaymentID: second.ID})
+	assert.NoError(t, err)
+	assert.Nil(t, refund.Refund.PocketID, "closed pockets are refunded to the main balance")
+	assert.Equal(t, 1000.0, store.data.users[userID].Balance)
+}
+
+func TestReversingATopUp(t *testing.T) {
+	wallet, store, notifier, users := walletFixture(0)
+	ctx := WithActor(context.Background(), "admin:budi")
+	userID := users[0].ID
+	topUp, err := wallet.TopUp(ctx, userID, 500, Account{})
+	if err != nil {
+		t.Fatalf("Failed to top up: %v", err)
+	}
+
+	_, err = wallet.ReverseTopUp(ctx, topUp.ID, "")
+	assert.True(t, errors.Is(err, apperrors.ErrValidationFailed), "a reason is required")
+	_, err = wallet.ReverseTopUp(ctx, uuid.New(), "Duplicate")
+	assert.True(t, errors.Is(err, apperrors.ErrTransactionNotFound))
+
+	reversal, err := wallet.ReverseTopUp(ctx, topUp.ID, "Duplicate")
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReversalDebit, reversal.TransactionType)
+	assert.Equal(t, "admin:budi", reversal.Actor)
+	assert.Equal(t, 500.0, reversal.BalanceBefore)
+	assert.Equal(t, 0.0, store.data.users[userID].Balance)
+	assert.NotNil(t, store.data.topUps[0].ReversedAt)
+	if assert.Len(t, notifier.sent, 2) {
+		assert.Equal(t, "reversal_debited", notifier.sent[1].Key)
+	}
+
+	_, err = wallet.ReverseTopUp(ctx, topUp.ID, "Duplicate")
+	assert.True(t, errors.Is(err, apperrors.ErrAlreadyReversed))
+}
+
+func TestReversingATopUpThatWasSpent(t *testing.T) {
+	wallet, store, _, users := walletFixture(0)
+	ctx := context.Background()
+	topUp, err := wallet.TopUp(ctx, users[0].ID, 500, Account{})
+	if err != nil {
+		t.Fatalf("Failed to top up: %v", err)
+	}
+	if _, err := wallet.Pay(ctx, users[0].ID, PaymentOrder{Amount: 200}); err != nil {
+		t.Fatalf("Failed to pay: %v", err)
+	}
+
+	_, err = wallet.ReverseTopUp(ctx, topUp.ID, "Duplicate")
+
+	assert.True(t, errors.Is(err, apperrors.ErrInsufficientBalance))
+	assert.Nil(t, store.data.topUps[0].ReversedAt, "a failed reversal can be retried")
+}
+
+func TestReversingATransferReturnsWhatMoved(t *testing.T) {
+	wallet, store, notifier, users := walletFixture(1000, 0)
+	ctx := WithActor(context.Background(), "admin:budi")
+	result, err := wallet.Transfer(ctx, TransferOrder{From: users[0].ID, To: users[1].ID, Amount: 400})
+	if err != nil {
+		t.Fatalf("Failed to transfer: %v", err)
+	}
+
+	reversal, err := wallet.ReverseTransfer(ctx, result.Transfer.ID, "Wrong recipient")
+	assert.NoError(t, err)
+	assert.Equal(t, users[0].ID, reversal.Sender.UserID)
+	assert.Equal(t, models.ReversalCredit, reversal.Sender.TransactionType)
+	assert.Equal(t, users[1].ID, reversal.Recipient.UserID)
+	assert.Equal(t, models.ReversalDebit, reversal.Recipient.TransactionType)
+	assert.Equal(t, 1000.0, store.data.users[users[0].ID].Balance)
+	assert.Equal(t, 0.0, store.data.users[users[1].ID].Balance)
+	assert.Equal(t, models.TransferReversed, store.data.transfers[0].Status)
+	assert.Len(t, notifier.sent, 4)
+
+	_, err = wallet.ReverseTransfer(ctx, result.Transfer.ID, "Wrong recipient")
+	assert.True(t, errors.Is(err, apperrors.ErrAlreadyReversed))
+	history, err := wallet.History(ctx, users[1].ID)
+	assert.NoError(t, err)
+	assert.Len(t, history.Reversals, 1)
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"myapp/apperrors"
+	"myapp/i18n"
+	"myapp/models"
+	"myapp/repository"
+	"myapp/tracing"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MoneyRequestTTL is how long a money request waits for its payer.
+	MoneyRequestTTL = 7 * 24 * time.Hour
+	// MaxPendingRequests is how many pending money requests a user may
+	// have made.
+	MaxPendingRequests = 10
+	// MaxDailyRequestsPerPayer is how many money requests a user may make
+	// of the same payer within a day, however they were answered.
+	MaxDailyRequestsPerPayer = 3
+)
+
+// MoneyRequestOrder asks Payer to pay the requester, From, an amount.
+type MoneyRequestOrder struct {
+	From   uuid.UUID
+	Payer  uuid.UUID
+	Amount float64
+	// Currency is models.DefaultCurrency when empty.
+	Currency string
+	Remarks  string
+}
+
+// AcceptedRequest is a money request with the transfer that paid it.
+type AcceptedRequest struct {
+	Request models.MoneyRequest
+	TransferResult
+}
+
+// RequestMoney asks the payer for money and tells them about it. A user may
+// not have the same amount pending with the same payer twice, nor make more
+// requests than the limits allow, nor make any while their account is
+// frozen.
+func (s *WalletService) RequestMoney(ctx context.Context, order MoneyRequestOrder) (models.MoneyRequest, error) {
+	if order.From == order.Payer {
+		return models.MoneyRequest{}, apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"target_user": {Rule: "self_transfer"},
+		})
+	}
+	remarks := strings.TrimSpace(order.Remarks)
+	if remarks == "" {
+		return models.MoneyRequest{}, apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"remarks": {Rule: "required"},
+		})
+	}
+	currency, err := s.currency("currency", order.Currency)
+	if err != nil {
+		return models.MoneyRequest{}, err
+	}
+
+	now := s.now()
+	request := models.MoneyRequest{
+		RequesterID: order.From,
+		PayerID:     order.Payer,
+		Amount:      order.Amount,
+		Currency:    currency,
+		Remarks:     remarks,
+		Status:      models.RequestPending,
+		ExpiresAt:   now.Add(MoneyRequestTTL),
+		CreatedDate: now,
+	}
+	var requester, payer models.User
+	err = s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		var err error
+		// Locking the requester makes their concurrent requests check the
+		// limits one after another, each seeing the ones made before it.
+		if requester, err = repos.Users.FindForUpdate(ctx, order.From); err != nil {
+			return lookupError(err, apperrors.ErrUserNotFound)
+		}
+		if requester.Frozen() {
+			return apperrors.ErrAccountFrozen
+		}
+		if payer, err = repos.Users.FindByID(ctx, order.Payer); err != nil {
+			return lookupError(err, apperrors.ErrTargetUserNotFound)
+		}
+		// Requests older than their lifetime are closed and too old to
+		// count towards any limit.
+		recent, err := repos.Requests.ListByRequester(ctx, order.From, now.Add(-MoneyRequestTTL))
+		if err != nil {
+			return err
+		}
+		if err := checkRequestLimits(recent, request, now); err != nil {
+			return err
+		}
+
+		if err := repos.Requests.Create(ctx, &request); err != nil {
+			return err
+		}
+		return audit(ctx, repos, order.From, ActionRequestCreate, "", map[string]interface{}{
+			"request_id": request.ID,
+			"payer_id":   order.Payer,
+			"amount":     request.Amount,
+			"currency":   request.Currency,
+		}, now)
+	})
+	if err != nil {
+		return models.MoneyRequest{}, err
+	}
+
+	notify(ctx, s.notifier, payer, "money_request_received", func(lang i18n.Language) i18n.Params {
+		return i18n.Params{
+			"amount":  i18n.FormatMoney(lang, request.Amount, request.Currency),
+			"name":    displayName(requester),
+			"remarks": request.Remarks,
+		}
+	})
+	return request, nil
+}
+
+// IncomingRequests returns the requests the user is asked to pay that are
+// still pending, oldest first. It may be served by a read replica.
+func (s *WalletService) IncomingRequests(ctx context.Context, userID uuid.UUID) ([]models.MoneyRequest, error) {
+	repos := s.store.Reader(userID)
+	if _, err := repos.Users.FindByID(ctx, userID); err != nil {
+		return nil, lookupError(err, apperrors.ErrUserNotFound)
+	}
+	return repos.Requests.ListPendingByPayer(ctx, userID, s.now())
+}
+
+// OutgoingRequests returns the requests the user made, newest first, the
+// expired ones as such. It may be served by a read replica.
+func (s *WalletService) OutgoingRequests(ctx context.Context, userID uuid.UUID) ([]models.MoneyRequest, error) {
+	repos := s.store.Reader(userID)
+	if _, err := repos.Users.FindByID(ctx, userID); err != nil {
+		return nil, lookupError(err, apperrors.ErrUserNotFound)
+	}
+	requests, err := repos.Requests.ListByRequester(ctx, userID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	expire(requests, s.now())
+	return requests, nil
+}
+
+// AcceptRequest pays one of the requests the user was asked to pay by
+// transferring its amount to the requester. The request is closed in the
+// same transaction, so it is paid at most once.
+func (s *WalletService) AcceptRequest(ctx context.Context, payerID, id uuid.UUID) (AcceptedRequest, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WalletService.AcceptRequest")
+	defer span.End()
+
+	accepted, err := s.acceptRequest(ctx, payerID, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return AcceptedRequest{}, err
+	}
+	s.notifyTransfer(ctx, accepted.TransferResult)
+	return accepted, nil
+}
+
+func (s *WalletService) acceptRequest(ctx context.Context, payerID, id uuid.UUID) (AcceptedRequest, error) {
+	now := s.now()
+	request, err := findRequest(ctx, s.store.Repositories(), id, payerID, true)
+	if err != nil {
+		return AcceptedRequest{}, err
+	}
+	if err := closedError(request, now); err != nil {
+		return AcceptedRequest{}, err
+	}
+	priced, err := s.price(ctx, TransferOrder{
+		From:     payerID,
+		To:       request.RequesterID,
+		Amount:   request.Amount,
+		Currency: request.Currency,
+		Remarks:  request.Remarks,
+	})
+	if err != nil {
+		return AcceptedRequest{}, err
+	}
+	priced.requestID = &request.ID
+
+	var accepted AcceptedRequest
+	err = s.store.Transaction(ctx, func(repos repository.Repositories) (err error) {
+		if accepted.TransferResult, err = s.book(ctx, repos, priced); err != nil {
+			return err
+		}
+		transferID := accepted.Transfer.ID
+		if err := respond(ctx, repos, id, models.RequestAccepted, now, &transferID); err != nil {
+			return err
+		}
+		accepted.Request, err = repos.Requests.FindByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return AcceptedRequest{}, err
+	}
+	return accepted, nil
+}
+
+// DeclineRequest refuses to pay one of the requests the user was asked to
+// pay, and tells the requester so.
+func (s *WalletService) DeclineRequest(ctx context.Context, payerID, id uuid.UUID) (models.MoneyRequest, error) {
+	now := s.now()
+	var request models.MoneyRequest
+	var requester, payer models.User
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		var err error
+		if request, err = findRequest(ctx, repos, id, payerID, true); err != nil {
+			return err
+		}
+		if err := respond(ctx, repos, id, models.RequestDeclined, now, nil); err != nil {
+			return err
+		}
+		if requester, err = repos.Users.FindByID(ctx, request.RequesterID); err != nil {
+			return err
+		}
+		if payer, err = repos.Users.FindByID(ctx, payerID); err != nil {
+			return err
+		}
+		request.Status, request.RespondedAt = models.RequestDeclined, &now
+		return audit(ctx, repos, payerID, ActionRequestDecline, "", map[string]interface{}{
+			"request_id": id,
+		}, now)
+	})
+	if err != nil {
+		return models.MoneyRequest{}, err
+	}
+
+	notify(ctx, s.notifier, requester, "money_request_declined", func(lang i18n.Language) i18n.Params {
+		return i18n.Params{
+			"amount": i18n.FormatMoney(lang, request.Amount, request.Currency),
+			"name":   displayName(payer),
+		}
+	})
+	return request, nil
+}
+
+// CancelRequest withdraws one of the requests the user made before it is
+// answered.
+func (s *WalletService) CancelRequest(ctx context.Context, requesterID, id uuid.UUID) (models.MoneyRequest, error) {
+	now := s.now()
+	var request models.MoneyRequest
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		var err error
+		if request, err = findRequest(ctx, repos, id, requesterID, false); err != nil {
+			return err
+		}
+		if err := respond(ctx, repos, id, models.RequestCancelled, now, nil); err != nil {
+			return err
+		}
+		request.Status, request.RespondedAt = models.RequestCancelled, &now
+		return audit(ctx, repos, requesterID, ActionRequestCancel, "", map[string]interface{}{
+			"request_id": id,
+		}, now)
+	})
+	if err != nil {
+		return models.MoneyRequest{}, err
+	}
+	return request, nil
+}
+
+// checkRequestLimits fails when request would repeat a pending one or make
+// the requester exceed the request limits. recent holds the requester's
+// requests that could still be pending.
+func checkRequestLimits(recent []models.MoneyRequest, request models.MoneyRequest, now time.Time) error {
+	pending, toPayer := 0, 0
+	for _, other := range recent {
+		if other.PayerID == request.PayerID && now.Sub(other.CreatedDate) < 24*time.Hour {
+			toPayer++
+		}
+		if other.StatusAt(now) != models.RequestPending {
+			continue
+		}
+		if other.PayerID == request.PayerID && other.Amount == request.Amount && other.Currency == request.Currency {
+			return apperrors.ErrDuplicateRequest
+		}
+		pending++
+	}
+	if pending >= MaxPendingRequests || toPayer >= MaxDailyRequestsPerPayer {
+		return apperrors.ErrRequestLimitReached
+	}
+	return nil
+}
+
+// findRequest returns a request the user made, or was asked to pay when
+// asPayer is set. Requests of other users are not found.
+func findRequest(ctx context.Context, repos repository.Repositories, id, userID uuid.UUID, asPayer bool) (models.MoneyRequest, error) {
+	request, err := repos.Requests.FindByID(ctx, id)
+	party := request.RequesterID
+	if asPayer {
+		party = request.PayerID
+	}
+	if err == nil && party != userID {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		return models.MoneyRequest{}, lookupError(err, apperrors.ErrRequestNotFound)
+	}
+	return request, nil
+}
+
+// respond closes a pending request, failing with MONEY_REQUEST_EXPIRED or
+// MONEY_REQUEST_CLOSED when it no longer is.
+func respond(ctx context.Context, repos repository.Repositories, id uuid.UUID, status string, now time.Time, transferID *uuid.UUID) error {
+	err := repos.Requests.Respond(ctx, id, status, now, transferID)
+	if !errors.Is(err, repository.ErrStale) {
+		return err
+	}
+	request, findErr := repos.Requests.FindByID(ctx, id)
+	if findErr != nil {
+		return findErr
+	}
+	if closed := closedError(request, now); closed != nil {
+		return closed
+	}
+	return apperrors.ErrRequestClosed.Wrap(err)
+}
+
+// closedError returns why request can no longer be answered at now, or
+// nil when it can.
+func closedError(request models.MoneyRequest, now time.Time) error {
+	switch request.StatusAt(now) {
+	case models.RequestPending:
+		return nil
+	case models.RequestExpired:
+		return apperrors.ErrRequestExpired
+	default:
+		return apperrors.ErrRequestClosed
+	}
+}
+
+// expire marks the pending requests that expired by now as such.
+func expire(requests []models.MoneyRequest, now time.Time) {
+	for i := range requests {
+		requests[i].Status = requests[i].StatusAt(now)
+	}
+}
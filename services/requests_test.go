@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"myapp/apperrors"
+	"myapp/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptingRequestPaysItOnce(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	wallet, store, notifier, users := walletFixture(0, 1000)
+	wallet.now = func() time.Time { return now }
+	ctx := context.Background()
+	requester, payer := users[0].ID, users[1].ID
+
+	request, err := wallet.RequestMoney(ctx, MoneyRequestOrder{From: requester, Payer: payer, Amount: 150, Remarks: " Dinner "})
+	if err != nil {
+		t.Fatalf("Failed to request money: %v", err)
+	}
+	assert.Equal(t, "Dinner", request.Remarks)
+	assert.Equal(t, models.DefaultCurrency, request.Currency)
+	assert.Equal(t, now.Add(MoneyRequestTTL), request.ExpiresAt)
+	if assert.Len(t, notifier.sent, 1) {
+		assert.Equal(t, payer, notifier.sent[0].UserID)
+		assert.Equal(t, "money_request_received", notifier.sent[0].Key)
+	}
+
+	incoming, err := wallet.IncomingRequests(ctx, payer)
+	assert.NoError(t, err)
+	assert.Len(t, incoming, 1)
+	_, err = wallet.AcceptRequest(ctx, requester, request.ID)
+	assert.True(t, errors.Is(err, apperrors.ErrRequestNotFound), "requesters cannot pay themselves")
+
+	accepted, err := wallet.AcceptRequest(ctx, payer, request.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RequestAccepted, accepted.Request.Status)
+	assert.Equal(t, &accepted.Transfer.ID, accepted.Request.TransferID)
+	assert.Equal(t, "Dinner", accepted.Transfer.Remarks)
+	assert.Equal(t, 150.0, store.data.users[requester].Balance)
+	assert.Equal(t, 850.0, store.data.users[payer].Balance)
+
+	_, err = wallet.AcceptRequest(ctx, payer, request.ID)
+	assert.True(t, errors.Is(err, apperrors.ErrRequestClosed))
+	_, err = wallet.DeclineRequest(ctx, payer, request.ID)
+	assert.True(t, errors.Is(err, apperrors.ErrRequestClosed))
+	assert.Len(t, store.data.transfers, 1)
+
+	incoming, _ = wallet.IncomingRequests(ctx, payer)
+	assert.Empty(t, incoming)
+	history, err := wallet.History(ctx, requester)
+	assert.NoError(t, err)
+	assert.Len(t, history.Requests, 1)
+}
+
+func TestRequestsExpireAndCanBeDeclinedOrCancelled(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	wallet, store, notifier, users := walletFixture(0, 1000)
+	wallet.now = func() time.Time { return now }
+	ctx := context.Background()
+	order := MoneyRequestOrder{From: users[0].ID, Payer: users[1].ID, Amount: 100, Remarks: "Rent"}
+
+	expiring, err := wallet.RequestMoney(ctx, order)
+	if err != nil {
+		t.Fatalf("Failed to request money: %v", err)
+	}
+	order.Amount = 200
+	declined, err := wallet.RequestMoney(ctx, order)
+	if err != nil {
+		t.Fatalf("Failed to request money: %v", err)
+	}
+	order.Amount = 300
+	cancelled, err := wallet.RequestMoney(ctx, order)
+	if err != nil {
+		t.Fatalf("Failed to request money: %v", err)
+	}
+
+	_, err = wallet.DeclineRequest(ctx, users[1].ID, declined.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, notifier.sent, 4) {
+		assert.Equal(t, users[0].ID, notifier.sent[3].UserID)
+		assert.Equal(t, "money_request_declined", notifier.sent[3].Key)
+	}
+	_, err = wallet.CancelRequest(ctx, users[1].ID, cancelled.ID)
+	assert.True(t, errors.Is(err, apperrors.ErrRequestNotFound), "only requesters cancel")
+	_, err = wallet.CancelRequest(ctx, users[0].ID, cancelled.ID)
+	assert.NoError(t, err)
+
+	now = now.Add(MoneyRequestTTL)
+	_, err = wallet.AcceptRequest(ctx, users[1].ID, expiring.ID)
+	assert.True(t, errors.Is(err, apperrors.ErrRequestExpired))
+	assert.Empty(t, store.data.transfers)
+	assert.Equal(t, 1000.0, store.data.users[users[1].ID].Balance)
+
+	outgoing, err := wallet.OutgoingRequests(ctx, users[0].ID)
+	assert.NoError(t, err)
+	statuses := map[float64]string{}
+	for _, request := range outgoing {
+		statuses[request.Amount] = request.Status
+	}
+	assert.Equal(t, map[float64]string{
+		100: models.RequestExpired,
+		200: models.RequestDeclined,
+		300: models.RequestCancelled,
+	}, statuses)
+}
+
+func TestRequestMoneyRejectsDuplicatesAndSpam(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	wallet, _, _, users := walletFixture(0, 0, 0)
+	wallet.now = func() time.Time { return now }
+	ctx := context.Background()
+	order := MoneyRequestOrder{From: users[0].ID, Payer: users[1].ID, Amount: 100, Remarks: "Lunch"}
+
+	for name, change := range map[string]func(o *MoneyRequestOrder){
+		"self":     func(o *MoneyRequestOrder) { o.Payer = o.From },
+		"remarks":  func(o *MoneyRequestOrder) { o.Remarks = "  " },
+		"currency": func(o *MoneyRequestOrder) { o.Currency = "XYZ" },
+	} {
+		invalid := order
+		change(&invalid)
+		_, err := wallet.RequestMoney(ctx, invalid)
+		assert.Error(t, err, name)
+	}
+
+	first, err := wallet.RequestMoney(ctx, order)
+	if err != nil {
+		t.Fatalf("Failed to request money: %v", err)
+	}
+	_, err = wallet.RequestMoney(ctx, order)
+	assert.True(t, errors.Is(err, apperrors.ErrDuplicateRequest))
+
+	// Declined requests still count towards the daily limit per payer.
+	if _, err := wallet.DeclineRequest(ctx, users[1].ID, first.ID); err != nil {
+		t.Fatalf("Failed to decline request: %v", err)
+	}
+	for i := 1; i < MaxDailyRequestsPerPayer; i++ {
+		order.Amount = float64(100 + i)
+		if _, err := wallet.RequestMoney(ctx, order); err != nil {
+			t.Fatalf("Failed to request money: %v", err)
+		}
+	}
+	order.Amount = 500
+	_, err = wallet.RequestMoney(ctx, order)
+	assert.True(t, errors.Is(err, apperrors.ErrRequestLimitReached))
+
+	now = now.Add(24 * time.Hour)
+	_, err = wallet.RequestMoney(ctx, order)
+	assert.NoError(t, err)
+
+	order.Payer = users[2].ID
+	for i := 0; i < MaxPendingRequests; i++ {
+		order.Amount = float64(1 + i)
+		now = now.Add(9 * time.Hour)
+		_, err = wallet.RequestMoney(ctx, order)
+	}
+	assert.True(t, errors.Is(err, apperrors.ErrRequestLimitReached), "too many requests are pending")
+}
+
+func TestFrozenAccountCannotRequestMoney(t *testing.T) {
+	wallet, store, notifier, users := walletFixture(0, 1000)
+	ctx := context.Background()
+	frozen := store.data.users[users[0].ID]
+	frozenAt := frozen.CreatedDate
+	frozen.FrozenAt = &frozenAt
+	store.data.users[frozen.ID] = frozen
+
+	_, err := wallet.RequestMoney(ctx, MoneyRequestOrder{From: users[0].ID, Payer: users[1].ID, Amount: 100, Remarks: "Lunch"})
+	assert.True(t, errors.Is(err, apperrors.ErrAccountFrozen))
+	assert.Empty(t, store.data.requests)
+	assert.Empty(t, notifier.sent)
+}
+
+func TestConcurrentIdenticalRequestsAreMadeOnce(t *testing.T) {
+	wallet, store, _, users := walletFixture(0, 0)
+	store.delay = 10 * time.Millisecond
+	ctx := context.Background()
+	order := MoneyRequestOrder{From: users[0].ID, Payer: users[1].ID, Amount: 100, Remarks: "Lunch"}
+
+	const attempts = 5
+	errs := make(chan error, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := wallet.RequestMoney(ctx, order)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	made := 0
+	for err := range errs {
+		if err == nil {
+			made++
+			continue
+		}
+		assert.True(t, errors.Is(err, apperrors.ErrDuplicateRequest), "unexpected error: %v", err)
+	}
+	assert.Equal(t, 1, made)
+	assert.Len(t, store.data.requests, 1)
+}
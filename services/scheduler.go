@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"myapp/apperrors"
+	"myapp/i18n"
+	"myapp/models"
+	"myapp/repository"
+	"myapp/tracing"
+)
+
+// SchedulerOptions tune a Scheduler.
+type SchedulerOptions struct {
+	// Interval is how often the scheduler looks for due transfers.
+	Interval time.Duration
+	// RetryDelay is how long after a failed attempt an occurrence is tried
+	// again, and MaxAttempts how often it is tried in all before it is
+	// given up.
+	RetryDelay  time.Duration
+	MaxAttempts int
+	// BatchSize is the most occurrences made per look.
+	BatchSize int
+}
+
+// Scheduler makes the transfers users scheduled when they fall due.
+//
+// Every instance of the app may run one. An occurrence is made in the same
+// transaction as the version-guarded update that moves its schedule on, so
+// when several instances race for it only one commits; the unique index on
+// the transfers of a schedule backs this up. Occurrences missed while no
+// scheduler ran are made once, not once each.
+//
+// An attempt that fails, e.g. for want of balance, is retried after
+// RetryDelay; after MaxAttempts the occurrence is given up, and a one-off
+// transfer fails. The sender is notified either way.
+type Scheduler struct {
+	wallet *WalletService
+	opts   SchedulerOptions
+	done   chan struct{}
+}
+
+func NewScheduler(wallet *WalletService, opts SchedulerOptions) *Scheduler {
+	return &Scheduler{wallet: wallet, opts: opts, done: make(chan struct{})}
+}
+
+// Run makes due transfers every interval until ctx is done. The occurrence
+// being made then is still finished.
+func (s *Scheduler) Run(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.RunDue(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "scheduled transfers failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown waits for Run to return after its context is done, or for ctx
+// to be done, whichever comes first.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunDue makes the transfers that are due now, up to the batch size, and
+// returns how many it made. Failed attempts are recorded on their schedule
+// rather than returned.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "Scheduler.RunDue")
+	defer span.End()
+
+	now := s.wallet.now()
+	due, err := s.wallet.store.Repositories().Schedules.ListDue(ctx, now, s.opts.BatchSize)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	made := 0
+	for _, scheduled := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		// A transfer must not be abandoned half-way on shutdown.
+		ok, err := s.run(tracing.Detach(ctx), scheduled, now)
+		if err != nil {
+			slog.ErrorContext(ctx, "unable to record failed scheduled transfer",
+				"schedule_id", scheduled.ID.String(), "error", err)
+		}
+		if ok {
+			made++
+		}
+	}
+	return made, nil
+}
+
+// run makes the next occurrence of scheduled and reports whether it did.
+// Losing the race for it to another instance is not an error.
+func (s *Scheduler) run(ctx context.Context, scheduled models.ScheduledTransfer, now time.Time) (bool, error) {
+	w := s.wallet
+	occurrence := *scheduled.NextRunAt
+	priced, err := w.price(ctx, TransferOrder{
+		From:       scheduled.UserID,
+		To:         scheduled.ToUserID,
+		Amount:     scheduled.Amount,
+		Currency:   scheduled.Currency,
+		ToCurrency: scheduled.ToCurrency,
+		Remarks:    scheduled.Remarks,
+	})
+	if err == nil {
+		priced.scheduleID, priced.occurrence = &scheduled.ID, &occurrence
+		var result TransferResult
+		err = w.store.Transaction(ctx, func(repos repository.Repositories) error {
+			claimed := scheduled
+			s.advance(&claimed, occurrence, now)
+			if err := repos.Schedules.Update(ctx, &claimed); err != nil {
+				return err
+			}
+			var err error
+			result, err = w.book(ctx, repos, priced)
+			return err
+		})
+		switch {
+		case err == nil:
+			w.notifyTransfer(ctx, result)
+			return true, nil
+		case errors.Is(err, repository.ErrStale):
+			return false, nil
+		case errors.Is(err, repository.ErrDuplicate):
+			// The occurrence was made already; only the schedule is behind.
+			return false, s.skip(ctx, scheduled, occurrence, now)
+		}
+	}
+	return false, s.fail(ctx, scheduled, err, now)
+}
+
+// advance moves scheduled on past occurrence, completing it when no
+// occurrence is left.
+func (s *Scheduler) advance(scheduled *models.ScheduledTransfer, occurrence, now time.Time) {
+	scheduled.LastRunAt = &now
+	scheduled.UpdatedDate = now
+	after := occurrence
+	if now.After(after) {
+		after = now
+	}
+	if !plan(scheduled, after) {
+		scheduled.Status = models.ScheduleCompleted
+	}
+}
+
+func (s *Scheduler) skip(ctx context.Context, scheduled models.ScheduledTransfer, occurrence, now time.Time) error {
+	s.advance(&scheduled, occurrence, now)
+	err := s.wallet.store.Repositories().Schedules.Update(ctx, &scheduled)
+	if errors.Is(err, repository.ErrStale) {
+		return nil
+	}
+	return err
+}
+
+// fail records a failed attempt at the next occurrence of scheduled, and
+// plans when it is tried again, if at all.
+func (s *Scheduler) fail(ctx context.Context, scheduled models.ScheduledTransfer, cause error, now time.Time) error {
+	code := apperrors.From(cause).Code
+	occurrence := *scheduled.NextRunAt
+	attempts := scheduled.Attempts + 1
+
+	key := "scheduled_transfer_retrying"
+	if attempts < s.opts.MaxAttempts {
+		retry := now.Add(s.opts.RetryDelay)
+		scheduled.Attempts, scheduled.AttemptAt = attempts, &retry
+	} else {
+		key = "scheduled_transfer_failed"
+		s.advance(&scheduled, occurrence, now)
+		if scheduled.Status == models.ScheduleCompleted {
+			scheduled.Status = models.ScheduleFailed
+		}
+	}
+	scheduled.LastError = string(code)
+	scheduled.UpdatedDate = now
+
+	var sender, recipient models.User
+	err := s.wallet.store.Transaction(ctx, func(repos repository.Repositories) error {
+		if err := repos.Schedules.Update(ctx, &scheduled); err != nil {
+			return err
+		}
+		var err error
+		if sender, err = repos.Users.FindByID(ctx, scheduled.UserID); err != nil {
+			return err
+		}
+		if recipient, err = repos.Users.FindByID(ctx, scheduled.ToUserID); err != nil {
+			return err
+		}
+		return audit(ctx, repos, scheduled.UserID, ActionScheduleFail, "", map[string]interface{}{
+			"schedule_id": scheduled.ID,
+			"occurrence":  occurrence,
+			"attempts":    attempts,
+			"error":       code,
+		}, now)
+	})
+	if errors.Is(err, repository.ErrStale) {
+		// Another instance or the sender changed the schedule meanwhile.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	notify(ctx, s.wallet.notifier, sender, key, func(lang i18n.Language) i18n.Params {
+		return i18n.Params{
+			"amount": i18n.FormatMoney(lang, scheduled.Amount, scheduled.Currency),
+			"name":   displayName(recipient),
+			"reason": i18n.T(lang, "error."+string(code), nil),
+		}
+	})
+	return nil
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"myapp/apperrors"
+	"myapp/models"
+	"myapp/schedule"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func schedulerFixture(now *time.Time, balances ...float64) (*Scheduler, *WalletService, *fakeStore, *recordingNotifier, []models.User) {
+	wallet, store, notifier, users := walletFixture(balances...)
+	wallet.now = func() time.Time { return *now }
+	scheduler := NewScheduler(wallet, SchedulerOptions{
+		Interval:    time.Minute,
+		RetryDelay:  time.Hour,
+		MaxAttempts: 3,
+		BatchSize:   10,
+	})
+	return scheduler, wallet, store, notifier, users
+}
+
+func TestCreateScheduleValidates(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	_, wallet, _, _, users := schedulerFixture(&now, 1000, 0)
+	ctx := context.Background()
+	order := ScheduleOrder{From: users[0].ID, To: users[1].ID, Amount: 100, Frequency: schedule.Once}
+
+	for name, change := range map[string]func(o *ScheduleOrder){
+		"self":       func(o *ScheduleOrder) { o.To = o.From },
+		"no start":   func(o *ScheduleOrder) {},
+		"past":       func(o *ScheduleOrder) { o.StartAt = now.Add(-time.Hour) },
+		"frequency":  func(o *ScheduleOrder) { o.Frequency = "HOURLY" },
+		"cron":       func(o *ScheduleOrder) { o.Frequency, o.Cron = schedule.Cron, "0 0 30 2 *" },
+		"bad cron":   func(o *ScheduleOrder) { o.Frequency, o.Cron = schedule.Cron, "every day" },
+		"currency":   func(o *ScheduleOrder) { o.StartAt, o.Currency = now.Add(time.Hour), "USD" },
+		"not future": func(o *ScheduleOrder) { o.StartAt = now },
+	} {
+		invalid := order
+		change(&invalid)
+		_, err := wallet.CreateSchedule(ctx, invalid)
+		assert.Error(t, err, name)
+	}
+
+	order.StartAt = now.Add(time.Hour)
+	order.To = users[0].ID
+	order.To[0]++
+	_, err := wallet.CreateSchedule(ctx, order)
+	assert.True(t, errors.Is(err, apperrors.ErrTargetUserNotFound))
+
+	order.To = users[1].ID
+	order.Frequency = schedule.Monthly
+	for i := 0; i < MaxScheduledTransfers; i++ {
+		if _, err := wallet.CreateSchedule(ctx, order); err != nil {
+			t.Fatalf("Failed to create schedule: %v", err)
+		}
+	}
+	_, err = wallet.CreateSchedule(ctx, order)
+	assert.True(t, errors.Is(err, apperrors.ErrScheduleLimitReached))
+}
+
+func TestSchedulerMakesEveryOccurrenceOnce(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	scheduler, wallet, store, notifier, users := schedulerFixture(&now, 1000, 0)
+	ctx := context.Background()
+	scheduled, err := wallet.CreateSchedule(ctx, ScheduleOrder{
+		From: users[0].ID, To: users[1].ID, Amount: 100, Remarks: "Allowance",
+		Frequency: schedule.Daily, StartAt: now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+	assert.Equal(t, now.Add(time.Hour), *scheduled.NextRunAt)
+
+	made, err := scheduler.RunDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, made, "nothing is due before the start")
+
+	now = now.Add(time.Hour)
+	due := store.data.schedules[scheduled.ID]
+	made, err = scheduler.RunDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, made)
+	if assert.Len(t, store.data.transfers, 1) {
+		transfer := store.data.transfers[0]
+		assert.Equal(t, &scheduled.ID, transfer.ScheduleID)
+		assert.Equal(t, now, *transfer.ScheduledFor)
+		assert.Equal(t, "Allowance", transfer.Remarks)
+	}
+	assert.Len(t, notifier.sent, 2)
+
+	// Another instance that read the schedule before the first made the
+	// occurrence loses the race.
+	ok, err := scheduler.run(ctx, due, now)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	made, _ = scheduler.RunDue(ctx)
+	assert.Equal(t, 0, made)
+	assert.Len(t, store.data.transfers, 1)
+
+	next := store.data.schedules[scheduled.ID]
+	assert.Equal(t, now.Add(24*time.Hour), *next.NextRunAt)
+	assert.Equal(t, models.ScheduleActive, next.Status)
+
+	// Two days later only the latest occurrence is made.
+	now = now.Add(48 * time.Hour)
+	made, _ = scheduler.RunDue(ctx)
+	assert.Equal(t, 1, made)
+	assert.Equal(t, 800.0, store.data.users[users[0].ID].Balance)
+	assert.Equal(t, now.Add(24*time.Hour), *store.data.schedules[scheduled.ID].NextRunAt)
+}
+
+func TestSchedulerRetriesAndGivesUp(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	scheduler, wallet, store, notifier, users := schedulerFixture(&now, 50, 0)
+	ctx := context.Background()
+	once, err := wallet.CreateSchedule(ctx, ScheduleOrder{
+		From: users[0].ID, To: users[1].ID, Amount: 100, Frequency: schedule.Once, StartAt: now.Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+	weekly, err := wallet.CreateSchedule(ctx, ScheduleOrder{
+		From: users[0].ID, To: users[1].ID, Amount: 100, Frequency: schedule.Weekly, StartAt: now.Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+	now = now.Add(time.Minute)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		made, err := scheduler.RunDue(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, made)
+		now = now.Add(time.Hour)
+	}
+
+	failed := store.data.schedules[once.ID]
+	assert.Equal(t, models.ScheduleFailed, failed.Status)
+	assert.Equal(t, "INSUFFICIENT_BALANCE", failed.LastError)
+	assert.Nil(t, failed.NextRunAt)
+	skipped := store.data.schedules[weekly.ID]
+	assert.Equal(t, models.ScheduleActive, skipped.Status)
+	assert.Equal(t, 0, skipped.Attempts)
+	assert.Equal(t, weekly.StartAt.Add(7*24*time.Hour), *skipped.NextRunAt)
+	assert.Empty(t, store.data.transfers)
+
+	if assert.Len(t, notifier.sent, 6) {
+		assert.Equal(t, "scheduled_transfer_retrying", notifier.sent[0].Key)
+		assert.Equal(t, "scheduled_transfer_failed", notifier.sent[5].Key)
+	}
+
+	// A top-up in time lets the next occurrence through.
+	if _, err := wallet.TopUp(ctx, users[0].ID, 100, Account{}); err != nil {
+		t.Fatalf("Failed to top up: %v", err)
+	}
+	now = *skipped.NextRunAt
+	made, _ := scheduler.RunDue(ctx)
+	assert.Equal(t, 1, made)
+}
+
+func TestUpdatingAndCancellingSchedules(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	scheduler, wallet, store, _, users := schedulerFixture(&now, 1000, 0)
+	ctx := context.Background()
+	order := ScheduleOrder{From: users[0].ID, To: users[1].ID, Amount: 100, Frequency: schedule.Monthly, StartAt: now}
+	scheduled, err := wallet.CreateSchedule(ctx, order)
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+
+	order.Paused = true
+	order.Frequency, order.Cron = schedule.Cron, "0 9 * * 1"
+	paused, err := wallet.UpdateSchedule(ctx, scheduled.ID, order)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SchedulePaused, paused.Status)
+	assert.Equal(t, time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC), *paused.NextRunAt)
+
+	now = now.Add(7 * 24 * time.Hour)
+	made, _ := scheduler.RunDue(ctx)
+	assert.Equal(t, 0, made, "paused schedules do not run")
+
+	_, err = wallet.Schedule(ctx, users[1].ID, scheduled.ID)
+	assert.True(t, errors.Is(err, apperrors.ErrScheduleNotFound), "others cannot see the schedule")
+	_, err = wallet.CancelSchedule(ctx, users[0].ID, scheduled.ID)
+	assert.NoError(t, err)
+	_, err = wallet.Schedule(ctx, users[0].ID, scheduled.ID)
+	assert.True(t, errors.Is(err, apperrors.ErrScheduleNotFound))
+	listed, err := wallet.Schedules(ctx, users[0].ID)
+	assert.NoError(t, err)
+	assert.Empty(t, listed)
+	assert.Equal(t, models.ScheduleCancelled, store.data.schedules[scheduled.ID].Status)
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"myapp/apperrors"
+	"myapp/models"
+	"myapp/repository"
+	"myapp/schedule"
+
+	"github.com/google/uuid"
+)
+
+// MaxScheduledTransfers is how many active or paused scheduled transfers a
+// user may have.
+const MaxScheduledTransfers = 20
+
+// ScheduleOrder defines a scheduled transfer as the sender asked for it.
+type ScheduleOrder struct {
+	From   uuid.UUID
+	To     uuid.UUID
+	Amount float64
+	// Currency and ToCurrency are those of TransferOrder. Transfers
+	// between currencies are converted at the rate of the moment they are
+	// made.
+	Currency   string
+	ToCurrency string
+	Remarks    string
+	// Frequency is one of the schedule frequencies, and Cron the
+	// expression of schedule.Cron ones, evaluated in UTC.
+	Frequency string
+	Cron      string
+	// StartAt is when a one-off transfer is made, and when recurring ones
+	// start; they start at once when it is zero.
+	StartAt time.Time
+	// Paused keeps the schedule from running until it is updated again.
+	Paused bool
+}
+
+// Schedules returns the user's scheduled transfers that were not
+// cancelled, oldest first. It may be served by a read replica.
+func (s *WalletService) Schedules(ctx context.Context, userID uuid.UUID) ([]models.ScheduledTransfer, error) {
+	repos := s.store.Reader(userID)
+	if _, err := repos.Users.FindByID(ctx, userID); err != nil {
+		return nil, lookupError(err, apperrors.ErrUserNotFound)
+	}
+	return repos.Schedules.ListByUser(ctx, userID)
+}
+
+// Schedule returns one of the user's scheduled transfers. It may be served
+// by a read replica.
+func (s *WalletService) Schedule(ctx context.Context, userID, id uuid.UUID) (models.ScheduledTransfer, error) {
+	return findSchedule(ctx, s.store.Reader(userID), userID, id)
+}
+
+// CreateSchedule sets up a transfer to be made at StartAt, or again and
+// again from then on. One-off transfers must lie in the future.
+func (s *WalletService) CreateSchedule(ctx context.Context, order ScheduleOrder) (models.ScheduledTransfer, error) {
+	now := s.now()
+	scheduled := models.ScheduledTransfer{UserID: order.From, CreatedDate: now}
+	if err := s.define(&scheduled, order, now); err != nil {
+		return models.ScheduledTransfer{}, err
+	}
+
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		if err := checkParties(ctx, repos, order); err != nil {
+			return err
+		}
+		existing, err := repos.Schedules.ListByUser(ctx, order.From)
+		if err != nil {
+			return err
+		}
+		pending := 0
+		for _, other := range existing {
+			if other.Status == models.ScheduleActive || other.Status == models.SchedulePaused {
+				pending++
+			}
+		}
+		if pending >= MaxScheduledTransfers {
+			return apperrors.ErrScheduleLimitReached
+		}
+
+		if err := repos.Schedules.Create(ctx, &scheduled); err != nil {
+			return err
+		}
+		return audit(ctx, repos, order.From, ActionScheduleCreate, "", scheduleDetails(scheduled), now)
+	})
+	if err != nil {
+		return models.ScheduledTransfer{}, err
+	}
+	return scheduled, nil
+}
+
+// UpdateSchedule replaces the definition of one of the user's scheduled
+// transfers and plans its next occurrence afresh, which also resumes
+// completed and failed ones. It fails with VERSION_CONFLICT when the
+// scheduler made a transfer meanwhile.
+func (s *WalletService) UpdateSchedule(ctx context.Context, id uuid.UUID, order ScheduleOrder) (models.ScheduledTransfer, error) {
+	now := s.now()
+	var scheduled models.ScheduledTransfer
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		var err error
+		if scheduled, err = findSchedule(ctx, repos, order.From, id); err != nil {
+			return err
+		}
+		if err := s.define(&scheduled, order, now); err != nil {
+			return err
+		}
+		if err := checkParties(ctx, repos, order); err != nil {
+			return err
+		}
+
+		if err := repos.Schedules.Update(ctx, &scheduled); err != nil {
+			return err
+		}
+		return audit(ctx, repos, order.From, ActionScheduleUpdate, "", scheduleDetails(scheduled), now)
+	})
+	if errors.Is(err, repository.ErrStale) {
+		return models.ScheduledTransfer{}, apperrors.ErrVersionConflict.Wrap(err)
+	}
+	if err != nil {
+		return models.ScheduledTransfer{}, err
+	}
+	return scheduled, nil
+}
+
+// CancelSchedule stops one of the user's scheduled transfers for good.
+// Transfers it already made stay as they are.
+func (s *WalletService) CancelSchedule(ctx context.Context, userID, id uuid.UUID) (models.ScheduledTransfer, error) {
+	now := s.now()
+	var scheduled models.ScheduledTransfer
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		var err error
+		if scheduled, err = findSchedule(ctx, repos, userID, id); err != nil {
+			return err
+		}
+		scheduled.Status = models.ScheduleCancelled
+		scheduled.NextRunAt, scheduled.AttemptAt = nil, nil
+		if err := repos.Schedules.Update(ctx, &scheduled); err != nil {
+			return err
+		}
+		return audit(ctx, repos, userID, ActionScheduleCancel, "", map[string]interface{}{
+			"schedule_id": id,
+		}, now)
+	})
+	if errors.Is(err, repository.ErrStale) {
+		return models.ScheduledTransfer{}, apperrors.ErrVersionConflict.Wrap(err)
+	}
+	if err != nil {
+		return models.ScheduledTransfer{}, err
+	}
+	return scheduled, nil
+}
+
+// define validates order and applies it to scheduled, planning its first
+// occurrence after now, or at now when order has no start.
+func (s *WalletService) define(scheduled *models.ScheduledTransfer, order ScheduleOrder, now time.Time) error {
+	if order.From == order.To {
+		return apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"target_user": {Rule: "self_transfer"},
+		})
+	}
+	from, err := s.currency("currency", order.Currency)
+	if err != nil {
+		return err
+	}
+	to := from
+	if order.ToCurrency != "" {
+		if to, err = s.currency("to_currency", order.ToCurrency); err != nil {
+			return err
+		}
+	}
+
+	start, after := order.StartAt.UTC().Truncate(time.Second), now
+	if order.StartAt.IsZero() {
+		if order.Frequency == schedule.Once {
+			return apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+				"start_at": {Rule: "required"},
+			})
+		}
+		// The first occurrence is due at once.
+		start = now.UTC().Truncate(time.Second)
+		after = start.Add(-time.Nanosecond)
+	}
+	expression := ""
+	if order.Frequency == schedule.Cron {
+		expression = order.Cron
+	}
+	rule := schedule.Rule{Frequency: order.Frequency, Expression: expression, Start: start}
+	if err := rule.Validate(); err != nil {
+		field := "cron"
+		if errors.Is(err, schedule.ErrUnknownFrequency) {
+			field = "frequency"
+		}
+		return apperrors.ErrValidationFailed.Wrap(err).WithFields(map[string]apperrors.FieldError{
+			field: {Rule: "invalid"},
+		})
+	}
+
+	scheduled.ToUserID = order.To
+	scheduled.Amount = order.Amount
+	scheduled.Currency = from
+	scheduled.ToCurrency = to
+	scheduled.Remarks = order.Remarks
+	scheduled.Frequency = order.Frequency
+	scheduled.Cron = expression
+	scheduled.StartAt = start
+	scheduled.Status = models.ScheduleActive
+	if order.Paused {
+		scheduled.Status = models.SchedulePaused
+	}
+	scheduled.UpdatedDate = now
+	if !plan(scheduled, after) {
+		// Only one-off transfers run out of occurrences.
+		return apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"start_at": {Rule: "invalid"},
+		})
+	}
+	return nil
+}
+
+// plan sets the first occurrence of scheduled after after as the next one,
+// with no failed attempts at it yet. It reports false, clearing the next
+// occurrence, when there is none.
+func plan(scheduled *models.ScheduledTransfer, after time.Time) bool {
+	rule := schedule.Rule{Frequency: scheduled.Frequency, Expression: scheduled.Cron, Start: scheduled.StartAt}
+	scheduled.Attempts, scheduled.LastError = 0, ""
+	next, ok := rule.Next(after)
+	if !ok {
+		scheduled.NextRunAt, scheduled.AttemptAt = nil, nil
+		return false
+	}
+	attempt := next
+	scheduled.NextRunAt, scheduled.AttemptAt = &next, &attempt
+	return true
+}
+
+// checkParties fails unless both users of a scheduled transfer exist.
+func checkParties(ctx context.Context, repos repository.Repositories, order ScheduleOrder) error {
+	if _, err := repos.Users.FindByID(ctx, order.From); err != nil {
+		return lookupError(err, apperrors.ErrUserNotFound)
+	}
+	if _, err := repos.Users.FindByID(ctx, order.To); err != nil {
+		return lookupError(err, apperrors.ErrTargetUserNotFound)
+	}
+	return nil
+}
+
+// findSchedule returns the user's scheduled transfer. Cancelled ones and
+// those of other users are not found.
+func findSchedule(ctx context.Context, repos repository.Repositories, userID, id uuid.UUID) (models.ScheduledTransfer, error) {
+	scheduled, err := repos.Schedules.FindByID(ctx, id)
+	if err == nil && (scheduled.UserID != userID || scheduled.Status == models.ScheduleCancelled) {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		return models.ScheduledTransfer{}, lookupError(err, apperrors.ErrScheduleNotFound)
+	}
+	return scheduled, nil
+}
+
+func scheduleDetails(scheduled models.ScheduledTransfer) map[string]interface{} {
+	details := map[string]interface{}{
+		"schedule_id": scheduled.ID,
+		"to_user_id":  scheduled.ToUserID,
+		"amount":      scheduled.Amount,
+		"currency":    scheduled.Currency,
+		"frequency":   scheduled.Frequency,
+		"start_at":    scheduled.StartAt,
+		"status":      scheduled.Status,
+	}
+	if scheduled.ToCurrency != scheduled.Currency {
+		details["to_currency"] = scheduled.ToCurrency
+	}
+	if scheduled.Cron != "" {
+		details["cron"] = scheduled.Cron
+	}
+	return details
+}
@@ -0,0 +1,20 @@
+// Package services holds the business rules of the wallet, independent of
+// any transport. HTTP handlers, the CLI and background jobs call the same
+// services, which report failures as apperrors domain errors.
+package services
+
+import (
+	"errors"
+
+	"myapp/apperrors"
+	"myapp/repository"
+)
+
+// lookupError maps a failed user lookup to notFound when no record matched,
+// and leaves any other error to be reported as internal.
+func lookupError(err error, notFound *apperrors.Error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return notFound.Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"myapp/apperrors"
+	"myapp/models"
+	"myapp/repository"
+
+	"github.com/google/uuid"
+)
+
+// UserService manages accounts and profiles.
+type UserService struct {
+	store repository.Store
+	now   func() time.Time
+}
+
+func NewUserService(store repository.Store) *UserService {
+	return &UserService{store: store, now: time.Now}
+}
+
+// Register creates user, failing with DUPLICATE_PHONE when the phone number
+// is already taken.
+func (s *UserService) Register(ctx context.Context, user *models.User) error {
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		if err := repos.Users.Create(ctx, user); err != nil {
+			return err
+		}
+		return audit(ctx, repos, user.ID, ActionRegister, "", nil, s.now())
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return apperrors.ErrDuplicatePhone.Wrap(err)
+	}
+	return err
+}
+
+// Authenticate returns the user with phoneNumber if pin is theirs. Unknown
+// numbers and wrong PINs fail alike, so callers cannot probe for accounts.
+func (s *UserService) Authenticate(ctx context.Context, phoneNumber, pin string) (models.User, error) {
+	user, err := s.store.Repositories().Users.FindByPhone(ctx, phoneNumber)
+	if err != nil {
+		return models.User{}, lookupError(err, apperrors.ErrInvalidCredentials)
+	}
+	if user.PIN != pin {
+		return models.User{}, apperrors.ErrInvalidCredentials
+	}
+	if user.Frozen() {
+		return models.User{}, apperrors.ErrAccountFrozen
+	}
+	return user, nil
+}
+
+// Get returns the profile of the user id. It may come from a cache or a read
+// replica; writes based on it are still checked against the stored version.
+// Writes conditional on the version the client saw use GetLatest.
+func (s *UserService) Get(ctx context.Context, id uuid.UUID) (models.User, error) {
+	user, err := s.store.Reader(id).Users.FindByID(ctx, id)
+	if err != nil {
+		return models.User{}, lookupError(err, apperrors.ErrUserNotFound)
+	}
+	return user, nil
+}
+
+// GetLatest returns the profile of the user id as stored on the primary, so
+// that an up-to-date version is never taken for a stale one.
+func (s *UserService) GetLatest(ctx context.Context, id uuid.UUID) (models.User, error) {
+	user, err := s.store.Repositories().Users.FindByID(ctx, id)
+	if err != nil {
+		return models.User{}, lookupError(err, apperrors.ErrUserNotFound)
+	}
+	return user, nil
+}
+
+// Lookup finds a user by ID or by phone number, as typed by an operator.
+// It always reads the primary, as operators act on what they see.
+func (s *UserService) Lookup(ctx context.Context, ref string) (models.User, error) {
+	users := s.store.Repositories().Users
+	var user models.User
+	var err error
+	if id, parseErr := uuid.Parse(ref); parseErr == nil {
+		user, err = users.FindByID(ctx, id)
+	} else {
+		user, err = users.FindByPhone(ctx, ref)
+	}
+	if err != nil {
+		return models.User{}, lookupError(err, apperrors.ErrUserNotFound)
+	}
+	return user, nil
+}
+
+// UpdateProfile saves changes, keyed by column, already applied to user. It
+// fails with VERSION_CONFLICT when the profile changed since user was read.
+func (s *UserService) UpdateProfile(ctx context.Context, user *models.User, changes map[string]interface{}) error {
+	// Only the names of the fields are audited, not their personal values.
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		if err := repos.Users.UpdateProfile(ctx, user, changes); err != nil {
+			return err
+		}
+		return audit(ctx, repos, user.ID, ActionUpdateProfile, "", map[string]interface{}{"fields": fields}, s.now())
+	})
+	if errors.Is(err, repository.ErrStale) {
+		return apperrors.ErrVersionConflict.Wrap(err)
+	}
+	return err
+}
+
+// Freeze stops the user from logging in and moving money out until
+// Unfreeze. Freezing a frozen account keeps the original time.
+func (s *UserService) Freeze(ctx context.Context, id uuid.UUID, reason string) (models.User, error) {
+	return s.setFrozen(ctx, id, true, reason)
+}
+
+// Unfreeze lifts a freeze.
+func (s *UserService) Unfreeze(ctx context.Context, id uuid.UUID, reason string) (models.User, error) {
+	return s.setFrozen(ctx, id, false, reason)
+}
+
+func (s *UserService) setFrozen(ctx context.Context, id uuid.UUID, frozen bool, reason string) (models.User, error) {
+	if reason == "" {
+		return models.User{}, apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"reason": {Rule: "required"},
+		})
+	}
+
+	var user models.User
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		var err error
+		if user, err = repos.Users.FindByID(ctx, id); err != nil {
+			return lookupError(err, apperrors.ErrUserNotFound)
+		}
+		if user.Frozen() == frozen {
+			return nil
+		}
+
+		var frozenAt *time.Time
+		action := ActionUnfreeze
+		if frozen {
+			now := s.now()
+			frozenAt, action = &now, ActionFreeze
+		}
+		if user, err = repos.Users.SetFrozenAt(ctx, id, frozenAt); err != nil {
+			return lookupError(err, apperrors.ErrUserNotFound)
+		}
+		return audit(ctx, repos, id, action, reason, nil, s.now())
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// AuditTrail returns the latest limit audit entries about a user, newest
+// first.
+func (s *UserService) AuditTrail(ctx context.Context, id uuid.UUID, limit int) ([]models.AuditLog, error) {
+	return s.store.Reader(id).Audit.ListByUser(ctx, id, limit)
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"myapp/apperrors"
+	"myapp/models"
+	"myapp/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterRejectsDuplicatePhone(t *testing.T) {
+	users := NewUserService(newFakeStore())
+	ctx := context.Background()
+
+	assert.NoError(t, users.Register(ctx, &models.User{ID: uuid.New(), PhoneNumber: "+62811255501"}))
+	err := users.Register(ctx, &models.User{ID: uuid.New(), PhoneNumber: "+62811255501"})
+
+	assert.True(t, errors.Is(err, apperrors.ErrDuplicatePhone))
+}
+
+func TestAuthenticate(t *testing.T) {
+	user := models.User{ID: uuid.New(), PhoneNumber: "+62811255501", PIN: "123456"}
+	users := NewUserService(newFakeStore(user))
+	ctx := context.Background()
+
+	found, err := users.Authenticate(ctx, "+62811255501", "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+
+	_, err = users.Authenticate(ctx, "+62811255501", "654321")
+	assert.True(t, errors.Is(err, apperrors.ErrInvalidCredentials))
+
+	_, err = users.Authenticate(ctx, "+62800000000", "123456")
+	assert.True(t, errors.Is(err, apperrors.ErrInvalidCredentials))
+}
+
+func TestUpdateProfileDetectsConflicts(t *testing.T) {
+	user := models.User{ID: uuid.New(), FirstName: "Budi", Version: 1}
+	users := NewUserService(newFakeStore(user))
+	ctx := context.Background()
+
+	first, second := user, user
+	first.FirstName = "Budi Santoso"
+	assert.NoError(t, users.UpdateProfile(ctx, &first, map[string]interface{}{"first_name": first.FirstName}))
+	assert.Equal(t, int64(2), first.Version)
+
+	second.FirstName = "Budiman"
+	err := users.UpdateProfile(ctx, &second, map[string]interface{}{"first_name": second.FirstName})
+	assert.True(t, errors.Is(err, apperrors.ErrVersionConflict))
+}
+
+// laggingStore serves reads from a snapshot taken before later writes, as a
+// cache or a lagging replica would.
+type laggingStore struct {
+	*fakeStore
+	stale fakeData
+}
+
+func (s laggingStore) Reader(userID uuid.UUID) repository.Repositories {
+	return fakeRepositories(&s.stale, nil)
+}
+
+func TestGetLatestReadsThePrimary(t *testing.T) {
+	user := models.User{ID: uuid.New(), FirstName: "Budi", Version: 1}
+	store := newFakeStore(user)
+	users := NewUserService(laggingStore{store, store.data.clone()})
+	ctx := context.Background()
+
+	updated := user
+	if err := users.UpdateProfile(ctx, &updated, map[string]interface{}{"first_name": "Budiman"}); err != nil {
+		t.Fatalf("Failed to update profile: %v", err)
+	}
+
+	stale, err := users.Get(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), stale.Version)
+	latest, err := users.GetLatest(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), latest.Version)
+}
+
+func TestFreezeBlocksLogin(t *testing.T) {
+	user := models.User{ID: uuid.New(), PhoneNumber: "+62811255501", PIN: "123456"}
+	store := newFakeStore(user)
+	users := NewUserService(store)
+	ctx := WithActor(context.Background(), "admin:ops")
+
+	frozen, err := users.Freeze(ctx, user.ID, "Reported stolen phone")
+	assert.NoError(t, err)
+	assert.True(t, frozen.Frozen())
+
+	_, err = users.Authenticate(ctx, "+62811255501", "123456")
+	assert.True(t, errors.Is(err, apperrors.ErrAccountFrozen))
+
+	// Wrong PINs do not reveal that the account is frozen
+	_, err = users.Authenticate(ctx, "+62811255501", "654321")
+	assert.True(t, errors.Is(err, apperrors.ErrInvalidCredentials))
+
+	// Freezing again changes nothing
+	_, err = users.Freeze(ctx, user.ID, "Again")
+	assert.NoError(t, err)
+
+	_, err = users.Unfreeze(ctx, user.ID, "Phone recovered")
+	assert.NoError(t, err)
+	_, err = users.Authenticate(ctx, "+62811255501", "123456")
+	assert.NoError(t, err)
+
+	trail, err := users.AuditTrail(ctx, user.ID, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, trail, 2) {
+		assert.Equal(t, ActionUnfreeze, trail[0].Action)
+		assert.Equal(t, ActionFreeze, trail[1].Action)
+		assert.Equal(t, "Reported stolen phone", trail[1].Reason)
+		assert.Equal(t, "admin:ops", trail[1].Actor)
+	}
+
+	_, err = users.Freeze(ctx, user.ID, "")
+	assert.True(t, errors.Is(err, apperrors.ErrValidationFailed))
+}
+
+func TestLookupByIDOrPhone(t *testing.T) {
+	user := models.User{ID: uuid.New(), PhoneNumber: "+62811255501"}
+	users := NewUserService(newFakeStore(user))
+	ctx := context.Background()
+
+	found, err := users.Lookup(ctx, user.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+
+	found, err = users.Lookup(ctx, "+62811255501")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+
+	_, err = users.Lookup(ctx, "+62800000000")
+	assert.True(t, errors.Is(err, apperrors.ErrUserNotFound))
+}
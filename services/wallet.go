@@ -0,0 +1,662 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"myapp/apperrors"
+	"myapp/fx"
+	"myapp/i18n"
+	"myapp/models"
+	"myapp/notification"
+	"myapp/repository"
+	"myapp/tracing"
+
+	"github.com/google/uuid"
+)
+
+// WalletService moves money: top-ups, payments and transfers. Every
+// operation updates the balance and writes its record in one transaction,
+// and balances are changed with guarded updates so that concurrent requests
+// can never overdraw an account.
+//
+// Wallets hold models.DefaultCurrency, plus every currency of the exchange
+// when there is one. Transfers between currencies are converted at the rate
+// of a quote.
+type WalletService struct {
+	store    repository.Store
+	notifier notification.Notifier
+	// exchange is nil when wallets only hold models.DefaultCurrency.
+	exchange *fx.Exchange
+	now      func() time.Time
+}
+
+func NewWalletService(store repository.Store, notifier notification.Notifier) *WalletService {
+	return &WalletService{store: store, notifier: notifier, now: time.Now}
+}
+
+// WithExchange returns a service that supports the currencies of exchange
+// and converts between them.
+func (s *WalletService) WithExchange(exchange *fx.Exchange) *WalletService {
+	clone := *s
+	clone.exchange = exchange
+	return &clone
+}
+
+// Account picks which of a user's balances an operation works on: the main
+// balance in a currency, or a pocket.
+type Account struct {
+	// Currency is that of the main balance, models.DefaultCurrency when
+	// empty. With a pocket it may be left empty and must otherwise be the
+	// pocket's currency.
+	Currency string
+	// PocketID is the pocket, or uuid.Nil for the main balance.
+	PocketID uuid.UUID
+}
+
+// TopUp credits amount to the user's account.
+func (s *WalletService) TopUp(ctx context.Context, userID uuid.UUID, amount float64, account Account) (models.TopUp, error) {
+	var topUp models.TopUp
+	var user models.User
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		changed, err := s.adjustAccount(ctx, repos, userID, account, amount)
+		if err != nil {
+			return err
+		}
+		user = changed.user
+		if user.Frozen() {
+			return apperrors.ErrAccountFrozen
+		}
+
+		topUp = models.TopUp{
+			UserID:        user.ID,
+			Amount:        amount,
+			Currency:      changed.currency,
+			PocketID:      changed.pocketID,
+			BalanceBefore: changed.balance - amount,
+			BalanceAfter:  changed.balance,
+			CreatedDate:   s.now(),
+		}
+		if err := repos.TopUps.Create(ctx, &topUp); err != nil {
+			return err
+		}
+		return audit(ctx, repos, user.ID, ActionTopUp, "", withPocket(map[string]interface{}{
+			"top_up_id":     topUp.ID,
+			"amount":        amount,
+			"currency":      topUp.Currency,
+			"balance_after": topUp.BalanceAfter,
+		}, topUp.PocketID), topUp.CreatedDate)
+	})
+	if err != nil {
+		return models.TopUp{}, err
+	}
+
+	notify(ctx, s.notifier, user, "top_up_success", func(lang i18n.Language) i18n.Params {
+		return i18n.Params{
+			"amount":  i18n.FormatMoney(lang, topUp.Amount, topUp.Currency),
+			"balance": i18n.FormatMoney(lang, topUp.BalanceAfter, topUp.Currency),
+		}
+	})
+	return topUp, nil
+}
+
+// PaymentOrder is a payment as the user asked for it.
+type PaymentOrder struct {
+	Amount float64
+	Account
+	// Merchant is the code of the merchant paid, if any. That merchant may
+	// later refund the payment.
+	Merchant string
+	Remarks  string
+}
+
+// Pay debits the ordered amount from the user's account, failing with
+// INSUFFICIENT_BALANCE when its balance is too low.
+func (s *WalletService) Pay(ctx context.Context, userID uuid.UUID, order PaymentOrder) (models.Payment, error) {
+	var payment models.Payment
+	var user models.User
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		changed, err := s.adjustAccount(ctx, repos, userID, order.Account, -order.Amount)
+		if err != nil {
+			return err
+		}
+		user = changed.user
+		if user.Frozen() {
+			return apperrors.ErrAccountFrozen
+		}
+
+		payment = models.Payment{
+			UserID:        user.ID,
+			Amount:        order.Amount,
+			Currency:      changed.currency,
+			PocketID:      changed.pocketID,
+			Merchant:      order.Merchant,
+			Remarks:       order.Remarks,
+			BalanceBefore: changed.balance + order.Amount,
+			BalanceAfter:  changed.balance,
+			CreatedDate:   s.now(),
+		}
+		if err := repos.Payments.Create(ctx, &payment); err != nil {
+			return err
+		}
+		details := withPocket(map[string]interface{}{
+			"payment_id":    payment.ID,
+			"amount":        payment.Amount,
+			"currency":      payment.Currency,
+			"balance_after": payment.BalanceAfter,
+		}, payment.PocketID)
+		if payment.Merchant != "" {
+			details["merchant"] = payment.Merchant
+		}
+		return audit(ctx, repos, user.ID, ActionPayment, "", details, payment.CreatedDate)
+	})
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	notify(ctx, s.notifier, user, "payment_success", func(lang i18n.Language) i18n.Params {
+		return i18n.Params{
+			"amount":  i18n.FormatMoney(lang, payment.Amount, payment.Currency),
+			"remarks": payment.Remarks,
+			"balance": i18n.FormatMoney(lang, payment.BalanceAfter, payment.Currency),
+		}
+	})
+	return payment, nil
+}
+
+// TransferOrder is a transfer as the sender asked for it.
+type TransferOrder struct {
+	From   uuid.UUID
+	To     uuid.UUID
+	Amount float64
+	// Currency is debited from the sender, models.DefaultCurrency when
+	// empty.
+	Currency string
+	// ToCurrency is credited to the recipient, Currency when empty. When
+	// they differ the amount is converted at the rate of QuoteID, or at the
+	// current rate when no quote is given.
+	ToCurrency string
+	QuoteID    uuid.UUID
+	Remarks    string
+}
+
+// TransferResult is a completed transfer with both parties as they are
+// after it.
+type TransferResult struct {
+	Transfer models.Transfer
+	From     models.User
+	To       models.User
+}
+
+// Transfer moves the ordered amount from one user to another. Sending to
+// oneself is a validation error.
+func (s *WalletService) Transfer(ctx context.Context, order TransferOrder) (TransferResult, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WalletService.Transfer")
+	defer span.End()
+
+	result, err := s.transfer(ctx, order)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return TransferResult{}, err
+	}
+	s.notifyTransfer(ctx, result)
+	return result, nil
+}
+
+func (s *WalletService) transfer(ctx context.Context, order TransferOrder) (TransferResult, error) {
+	priced, err := s.price(ctx, order)
+	if err != nil {
+		return TransferResult{}, err
+	}
+	var result TransferResult
+	err = s.store.Transaction(ctx, func(repos repository.Repositories) (err error) {
+		result, err = s.book(ctx, repos, priced)
+		return err
+	})
+	if err != nil {
+		return TransferResult{}, err
+	}
+	return result, nil
+}
+
+// pricedTransfer is a validated transfer order in the currencies it moves
+// between, at the current rate unless it uses a quote.
+type pricedTransfer struct {
+	TransferOrder
+	from, to string
+	rate     float64
+	// scheduleID and occurrence are set when the transfer is an occurrence
+	// of a scheduled transfer.
+	scheduleID *uuid.UUID
+	occurrence *time.Time
+	// requestID is set when the transfer pays a money request.
+	requestID *uuid.UUID
+}
+
+// price validates the order and fetches its rate. It happens outside the
+// transaction, so that the database is not held up by the exchange.
+func (s *WalletService) price(ctx context.Context, order TransferOrder) (pricedTransfer, error) {
+	if order.From == order.To {
+		return pricedTransfer{}, apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"target_user": {Rule: "self_transfer"},
+		})
+	}
+	from, err := s.currency("currency", order.Currency)
+	if err != nil {
+		return pricedTransfer{}, err
+	}
+	to := from
+	if order.ToCurrency != "" {
+		if to, err = s.currency("to_currency", order.ToCurrency); err != nil {
+			return pricedTransfer{}, err
+		}
+	}
+
+	// Without a quote of their own the sender gets the current rate.
+	rate := 1.0
+	if from != to && order.QuoteID == uuid.Nil {
+		quote, err := s.quote(ctx, from, to)
+		if err != nil {
+			return pricedTransfer{}, err
+		}
+		rate = quote.Rate
+	}
+	return pricedTransfer{TransferOrder: order, from: from, to: to, rate: rate}, nil
+}
+
+// book makes a priced transfer within the transaction of repos.
+func (s *WalletService) book(ctx context.Context, repos repository.Repositories, order pricedTransfer) (TransferResult, error) {
+	var result TransferResult
+	rate := order.rate
+	var quoteID *uuid.UUID
+	if order.QuoteID != uuid.Nil {
+		quote, err := s.useQuote(ctx, repos, order.From, order.QuoteID, order.from, order.to)
+		if err != nil {
+			return TransferResult{}, err
+		}
+		rate, quoteID = quote.Rate, &quote.ID
+	}
+	toAmount := fx.Round(order.Amount * rate)
+	if toAmount <= 0 {
+		return TransferResult{}, apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"amount": {Rule: "invalid"},
+		})
+	}
+
+	var balance float64
+	debit := func() (err error) {
+		result.From, balance, err = adjust(ctx, repos, order.From, order.from, -order.Amount)
+		return balanceError(err, apperrors.ErrUserNotFound)
+	}
+	credit := func() (err error) {
+		result.To, _, err = adjust(ctx, repos, order.To, order.to, toAmount)
+		return balanceError(err, apperrors.ErrTargetUserNotFound)
+	}
+
+	// Rows are always updated in ID order, so that two opposite
+	// transfers between the same users cannot deadlock.
+	steps := []func() error{debit, credit}
+	if bytes.Compare(order.To[:], order.From[:]) < 0 {
+		steps = []func() error{credit, debit}
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return TransferResult{}, err
+		}
+	}
+	if result.From.Frozen() {
+		return TransferResult{}, apperrors.ErrAccountFrozen
+	}
+
+	result.Transfer = models.Transfer{
+		FromUserID:    order.From,
+		ToUserID:      order.To,
+		Amount:        order.Amount,
+		Currency:      order.from,
+		ToAmount:      toAmount,
+		ToCurrency:    order.to,
+		Rate:          rate,
+		QuoteID:       quoteID,
+		Remarks:       order.Remarks,
+		BalanceBefore: balance + order.Amount,
+		BalanceAfter:  balance,
+		Status:        models.TransferSucceeded,
+		ScheduleID:    order.scheduleID,
+		ScheduledFor:  order.occurrence,
+		CreatedDate:   s.now(),
+	}
+	if err := repos.Transfers.Create(ctx, &result.Transfer); err != nil {
+		return TransferResult{}, err
+	}
+	details := map[string]interface{}{
+		"transfer_id":   result.Transfer.ID,
+		"to_user_id":    order.To,
+		"amount":        order.Amount,
+		"currency":      order.from,
+		"balance_after": result.Transfer.BalanceAfter,
+	}
+	if order.from != order.to {
+		details["to_amount"] = toAmount
+		details["to_currency"] = order.to
+		details["rate"] = rate
+	}
+	if order.scheduleID != nil {
+		details["schedule_id"] = *order.scheduleID
+	}
+	if order.requestID != nil {
+		details["request_id"] = *order.requestID
+	}
+	if err := audit(ctx, repos, order.From, ActionTransfer, "", details, result.Transfer.CreatedDate); err != nil {
+		return TransferResult{}, err
+	}
+	return result, nil
+}
+
+// notifyTransfer tells both parties about a completed transfer.
+func (s *WalletService) notifyTransfer(ctx context.Context, result TransferResult) {
+	transfer := result.Transfer
+	notify(ctx, s.notifier, result.From, "transfer_sent", func(lang i18n.Language) i18n.Params {
+		return i18n.Params{
+			"amount":  i18n.FormatMoney(lang, transfer.Amount, transfer.Currency),
+			"name":    displayName(result.To),
+			"balance": i18n.FormatMoney(lang, transfer.BalanceAfter, transfer.Currency),
+		}
+	})
+	notify(ctx, s.notifier, result.To, "transfer_received", func(lang i18n.Language) i18n.Params {
+		return i18n.Params{
+			"amount": i18n.FormatMoney(lang, transfer.ToAmount, transfer.ToCurrency),
+			"name":   displayName(result.From),
+		}
+	})
+}
+
+// Quote offers the user a rate for converting from into to, which they can
+// use for one transfer until it expires.
+func (s *WalletService) Quote(ctx context.Context, userID uuid.UUID, from, to string) (models.Quote, error) {
+	from, err := s.currency("from_currency", from)
+	if err != nil {
+		return models.Quote{}, err
+	}
+	if to, err = s.currency("to_currency", to); err != nil {
+		return models.Quote{}, err
+	}
+	if from == to {
+		return models.Quote{}, apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"to_currency": {Rule: "invalid"},
+		})
+	}
+
+	rate, err := s.quote(ctx, from, to)
+	if err != nil {
+		return models.Quote{}, err
+	}
+	repos := s.store.Repositories()
+	if _, err := repos.Users.FindByID(ctx, userID); err != nil {
+		return models.Quote{}, lookupError(err, apperrors.ErrUserNotFound)
+	}
+	quote := models.Quote{
+		UserID:       userID,
+		FromCurrency: from,
+		ToCurrency:   to,
+		MidRate:      rate.Mid,
+		Spread:       rate.Spread,
+		Rate:         rate.Rate,
+		CreatedDate:  s.now(),
+		ExpiresAt:    rate.ExpiresAt,
+	}
+	if err := repos.Quotes.Create(ctx, &quote); err != nil {
+		return models.Quote{}, err
+	}
+	return quote, nil
+}
+
+// quote asks the exchange for the current rate.
+func (s *WalletService) quote(ctx context.Context, from, to string) (fx.Quote, error) {
+	quote, err := s.exchange.Quote(ctx, from, to)
+	switch {
+	case errors.Is(err, fx.ErrUnsupportedCurrency):
+		return fx.Quote{}, apperrors.ErrUnsupportedCurrency.Wrap(err)
+	case err != nil:
+		return fx.Quote{}, apperrors.ErrRateUnavailable.Wrap(err)
+	}
+	return quote, nil
+}
+
+// useQuote claims the user's quote for a transfer from one currency into
+// another. Quotes of other users are not found.
+func (s *WalletService) useQuote(ctx context.Context, repos repository.Repositories, userID, quoteID uuid.UUID, from, to string) (models.Quote, error) {
+	quote, err := repos.Quotes.FindByID(ctx, quoteID)
+	if err == nil && quote.UserID != userID {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		return models.Quote{}, lookupError(err, apperrors.ErrQuoteNotFound)
+	}
+	if quote.FromCurrency != from || quote.ToCurrency != to {
+		return models.Quote{}, apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"quote_id": {Rule: "invalid"},
+		})
+	}
+
+	now := s.now()
+	if !now.Before(quote.ExpiresAt) {
+		return models.Quote{}, apperrors.ErrQuoteExpired
+	}
+	if err := repos.Quotes.MarkUsed(ctx, quote.ID, now); err != nil {
+		if errors.Is(err, repository.ErrStale) {
+			return models.Quote{}, apperrors.ErrQuoteExpired.Wrap(err)
+		}
+		return models.Quote{}, err
+	}
+	return quote, nil
+}
+
+// currency returns code, or models.DefaultCurrency when it is empty. Codes
+// the wallet cannot hold fail with UNSUPPORTED_CURRENCY, naming field.
+func (s *WalletService) currency(field, code string) (string, error) {
+	if code == "" || code == models.DefaultCurrency {
+		return models.DefaultCurrency, nil
+	}
+	if s.exchange == nil || !s.exchange.Supports(code) {
+		return "", apperrors.ErrUnsupportedCurrency.WithFields(map[string]apperrors.FieldError{
+			field: {Rule: "invalid"},
+		})
+	}
+	return code, nil
+}
+
+// adjust adds delta to the user's balance in currency and returns the user
+// along with that balance after the change.
+func adjust(ctx context.Context, repos repository.Repositories, userID uuid.UUID, currency string, delta float64) (models.User, float64, error) {
+	if currency == models.DefaultCurrency {
+		user, err := repos.Users.AdjustBalance(ctx, userID, delta)
+		return user, user.Balance, err
+	}
+	user, err := repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		return models.User{}, 0, err
+	}
+	balance, err := repos.Balances.Adjust(ctx, userID, currency, delta)
+	return user, balance.Amount, err
+}
+
+// adjusted is an account after a change to its balance.
+type adjusted struct {
+	user     models.User
+	balance  float64
+	currency string
+	// pocketID is nil for the main balance.
+	pocketID *uuid.UUID
+}
+
+// adjustAccount adds delta to the balance of the user's account. Unlike
+// adjust it reports failures as domain errors.
+func (s *WalletService) adjustAccount(ctx context.Context, repos repository.Repositories, userID uuid.UUID, account Account, delta float64) (adjusted, error) {
+	if account.PocketID == uuid.Nil {
+		currency, err := s.currency("currency", account.Currency)
+		if err != nil {
+			return adjusted{}, err
+		}
+		user, balance, err := adjust(ctx, repos, userID, currency, delta)
+		if err != nil {
+			return adjusted{}, balanceError(err, apperrors.ErrUserNotFound)
+		}
+		return adjusted{user: user, balance: balance, currency: currency}, nil
+	}
+
+	user, err := repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		return adjusted{}, lookupError(err, apperrors.ErrUserNotFound)
+	}
+	pocket, err := findPocket(ctx, repos, userID, account.PocketID)
+	if err != nil {
+		return adjusted{}, err
+	}
+	if account.Currency != "" && account.Currency != pocket.Currency {
+		return adjusted{}, apperrors.ErrValidationFailed.WithFields(map[string]apperrors.FieldError{
+			"currency": {Rule: "invalid"},
+		})
+	}
+	if pocket, err = repos.Pockets.AdjustBalance(ctx, pocket.ID, delta); err != nil {
+		return adjusted{}, balanceError(err, apperrors.ErrPocketNotFound)
+	}
+	return adjusted{user: user, balance: pocket.Balance, currency: pocket.Currency, pocketID: &pocket.ID}, nil
+}
+
+// Adjust corrects the models.DefaultCurrency balance by delta on an
+// administrator's behalf, e.g. to settle a dispute. It works on frozen
+// accounts too, and books a top-up for credits and a payment for debits so
+// that the history stays complete.
+func (s *WalletService) Adjust(ctx context.Context, userID uuid.UUID, delta float64, reason string) (models.User, error) {
+	fields := map[string]apperrors.FieldError{}
+	if delta == 0 {
+		fields["amount"] = apperrors.FieldError{Rule: "invalid"}
+	}
+	if reason == "" {
+		fields["reason"] = apperrors.FieldError{Rule: "required"}
+	}
+	if len(fields) > 0 {
+		return models.User{}, apperrors.ErrValidationFailed.WithFields(fields)
+	}
+
+	var user models.User
+	err := s.store.Transaction(ctx, func(repos repository.Repositories) error {
+		var err error
+		user, err = repos.Users.AdjustBalance(ctx, userID, delta)
+		if err != nil {
+			return balanceError(err, apperrors.ErrUserNotFound)
+		}
+
+		now := s.now()
+		before := user.Balance - delta
+		details := map[string]interface{}{
+			"amount":         delta,
+			"balance_before": before,
+			"balance_after":  user.Balance,
+		}
+		if delta > 0 {
+			topUp := models.TopUp{UserID: userID, Amount: delta, Currency: models.DefaultCurrency, BalanceBefore: before, BalanceAfter: user.Balance, CreatedDate: now}
+			err = repos.TopUps.Create(ctx, &topUp)
+			details["top_up_id"] = topUp.ID
+		} else {
+			payment := models.Payment{UserID: userID, Amount: -delta, Currency: models.DefaultCurrency, Remarks: "Adjustment: " + reason, BalanceBefore: before, BalanceAfter: user.Balance, CreatedDate: now}
+			err = repos.Payments.Create(ctx, &payment)
+			details["payment_id"] = payment.ID
+		}
+		if err != nil {
+			return err
+		}
+		return audit(ctx, repos, userID, ActionAdjust, reason, details, now)
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// Balances returns what the user holds in every currency, starting with
+// models.DefaultCurrency. It may be served by a read replica.
+func (s *WalletService) Balances(ctx context.Context, userID uuid.UUID) ([]models.Balance, error) {
+	repos := s.store.Reader(userID)
+	user, err := repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, lookupError(err, apperrors.ErrUserNotFound)
+	}
+	others, err := repos.Balances.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	balances := []models.Balance{{
+		UserID:      user.ID,
+		Currency:    models.DefaultCurrency,
+		Amount:      user.Balance,
+		UpdatedDate: user.UpdatedDate,
+	}}
+	return append(balances, others...), nil
+}
+
+// History is everything a user has done with their wallet.
+type History struct {
+	User      models.User
+	Transfers []models.Transfer
+	// Received are the transfers made to the user; Transfers are those
+	// they sent.
+	Received  []models.Transfer
+	Payments  []models.Payment
+	TopUps    []models.TopUp
+	Movements []models.PocketMovement
+	Reversals []models.Reversal
+	// Requests are the money requests the user made or was asked to pay,
+	// the expired ones as such.
+	Requests []models.MoneyRequest
+}
+
+// History returns the user with the transfers they sent and received,
+// their payments, top-ups, moves between pockets, the refunds and
+// reversals of any of these, and their money requests. It may be served
+// by a read replica.
+func (s *WalletService) History(ctx context.Context, userID uuid.UUID) (History, error) {
+	repos := s.store.Reader(userID)
+
+	var history History
+	var err error
+	if history.User, err = repos.Users.FindByID(ctx, userID); err != nil {
+		return History{}, lookupError(err, apperrors.ErrUserNotFound)
+	}
+	if history.Transfers, err = repos.Transfers.ListBySender(ctx, userID); err != nil {
+		return History{}, err
+	}
+	if history.Received, err = repos.Transfers.ListByRecipient(ctx, userID); err != nil {
+		return History{}, err
+	}
+	if history.Payments, err = repos.Payments.ListByUser(ctx, userID); err != nil {
+		return History{}, err
+	}
+	if history.TopUps, err = repos.TopUps.ListByUser(ctx, userID); err != nil {
+		return History{}, err
+	}
+	if history.Movements, err = repos.Movements.ListByUser(ctx, userID); err != nil {
+		return History{}, err
+	}
+	if history.Reversals, err = repos.Reversals.ListByUser(ctx, userID); err != nil {
+		return History{}, err
+	}
+	if history.Requests, err = repos.Requests.ListByUser(ctx, userID); err != nil {
+		return History{}, err
+	}
+	expire(history.Requests, s.now())
+	return history, nil
+}
+
+// balanceError maps the errors of a balance adjustment onto domain errors.
+func balanceError(err error, notFound *apperrors.Error) error {
+	if errors.Is(err, repository.ErrInsufficientFunds) {
+		return apperrors.ErrInsufficientBalance.Wrap(err)
+	}
+	if err != nil {
+		return lookupError(err, notFound)
+	}
+	return nil
+}
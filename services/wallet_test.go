@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"myapp/apperrors"
+	"myapp/fx"
+	"myapp/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func walletFixture(balances ...float64) (*WalletService, *fakeStore, *recordingNotifier, []models.User) {
+	users := make([]models.User, len(balances))
+	for i, balance := range balances {
+		users[i] = models.User{ID: uuid.New(), FirstName: "User", Balance: balance, Language: "en"}
+	}
+	store := newFakeStore(users...)
+	notifier := &recordingNotifier{}
+	return NewWalletService(store, notifier), store, notifier, users
+}
+
+func TestTopUpCreditsBalance(t *testing.T) {
+	wallet, store, notifier, users := walletFixture(1000)
+
+	topUp, err := wallet.TopUp(context.Background(), users[0].ID, 500, Account{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1000.0, topUp.BalanceBefore)
+	assert.Equal(t, 1500.0, topUp.BalanceAfter)
+	assert.Equal(t, 1500.0, store.data.users[users[0].ID].Balance)
+	assert.Len(t, store.data.topUps, 1)
+	if assert.Len(t, notifier.sent, 1) {
+		assert.Equal(t, "top_up_success", notifier.sent[0].Key)
+	}
+}
+
+func TestPayRejectsInsufficientBalance(t *testing.T) {
+	wallet, store, notifier, users := walletFixture(100)
+
+	_, err := wallet.Pay(context.Background(), users[0].ID, PaymentOrder{Amount: 150, Remarks: "coffee"})
+
+	assert.True(t, errors.Is(err, apperrors.ErrInsufficientBalance))
+	assert.Equal(t, 100.0, store.data.users[users[0].ID].Balance)
+	assert.Empty(t, store.data.payments)
+	assert.Empty(t, notifier.sent)
+}
+
+func TestPayUnknownUser(t *testing.T) {
+	wallet, _, _, _ := walletFixture()
+
+	_, err := wallet.Pay(context.Background(), uuid.New(), PaymentOrder{Amount: 10})
+
+	assert.True(t, errors.Is(err, apperrors.ErrUserNotFound))
+}
+
+func TestTransferMovesMoney(t *testing.T) {
+	wallet, store, notifier, users := walletFixture(1000, 0)
+
+	result, err := wallet.Transfer(context.Background(), TransferOrder{From: users[0].ID, To: users[1].ID, Amount: 400, Remarks: "rent"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1000.0, result.Transfer.BalanceBefore)
+	assert.Equal(t, 600.0, result.Transfer.BalanceAfter)
+	assert.Equal(t, 600.0, store.data.users[users[0].ID].Balance)
+	assert.Equal(t, 400.0, store.data.users[users[1].ID].Balance)
+	assert.Len(t, notifier.sent, 2)
+}
+
+func TestTransferFailuresLeaveBalancesUntouched(t *testing.T) {
+	wallet, store, _, users := walletFixture(100, 0)
+	ctx := context.Background()
+
+	_, err := wallet.Transfer(ctx, TransferOrder{From: users[0].ID, To: users[1].ID, Amount: 500})
+	assert.True(t, errors.Is(err, apperrors.ErrInsufficientBalance))
+
+	_, err = wallet.Transfer(ctx, TransferOrder{From: users[0].ID, To: uuid.New(), Amount: 50})
+	assert.True(t, errors.Is(err, apperrors.ErrTargetUserNotFound))
+
+	_, err = wallet.Transfer(ctx, TransferOrder{From: users[0].ID, To: users[0].ID, Amount: 50})
+	assert.True(t, errors.Is(err, apperrors.ErrValidationFailed))
+
+	assert.Equal(t, 100.0, store.data.users[users[0].ID].Balance)
+	assert.Equal(t, 0.0, store.data.users[users[1].ID].Balance)
+	assert.Empty(t, store.data.transfers)
+}
+
+func TestHistoryListsOwnRecords(t *testing.T) {
+	wallet, _, _, users := walletFixture(1000, 1000)
+	ctx := context.Background()
+
+	_, _ = wallet.TopUp(ctx, users[0].ID, 100, Account{})
+	_, _ = wallet.Pay(ctx, users[0].ID, PaymentOrder{Amount: 50})
+	_, _ = wallet.Transfer(ctx, TransferOrder{From: users[0].ID, To: users[1].ID, Amount: 10})
+	_, _ = wallet.TopUp(ctx, users[1].ID, 100, Account{})
+
+	history, err := wallet.History(ctx, users[0].ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, users[0].ID, history.User.ID)
+	assert.Len(t, history.TopUps, 1)
+	assert.Len(t, history.Payments, 1)
+	assert.Len(t, history.Transfers, 1)
+	assert.Empty(t, history.Received)
+
+	history, err = wallet.History(ctx, users[1].ID)
+	assert.NoError(t, err)
+	assert.Empty(t, history.Transfers)
+	assert.Len(t, history.Received, 1)
+}
+
+func TestWalletOperationsAreAudited(t *testing.T) {
+	wallet, store, _, users := walletFixture(1000, 0)
+	ctx := context.Background()
+
+	_, err := wallet.TopUp(ctx, users[0].ID, 500, Account{})
+	assert.NoError(t, err)
+	_, err = wallet.Transfer(ctx, TransferOrder{From: users[0].ID, To: users[1].ID, Amount: 200})
+	assert.NoError(t, err)
+
+	if assert.Len(t, store.data.audit, 2) {
+		assert.Equal(t, ActionTopUp, store.data.audit[0].Action)
+		assert.Equal(t, ActionTransfer, store.data.audit[1].Action)
+		assert.Equal(t, "user:"+users[0].ID.String(), store.data.audit[1].Actor)
+		assert.Contains(t, store.data.audit[1].Details, users[1].ID.String())
+	}
+}
+
+func TestFrozenAccountCannotMoveMoney(t *testing.T) {
+	wallet, store, notifier, users := walletFixture(1000, 500)
+	ctx := context.Background()
+	frozen := store.data.users[users[0].ID]
+	frozenAt := frozen.CreatedDate
+	frozen.FrozenAt = &frozenAt
+	store.data.users[frozen.ID] = frozen
+
+	_, err := wallet.Pay(ctx, users[0].ID, PaymentOrder{Amount: 100, Remarks: "Pulsa"})
+	assert.True(t, errors.Is(err, apperrors.ErrAccountFrozen))
+	_, err = wallet.Transfer(ctx, TransferOrder{From: users[0].ID, To: users[1].ID, Amount: 100})
+	assert.True(t, errors.Is(err, apperrors.ErrAccountFrozen))
+
+	// Money can still come in
+	_, err = wallet.Transfer(ctx, TransferOrder{From: users[1].ID, To: users[0].ID, Amount: 100})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1100.0, store.data.users[users[0].ID].Balance)
+	assert.Empty(t, store.data.payments)
+	assert.Len(t, notifier.sent, 2)
+}
+
+func TestAdjustBooksLedgerEntryAndAudit(t *testing.T) {
+	wallet, store, _, users := walletFixture(1000)
+	ctx := WithActor(context.Background(), "admin:ops")
+
+	user, err := wallet.Adjust(ctx, users[0].ID, -300, "Duplicate top-up")
+	assert.NoError(t, err)
+	assert.Equal(t, 700.0, user.Balance)
+	if assert.Len(t, store.data.payments, 1) {
+		assert.Equal(t, "Adjustment: Duplicate top-up", store.data.payments[0].Remarks)
+		assert.Equal(t, 1000.0, store.data.payments[0].BalanceBefore)
+	}
+
+	_, err = wallet.Adjust(ctx, users[0].ID, 50, "Goodwill")
+	assert.NoError(t, err)
+	assert.Len(t, store.data.topUps, 1)
+
+	if assert.Len(t, store.data.audit, 2) {
+		assert.Equal(t, ActionAdjust, store.data.audit[0].Action)
+		assert.Equal(t, "admin:ops", store.data.audit[0].Actor)
+		assert.Equal(t, "Duplicate top-up", store.data.audit[0].Reason)
+	}
+
+	_, err = wallet.Adjust(ctx, users[0].ID, -5000, "Too much")
+	assert.True(t, errors.Is(err, apperrors.ErrInsufficientBalance))
+
+	_, err = wallet.Adjust(ctx, users[0].ID, 10, "")
+	assert.True(t, errors.Is(err, apperrors.ErrValidationFailed))
+}
+
+// exchange converts at 1 USD = 16,000 IDR less a 1% spread.
+func exchange(usd float64) *fx.Exchange {
+	provider := fx.NewStaticProvider("IDR", map[string]float64{"USD": usd}, time.Time{})
+	return fx.NewExchange(provider, 0.01, time.Minute)
+}
+
+func TestForeignCurrencyBalances(t *testing.T) {
+	wallet, store, notifier, users := walletFixture(1000)
+	ctx := context.Background()
+
+	_, err := wallet.TopUp(ctx, users[0].ID, 20, Account{Currency: "USD"})
+	assert.True(t, errors.Is(err, apperrors.ErrUnsupportedCurrency), "without an exchange only rupiah is supported")
+
+	wallet = wallet.WithExchange(exchange(16000))
+	topUp, err := wallet.TopUp(ctx, users[0].ID, 20, Account{Currency: "USD"})
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", topUp.Currency)
+	assert.Equal(t, 20.0, topUp.BalanceAfter)
+	assert.Equal(t, 1000.0, store.data.users[users[0].ID].Balance, "the rupiah balance is untouched")
+	if assert.Len(t, notifier.sent, 1) {
+		assert.Equal(t, "USD 20", notifier.sent[0].Params["amount"])
+	}
+
+	_, err = wallet.Pay(ctx, users[0].ID, PaymentOrder{Amount: 25, Account: Account{Currency: "USD"}, Remarks: "Books"})
+	assert.True(t, errors.Is(err, apperrors.ErrInsufficientBalance))
+	payment, err := wallet.Pay(ctx, users[0].ID, PaymentOrder{Amount: 5, Account: Account{Currency: "USD"}, Remarks: "Books"})
+	assert.NoError(t, err)
+	assert.Equal(t, 15.0, payment.BalanceAfter)
+
+	_, err = wallet.TopUp(ctx, users[0].ID, 20, Account{Currency: "GBP"})
+	assert.True(t, errors.Is(err, apperrors.ErrUnsupportedCurrency))
+
+	balances, err := wallet.Balances(ctx, users[0].ID)
+	assert.NoError(t, err)
+	if assert.Len(t, balances, 2) {
+		assert.Equal(t, models.Balance{UserID: users[0].ID, Currency: "IDR", Amount: 1000}, balances[0])
+		assert.Equal(t, "USD", balances[1].Currency)
+		assert.Equal(t, 15.0, balances[1].Amount)
+	}
+}
+
+func TestCrossCurrencyTransferConverts(t *testing.T) {
+	wallet, store, notifier, users := walletFixture(0, 0)
+	wallet = wallet.WithExchange(exchange(16000))
+	ctx := context.Background()
+	if _, err := wallet.TopUp(ctx, users[0].ID, 100, Account{Currency: "USD"}); err != nil {
+		t.Fatalf("Failed to top up: %v", err)
+	}
+
+	result, err := wallet.Transfer(ctx, TransferOrder{From: users[0].ID, To: users[1].ID, Amount: 10, Currency: "USD", ToCurrency: "IDR"})
+	assert.NoError(t, err)
+	transfer := result.Transfer
+	assert.Equal(t, "USD", transfer.Currency)
+	assert.Equal(t, 90.0, transfer.BalanceAfter)
+	assert.Equal(t, "IDR", transfer.ToCurrency)
+	assert.Equal(t, 15840.0, transfer.Rate)
+	assert.Equal(t, 158400.0, transfer.ToAmount)
+	assert.Equal(t, 158400.0, store.data.users[users[1].ID].Balance)
+	if assert.Len(t, notifier.sent, 3) {
+		assert.Equal(t, "Rp158,400", notifier.sent[2].Params["amount"], "the recipient hears the converted amount")
+	}
+
+	// The same currency on both sides is not converted
+	result, err = wallet.Transfer(ctx, TransferOrder{From: users[0].ID, To: users[1].ID, Amount: 10, Currency: "USD"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, result.Transfer.Rate)
+	assert.Equal(t, 10.0, store.data.balances[balanceKey{users[1].ID, "USD"}].Amount)
+
+	history, err := wallet.History(ctx, users[1].ID)
+	assert.NoError(t, err)
+	if assert.Len(t, history.Received, 2) {
+		assert.Equal(t, transfer.ID, history.Received[0].ID)
+		assert.Equal(t, 158400.0, history.Received[0].ToAmount)
+		assert.Equal(t, "IDR", history.Received[0].ToCurrency)
+	}
+}
+
+func TestTransferAtQuotedRate(t *testing.T) {
+	wallet, store, _, users := walletFixture(1000000, 0)
+	wallet = wallet.WithExchange(exchange(16000))
+	ctx := context.Background()
+
+	quote, err := wallet.Quote(ctx, users[0].ID, "IDR", "USD")
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.0000625*0.99, quote.Rate, 1e-12)
+
+	_, err = wallet.Quote(ctx, users[0].ID, "USD", "USD")
+	assert.True(t, errors.Is(err, apperrors.ErrValidationFailed))
+
+	// The quoted rate holds even when the market moves
+	moved := wallet.WithExchange(exchange(20000))
+	order := TransferOrder{From: users[0].ID, To: users[1].ID, Amount: 160000, ToCurrency: "USD", QuoteID: quote.ID}
+	result, err := moved.Transfer(ctx, order)
+	assert.NoError(t, err)
+	assert.Equal(t, 9.9, result.Transfer.ToAmount)
+	assert.Equal(t, &quote.ID, result.Transfer.QuoteID)
+
+	_, err = moved.Transfer(ctx, order)
+	assert.True(t, errors.Is(err, apperrors.ErrQuoteExpired), "a quote is used once")
+
+	quote, err = wallet.Quote(ctx, users[0].ID, "IDR", "USD")
+	assert.NoError(t, err)
+	order.QuoteID = quote.ID
+	_, err = wallet.Transfer(ctx, TransferOrder{From: users[1].ID, To: users[0].ID, Amount: 1, Currency: "USD", ToCurrency: "IDR", QuoteID: quote.ID})
+	assert.True(t, errors.Is(err, apperrors.ErrQuoteNotFound), "quotes belong to the user they were made for")
+	_, err = wallet.Transfer(ctx, TransferOrder{From: users[0].ID, To: users[1].ID, Amount: 1, QuoteID: quote.ID})
+	assert.True(t, errors.Is(err, apperrors.ErrValidationFailed), "the quote must match the currencies")
+
+	wallet.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, err = wallet.Transfer(ctx, order)
+	assert.True(t, errors.Is(err, apperrors.ErrQuoteExpired))
+
+	assert.Len(t, store.data.transfers, 1)
+	assert.Equal(t, 840000.0, store.data.users[users[0].ID].Balance)
+}
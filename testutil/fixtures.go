@@ -0,0 +1,89 @@
+package testutil
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"myapp/auth"
+	"myapp/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultPIN is the PIN of users built by NewUser unless changed.
+const DefaultPIN = "123456"
+
+var phoneSequence atomic.Int64
+
+// UserBuilder creates users for tests. Every user gets a unique phone
+// number, so fixtures never collide with each other.
+type UserBuilder struct {
+	user models.User
+}
+
+func NewUser() *UserBuilder {
+	return &UserBuilder{user: models.User{
+		ID:          uuid.New(),
+		FirstName:   "Test",
+		LastName:    "User",
+		PhoneNumber: fmt.Sprintf("+62811%07d", phoneSequence.Add(1)),
+		Address:     "Jl. Pengujian No. 1",
+		PIN:         DefaultPIN,
+		Language:    "en",
+		CreatedDate: time.Now(),
+	}}
+}
+
+func (b *UserBuilder) WithName(first, last string) *UserBuilder {
+	b.user.FirstName, b.user.LastName = first, last
+	return b
+}
+
+func (b *UserBuilder) WithPhone(phoneNumber string) *UserBuilder {
+	b.user.PhoneNumber = phoneNumber
+	return b
+}
+
+func (b *UserBuilder) WithPIN(pin string) *UserBuilder {
+	b.user.PIN = pin
+	return b
+}
+
+func (b *UserBuilder) WithBalance(balance float64) *UserBuilder {
+	b.user.Balance = balance
+	return b
+}
+
+func (b *UserBuilder) WithLanguage(language string) *UserBuilder {
+	b.user.Language = language
+	return b
+}
+
+// Build returns the user without saving it.
+func (b *UserBuilder) Build() models.User {
+	return b.user
+}
+
+// Create saves the user in db and returns it as stored.
+func (b *UserBuilder) Create(t testing.TB, db *gorm.DB) models.User {
+	t.Helper()
+	user := b.user
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user fixture: %v", err)
+	}
+	return user
+}
+
+// Token returns an access token for user, as sent in the Authorization
+// header.
+func Token(t testing.TB, user models.User) string {
+	t.Helper()
+	token, err := auth.GenerateJWT(user.ID.String(), user.PhoneNumber, "access")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	return token
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"myapp/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// dryRunDB builds SQL without a database server, which is enough to
+// exercise the callbacks
+func dryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry-run database: %v", err)
+	}
+	assert.NoError(t, InstrumentGORM(db))
+	return db
+}
+
+func TestMiddlewareContinuesTraceAndNamesSpanByRoute(t *testing.T) {
+	recorder := recordSpans(t)
+	db := dryRunDB(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/users/:id", func(c *gin.Context) {
+		var user models.User
+		db.WithContext(c.Request.Context()).Where("id = ?", c.Param("id")).First(&user)
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		query, server := spans[0], spans[1]
+
+		assert.Equal(t, "GET /users/:id", server.Name())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+
+		assert.Equal(t, "gorm.query", query.Name())
+		assert.Equal(t, server.SpanContext().SpanID(), query.Parent().SpanID())
+		for _, attr := range query.Attributes() {
+			assert.NotContains(t, attr.Value.Emit(), "42'", "bound values must not be recorded")
+		}
+	}
+}
+
+func TestDetachKeepsSpanButNotCancellation(t *testing.T) {
+	recorder := recordSpans(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, span := Tracer().Start(ctx, "request")
+	cancel()
+
+	detached := Detach(ctx)
+	_, child := Tracer().Start(detached, "worker")
+	child.End()
+	span.End()
+
+	assert.NoError(t, detached.Err())
+	assert.Equal(t, span.SpanContext().SpanID(), recorder.Ended()[0].Parent().SpanID())
+}
@@ -0,0 +1,122 @@
+// Package validation configures the declarative request validation used by
+// gin's binding package and turns validation failures into per-field
+// messages.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"myapp/apperrors"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+var registerOnce sync.Once
+
+// Register installs the custom rules and makes validation errors report JSON
+// field names. It is safe to call more than once.
+func Register() {
+	registerOnce.Do(func() {
+		engine, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			panic("validation: unexpected binding validator engine")
+		}
+
+		engine.RegisterTagNameFunc(jsonFieldName)
+		mustRegister(engine, "decimals", validateDecimals)
+	})
+}
+
+func mustRegister(engine *validator.Validate, tag string, fn validator.Func) {
+	if err := engine.RegisterValidation(tag, fn); err != nil {
+		panic(fmt.Sprintf("validation: registering %q: %v", tag, err))
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// validateDecimals implements `decimals=N`: the number may have at most N
+// digits after the decimal point.
+func validateDecimals(fl validator.FieldLevel) bool {
+	places, err := strconv.Atoi(fl.Param())
+	if err != nil {
+		return false
+	}
+
+	var value float64
+	switch fl.Field().Kind() {
+	case reflect.Float32, reflect.Float64:
+		value = fl.Field().Float()
+	default:
+		return true
+	}
+
+	scaled := value * math.Pow10(places)
+	return math.Abs(scaled-math.Round(scaled)) < 1e-6
+}
+
+// Rules lists every FieldError rule this package produces, so that message
+// catalogues can be checked for completeness.
+func Rules() []string {
+	return []string{
+		"required", "gt", "min", "max", "min_length", "max_length", "len",
+		"numeric", "e164", "iso4217", "decimals", "oneof", "invalid",
+	}
+}
+
+// FieldErrors returns the failed rule per invalid field when err is a
+// validation failure, or nil when err is something else such as malformed
+// JSON.
+func FieldErrors(err error) map[string]apperrors.FieldError {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+
+	fields := make(map[string]apperrors.FieldError, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		name := fieldErr.Field()
+		if _, exists := fields[name]; exists {
+			continue
+		}
+		fields[name] = apperrors.FieldError{Rule: rule(fieldErr), Param: fieldErr.Param()}
+	}
+	return fields
+}
+
+func rule(fieldErr validator.FieldError) string {
+	isString := fieldErr.Kind() == reflect.String
+
+	switch tag := fieldErr.Tag(); tag {
+	case "required", "gt", "len", "numeric", "e164", "iso4217", "decimals", "oneof":
+		return tag
+	case "gte", "min":
+		if isString {
+			return "min_length"
+		}
+		return "min"
+	case "lte", "max":
+		if isString {
+			return "max_length"
+		}
+		return "max"
+	default:
+		return "invalid"
+	}
+}
@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"testing"
+
+	"myapp/apperrors"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+)
+
+type sampleRequest struct {
+	PhoneNumber string  `json:"phone_number" binding:"required,e164"`
+	PIN         string  `json:"pin" binding:"required,numeric,len=6"`
+	Amount      float64 `json:"amount" binding:"required,gt=0,max=1000,decimals=2"`
+	Remarks     string  `json:"remarks" binding:"max=5"`
+	Currency    string  `json:"currency" binding:"omitempty,iso4217"`
+}
+
+func validate(request sampleRequest) map[string]apperrors.FieldError {
+	Register()
+	return FieldErrors(binding.Validator.ValidateStruct(&request))
+}
+
+func TestValidRequestPasses(t *testing.T) {
+	errs := validate(sampleRequest{PhoneNumber: "+628123456789", PIN: "123456", Amount: 10.25})
+
+	assert.Empty(t, errs)
+}
+
+func TestFieldErrorsUseJSONNames(t *testing.T) {
+	errs := validate(sampleRequest{PhoneNumber: "08123456789", PIN: "12ab56", Amount: -5, Remarks: "too long", Currency: "usd"})
+
+	assert.Equal(t, map[string]apperrors.FieldError{
+		"phone_number": {Rule: "e164"},
+		"pin":          {Rule: "numeric"},
+		"amount":       {Rule: "gt", Param: "0"},
+		"remarks":      {Rule: "max_length", Param: "5"},
+		"currency":     {Rule: "iso4217"},
+	}, errs)
+}
+
+func TestAmountLimits(t *testing.T) {
+	base := sampleRequest{PhoneNumber: "+628123456789", PIN: "123456"}
+
+	base.Amount = 0
+	assert.Equal(t, "required", validate(base)["amount"].Rule)
+
+	base.Amount = 1000.01
+	assert.Equal(t, apperrors.FieldError{Rule: "max", Param: "1000"}, validate(base)["amount"])
+
+	base.Amount = 0.001
+	assert.Equal(t, apperrors.FieldError{Rule: "decimals", Param: "2"}, validate(base)["amount"])
+}
+
+func TestFieldErrorsIgnoresOtherErrors(t *testing.T) {
+	assert.Nil(t, FieldErrors(assert.AnError))
+}